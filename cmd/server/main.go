@@ -2,47 +2,128 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
 	"github.com/Rodjolo/pr-reviewer-service/internal/database"
 	"github.com/Rodjolo/pr-reviewer-service/internal/handlers"
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+	"github.com/Rodjolo/pr-reviewer-service/internal/metrics"
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/internal/router"
 	"github.com/Rodjolo/pr-reviewer-service/internal/service"
+	"github.com/Rodjolo/pr-reviewer-service/internal/tracing"
 
 	_ "github.com/Rodjolo/pr-reviewer-service/docs" // Swagger docs
 )
 
+// Значения по умолчанию для настроек HTTP сервера. WriteTimeout больше таймаута обработки запроса,
+// чтобы клиент успел получить ответ 504
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultWriteTimeout      = router.DefaultRequestTimeout + 5*time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+)
+
 func main() {
+	// Пока логгер не настроен, ошибки пишутся стандартным логгером slog
+	logLevel := slog.LevelInfo
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		level, err := logging.ParseLevel(value)
+		if err != nil {
+			fatal("invalid LOG_LEVEL", "error", err)
+		}
+		logLevel = level
+	}
+	slog.SetDefault(logging.New(os.Stdout, logLevel))
+
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+		fatal("DATABASE_URL environment variable is required")
+	}
+
+	// Трассировка настраивается до подключения к БД, чтобы запросы к ней попадали в трейсы
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
+	}
+	if tracing.Enabled() {
+		slog.Info("exporting traces over OTLP")
 	}
 
 	db, err := database.New(dbURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", "error", err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			log.Printf("Error closing database: %v", err)
-		}
-	}()
 
 	userRepo := repository.NewUserRepository(db.DB)
 	teamRepo := repository.NewTeamRepository(db.DB)
 	prRepo := repository.NewPRRepository(db.DB)
+	webhookRepo := repository.NewWebhookRepository(db.DB)
+	ownershipRepo := repository.NewOwnershipRepository(db.DB)
+
+	selectorOpts, err := service.SelectorOptionsFromConfig(os.Getenv("REVIEWER_STRATEGY"), os.Getenv("TEAM_REVIEWER_STRATEGIES"))
+	if err != nil {
+		fatal("invalid reviewer strategy configuration", "error", err)
+	}
 
-	userService := service.NewUserService(userRepo, prRepo, teamRepo)
+	webhookService := service.NewWebhookService(webhookRepo, nil)
+	appMetrics := metrics.New(db.DB)
+	serviceOpts := append(selectorOpts,
+		service.WithEventPublisher(webhookService),
+		service.WithOwnershipRules(ownershipRepo),
+		service.WithAssignmentObserver(appMetrics),
+	)
+
+	userService := service.NewUserService(userRepo, prRepo, teamRepo, serviceOpts...)
 	teamService := service.NewTeamService(teamRepo, userRepo)
-	prService := service.NewPRService(prRepo, userRepo, teamRepo)
+	prService := service.NewPRService(prRepo, userRepo, teamRepo, serviceOpts...)
 	statsService := service.NewStatsService(prRepo)
+	ownershipService := service.NewOwnershipService(ownershipRepo, teamRepo, userRepo)
+	integrationService := service.NewIntegrationService(prService, prRepo, userRepo, os.Getenv("GITHUB_WEBHOOK_SECRET"), os.Getenv("GITLAB_WEBHOOK_SECRET"))
+
+	availabilityInterval := durationFromEnv("AVAILABILITY_CHECK_INTERVAL", service.DefaultAvailabilityCheckInterval)
+	webhookInterval := durationFromEnv("WEBHOOK_DELIVERY_INTERVAL", service.DefaultWebhookDeliveryInterval)
+	requestTimeout := durationFromEnv("REQUEST_TIMEOUT", router.DefaultRequestTimeout)
+	shutdownTimeout := durationFromEnv("SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	shutdownDelay := durationFromEnv("SHUTDOWN_DELAY", 0)
 
-	h := handlers.NewHandlers(prService, userService, teamService, statsService)
-	r := router.NewRouter(h)
+	availabilityWorker := service.NewAvailabilityWorker(userService, availabilityInterval)
+	webhookWorker := service.NewWebhookWorker(webhookService, webhookInterval)
+	healthService := service.NewHealthService(repository.NewHealthRepository(db.DB), database.SchemaVersion, map[string]service.WorkerStatus{
+		"availability_worker": availabilityWorker,
+		"webhook_worker":      webhookWorker,
+	})
+
+	authenticator, err := authenticatorFromEnv()
+	if err != nil {
+		fatal("invalid authentication configuration", "error", err)
+	}
+	if !authenticator.Enabled() {
+		slog.Warn("authentication is disabled: set AUTH_API_KEYS, AUTH_JWT_SECRET or AUTH_JWT_PUBLIC_KEY_FILE to protect the API")
+	}
+
+	h := handlers.NewHandlers(prService, userService, teamService, statsService, webhookService, integrationService, ownershipService, healthService)
+	server := &http.Server{
+		Handler:           router.NewRouter(h, requestTimeout, appMetrics, authenticator),
+		ReadHeaderTimeout: durationFromEnv("READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
+		ReadTimeout:       durationFromEnv("READ_TIMEOUT", defaultReadTimeout),
+		WriteTimeout:      durationFromEnv("WRITE_TIMEOUT", defaultWriteTimeout),
+		IdleTimeout:       durationFromEnv("IDLE_TIMEOUT", defaultIdleTimeout),
+		MaxHeaderBytes:    intFromEnv("MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -54,15 +135,122 @@ func main() {
 		externalPort = port
 	}
 
-	log.Printf("Server starting on port %s (external: %s)", port, externalPort)
+	slog.Info("server starting", "port", port, "external_port", externalPort)
 
 	listener, err := net.Listen("tcp4", "0.0.0.0:"+port)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		fatal("failed to listen", "port", port, "error", err)
+	}
+
+	var workers sync.WaitGroup
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		availabilityWorker.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		webhookWorker.Run(workerCtx)
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", listener.Addr().String(), "external_port", externalPort)
+		serveErr <- server.Serve(listener)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		slog.Error("server failed", "error", err)
+	case sig := <-signals:
+		slog.Info("shutting down", "signal", sig.String(), "grace_period", shutdownTimeout.String())
+	}
+
+	// /readyz отвечает 503 сразу после сигнала; в течение shutdownDelay сервер еще принимает запросы,
+	// пока балансировщик не исключит его из ротации
+	healthService.SetShuttingDown()
+	time.Sleep(shutdownDelay)
+
+	// Сначала дожидаемся обработки текущих запросов, затем останавливаем фоновые задачи
+	// и только после этого закрываем пул соединений с БД
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server did not drain in time", "error", err)
+	}
+
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		slog.Error("background workers did not stop in time")
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// fatal записывает ошибку запуска в лог и завершает процесс
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// durationFromEnv читает длительность из переменной окружения name или возвращает def, если она не задана
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		fatal("invalid duration", "name", name, "error", err)
 	}
+	return d
+}
 
-	log.Printf("Server listening on %s (accessible externally on port %s)", listener.Addr().String(), externalPort)
-	if err := http.Serve(listener, r); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+// intFromEnv читает целое число из переменной окружения name или возвращает def, если она не задана
+func intFromEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		fatal("invalid positive integer", "name", name, "value", value)
+	}
+	return n
+}
+
+// authenticatorFromEnv читает настройки аутентификации из переменных AUTH_*
+func authenticatorFromEnv() (*auth.Authenticator, error) {
+	cfg := auth.Config{
+		APIKeys:     os.Getenv("AUTH_API_KEYS"),
+		JWTSecret:   os.Getenv("AUTH_JWT_SECRET"),
+		JWTIssuer:   os.Getenv("AUTH_JWT_ISSUER"),
+		JWTAudience: os.Getenv("AUTH_JWT_AUDIENCE"),
+	}
+	if path := os.Getenv("AUTH_JWT_PUBLIC_KEY_FILE"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		cfg.JWTPublicKey = key
 	}
+	return auth.NewAuthenticator(cfg)
 }
@@ -0,0 +1,222 @@
+// Package auth authenticates API clients by static API keys and JWTs and describes their roles.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Role - роль клиента API
+type Role string
+
+// Права в конкретной команде задаются не ролью клиента, а ролью его пользователя в команде (models.TeamRole):
+// RoleTeamLead сама по себе не дает прав лида ни в одной команде
+const (
+	RoleAdmin    Role = "admin"
+	RoleTeamLead Role = "team-lead"
+	RoleMember   Role = "member"
+	RoleBot      Role = "bot"
+)
+
+// Valid проверяет, что роль входит в список известных ролей
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleTeamLead, RoleMember, RoleBot:
+		return true
+	}
+	return false
+}
+
+// APIKeyHeader - заголовок, в котором можно передать API-ключ вместо Authorization: Bearer
+const APIKeyHeader = "X-API-Key"
+
+// minJWTSecretLength - минимальная длина секрета HS256, короткие секреты подбираются перебором
+const minJWTSecretLength = 32
+
+var (
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal - аутентифицированный клиент API
+type Principal struct {
+	Subject string
+	Role    Role
+	// UserID - пользователь сервиса, от имени которого действует клиент; 0, если клиент не связан с пользователем.
+	// Роль пользователя в командах хранится в сервисе и проверяется по этому ID
+	UserID int
+}
+
+// HasRole проверяет, что у клиента одна из ролей roles
+func (p *Principal) HasRole(roles ...Role) bool {
+	return slices.Contains(roles, p.Role)
+}
+
+// IsUser проверяет, что клиент действует от имени пользователя userID
+func (p *Principal) IsUser(userID int) bool {
+	return p.UserID != 0 && p.UserID == userID
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет аутентифицированного клиента в контексте
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает аутентифицированного клиента из контекста или nil, если аутентификация выключена
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Config - настройки аутентификации. Пустые поля выключают соответствующий способ
+type Config struct {
+	// APIKeys - статические ключи через запятую в формате name:role:key
+	APIKeys string
+	// JWTSecret - секрет для токенов HS256
+	JWTSecret string
+	// JWTPublicKey - открытый ключ RSA в формате PEM для токенов RS256
+	JWTPublicKey []byte
+	// JWTIssuer и JWTAudience, если заданы, должны совпадать с claims iss и aud токена
+	JWTIssuer   string
+	JWTAudience string
+}
+
+// claims - содержимое JWT. Роль задается claim role, пользователь сервиса - user_id
+type claims struct {
+	jwt.RegisteredClaims
+	Role   Role `json:"role"`
+	UserID int  `json:"user_id"`
+}
+
+// Authenticator проверяет учетные данные запроса: API-ключ или JWT
+type Authenticator struct {
+	apiKeys    map[[sha256.Size]byte]*Principal
+	hsSecret   []byte
+	rsaKey     *rsa.PublicKey
+	parser     *jwt.Parser
+	jwtEnabled bool
+}
+
+// NewAuthenticator разбирает настройки аутентификации. Если не задан ни один способ, аутентификация
+// выключена (см. Enabled)
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{apiKeys: make(map[[sha256.Size]byte]*Principal)}
+
+	for i, entry := range strings.Split(cfg.APIKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			// Запись может целиком оказаться ключом, поэтому в ошибке указывается только ее номер
+			return nil, fmt.Errorf("invalid API key entry #%d: expected name:role:key", i+1)
+		}
+		role := Role(parts[1])
+		if !role.Valid() {
+			return nil, fmt.Errorf("invalid role %q of API key %q", parts[1], parts[0])
+		}
+		hash := sha256.Sum256([]byte(parts[2]))
+		if _, ok := a.apiKeys[hash]; ok {
+			return nil, fmt.Errorf("duplicate API key %q", parts[0])
+		}
+		a.apiKeys[hash] = &Principal{Subject: parts[0], Role: role}
+	}
+
+	var methods []string
+	if cfg.JWTSecret != "" {
+		if len(cfg.JWTSecret) < minJWTSecretLength {
+			return nil, fmt.Errorf("JWT secret must be at least %d bytes long", minJWTSecretLength)
+		}
+		a.hsSecret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(cfg.JWTPublicKey) > 0 {
+		key, err := jwt.ParseRSAPublicKeyFromPEM(cfg.JWTPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT public key: %w", err)
+		}
+		a.rsaKey = key
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) > 0 {
+		a.jwtEnabled = true
+		opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+		if cfg.JWTIssuer != "" {
+			opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+		}
+		if cfg.JWTAudience != "" {
+			opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+
+	return a, nil
+}
+
+// Enabled сообщает, задан ли хотя бы один способ аутентификации
+func (a *Authenticator) Enabled() bool {
+	return len(a.apiKeys) > 0 || a.jwtEnabled
+}
+
+// Authenticate определяет клиента по заголовку X-API-Key или Authorization: Bearer, в котором передается
+// API-ключ или JWT. Без учетных данных возвращает ErrUnauthenticated, с неверными - ErrInvalidCredentials
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	credential := r.Header.Get(APIKeyHeader)
+	if credential == "" {
+		header := r.Header.Get("Authorization")
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			if header != "" {
+				return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrInvalidCredentials)
+			}
+			return nil, ErrUnauthenticated
+		}
+		credential = strings.TrimSpace(token)
+	}
+	if credential == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	// Ключи сравниваются по хешу, поэтому время поиска не зависит от совпавшего префикса ключа
+	if p, ok := a.apiKeys[sha256.Sum256([]byte(credential))]; ok {
+		return p, nil
+	}
+	if !a.jwtEnabled || strings.Count(credential, ".") != 2 {
+		return nil, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	return a.parseJWT(credential)
+}
+
+func (a *Authenticator) parseJWT(token string) (*Principal, error) {
+	var c claims
+	_, err := a.parser.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.hsSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			return a.rsaKey, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	if !c.Role.Valid() {
+		return nil, fmt.Errorf("%w: invalid role %q", ErrInvalidCredentials, c.Role)
+	}
+	return &Principal{Subject: c.Subject, Role: c.Role, UserID: c.UserID}, nil
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewAuthenticator_APIKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    string
+		wantErr bool
+	}{
+		{name: "valid", keys: "ci:bot:k1, ops:admin:k2"},
+		{name: "empty", keys: ""},
+		{name: "missing key", keys: "ci:bot", wantErr: true},
+		{name: "unknown role", keys: "ci:robot:k1", wantErr: true},
+		{name: "duplicate key", keys: "ci:bot:k1,ops:admin:k1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuthenticator(Config{APIKeys: tt.keys})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewAuthenticator(Config{JWTSecret: "short"}); err == nil {
+		t.Error("expected short JWT secret to be rejected")
+	}
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	a, err := NewAuthenticator(Config{APIKeys: "ci:bot:k1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !a.Enabled() {
+		t.Fatal("expected authentication to be enabled")
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		wantErr error
+	}{
+		{name: "X-API-Key", header: APIKeyHeader, value: "k1"},
+		{name: "bearer", header: "Authorization", value: "Bearer k1"},
+		{name: "no credentials", wantErr: ErrUnauthenticated},
+		{name: "unknown key", header: APIKeyHeader, value: "k2", wantErr: ErrInvalidCredentials},
+		{name: "basic auth", header: "Authorization", value: "Basic azE6", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/prs", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			p, err := a.Authenticate(req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Subject != "ci" || p.Role != RoleBot {
+				t.Errorf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	a, err := NewAuthenticator(Config{JWTSecret: testSecret, JWTPublicKey: publicPEM, JWTIssuer: "sso"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":     "alice",
+			"iss":     "sso",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"role":    "team-lead",
+			"user_id": 7,
+		}
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "HS256", token: sign(jwt.SigningMethodHS256, []byte(testSecret), valid())},
+		{name: "RS256", token: sign(jwt.SigningMethodRS256, rsaKey, valid())},
+		{name: "wrong secret", token: sign(jwt.SigningMethodHS256, []byte("another secret of the same length"), valid()), wantErr: true},
+		{name: "HS256 signed with public key", token: sign(jwt.SigningMethodHS256, publicPEM, valid()), wantErr: true},
+		{name: "unsupported algorithm", token: sign(jwt.SigningMethodHS512, []byte(testSecret), valid()), wantErr: true},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, []byte(testSecret), with("exp", time.Now().Add(-time.Minute).Unix())), wantErr: true},
+		{name: "no expiration", token: sign(jwt.SigningMethodHS256, []byte(testSecret), with("exp", nil)), wantErr: true},
+		{name: "wrong issuer", token: sign(jwt.SigningMethodHS256, []byte(testSecret), with("iss", "other")), wantErr: true},
+		{name: "unknown role", token: sign(jwt.SigningMethodHS256, []byte(testSecret), with("role", "owner")), wantErr: true},
+		{name: "no subject", token: sign(jwt.SigningMethodHS256, []byte(testSecret), with("sub", nil)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/prs", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			p, err := a.Authenticate(req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("expected ErrInvalidCredentials, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Subject != "alice" || p.Role != RoleTeamLead || !p.IsUser(7) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
@@ -4,12 +4,18 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/XSAM/otelsql"
 	// PostgreSQL driver
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
+// SchemaVersion is the number of the latest migration in migrations/.
+// The server reports itself not ready until the database schema is at this version; bump it with every new migration.
+const SchemaVersion = 17
+
 // DB wraps sql.DB with additional functionality.
 type DB struct {
 	*sql.DB
@@ -17,7 +23,16 @@ type DB struct {
 
 // New creates a new database connection with optimized connection pool settings.
 func New(connectionString string) (*DB, error) {
-	db, err := sql.Open("postgres", connectionString)
+	return open("postgres", connectionString)
+}
+
+// open connects through driverName wrapped with OpenTelemetry instrumentation:
+// every SQL statement and transaction gets a span in the global tracer provider.
+func open(driverName, connectionString string) (*DB, error) {
+	db, err := otelsql.Open(driverName, connectionString,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -33,7 +48,7 @@ func New(connectionString string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Database connection established")
+	slog.Info("database connection established")
 	return &DB{db}, nil
 }
 
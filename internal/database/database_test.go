@@ -0,0 +1,32 @@
+package database
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion_MatchesLatestMigration(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	if err != nil {
+		t.Fatalf("failed to read migrations: %v", err)
+	}
+
+	latest := 0
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, version)
+	}
+
+	if latest != SchemaVersion {
+		t.Errorf("expected SchemaVersion %d to match the latest migration %d", SchemaVersion, latest)
+	}
+}
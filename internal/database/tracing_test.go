@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/tracing/tracingtest"
+)
+
+// stubDriver выполняет любые запросы без БД, чтобы проверить инструментирование драйвера
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (stubConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+func TestOpen_TracesStatements(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	db, err := open("stub", "")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	const query = "UPDATE users SET is_active = false WHERE id = $1"
+	if _, err := db.ExecContext(context.Background(), query, 1); err != nil {
+		t.Fatalf("failed to execute statement: %v", err)
+	}
+
+	for _, span := range recorder.Ended() {
+		for _, attr := range span.Attributes() {
+			// Имя атрибута зависит от OTEL_SEMCONV_STABILITY_OPT_IN
+			if (attr.Key == "db.statement" || attr.Key == "db.query.text") && attr.Value.AsString() == query {
+				return
+			}
+		}
+	}
+	t.Errorf("expected a span with the statement, got %v", tracingtest.SpanNames(recorder))
+}
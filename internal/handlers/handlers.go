@@ -3,36 +3,166 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
 	"github.com/Rodjolo/pr-reviewer-service/internal/service"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 )
 
 type Handlers struct {
-	prService    service.PRServiceInterface
-	userService  service.UserServiceInterface
-	teamService  service.TeamServiceInterface
-	statsService service.StatsServiceInterface
+	prService          service.PRServiceInterface
+	userService        service.UserServiceInterface
+	teamService        service.TeamServiceInterface
+	statsService       service.StatsServiceInterface
+	webhookService     service.WebhookServiceInterface
+	integrationService service.IntegrationServiceInterface
+	ownershipService   service.OwnershipServiceInterface
+	healthService      service.HealthServiceInterface
 }
 
-func NewHandlers(prService service.PRServiceInterface, userService service.UserServiceInterface, teamService service.TeamServiceInterface, statsService service.StatsServiceInterface) *Handlers {
+func NewHandlers(prService service.PRServiceInterface, userService service.UserServiceInterface, teamService service.TeamServiceInterface, statsService service.StatsServiceInterface, webhookService service.WebhookServiceInterface, integrationService service.IntegrationServiceInterface, ownershipService service.OwnershipServiceInterface, healthService service.HealthServiceInterface) *Handlers {
 	return &Handlers{
-		prService:    prService,
-		userService:  userService,
-		teamService:  teamService,
-		statsService: statsService,
+		prService:          prService,
+		userService:        userService,
+		teamService:        teamService,
+		statsService:       statsService,
+		webhookService:     webhookService,
+		integrationService: integrationService,
+		ownershipService:   ownershipService,
+		healthService:      healthService,
 	}
 }
 
-func (h *Handlers) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+func (h *Handlers) respondJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		// Log error but don't change response since headers are already sent
-		_ = err
+		// Заголовки уже отправлены, поэтому ответ не меняется - ошибка только записывается в лог
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
 }
 
-func (h *Handlers) respondError(w http.ResponseWriter, status int, message string) {
-	h.respondJSON(w, status, map[string]string{"error": message})
+// respondError отвечает ошибкой с идентификатором запроса, по которому ее можно найти в логах
+func (h *Handlers) respondError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	h.respondJSON(w, r, status, dto.ErrorResponse{Error: message, RequestID: logging.RequestID(r.Context())})
+}
+
+// errorCodes - стабильные коды ошибок сервиса, по которым клиенты различают ответы с одинаковым статусом
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{service.ErrInsufficientReviewers, dto.ErrorCodeInsufficientReviewers},
+	{service.ErrReviewersAtCapacity, dto.ErrorCodeReviewersAtCapacity},
+}
+
+// respondServiceError отвечает ошибкой сервиса err и добавляет в поле code ее стабильный код, если он есть
+func (h *Handlers) respondServiceError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	body := dto.ErrorResponse{Error: err.Error(), RequestID: logging.RequestID(r.Context())}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			body.Code = c.code
+			break
+		}
+	}
+	h.respondJSON(w, r, status, body)
+}
+
+// respondInternalError записывает в лог причину внутренней ошибки и отвечает 500, не раскрывая ее клиенту
+func (h *Handlers) respondInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	h.respondError(w, r, http.StatusInternalServerError, "internal server error")
+}
+
+// actsAs проверяет, что клиент действует от имени пользователя userID или имеет одну из ролей privileged.
+// Если аутентификация выключена, проверка не выполняется
+func actsAs(r *http.Request, userID int, privileged ...auth.Role) bool {
+	p := auth.FromContext(r.Context())
+	return p == nil || p.IsUser(userID) || p.HasRole(privileged...)
+}
+
+// authorizeTeam проверяет, что клиент может изменять команду teamName: это администратор или пользователь
+// с ролью lead в этой команде. Иначе отвечает 403 и возвращает false. Если аутентификация выключена, проверка не выполняется
+func (h *Handlers) authorizeTeam(w http.ResponseWriter, r *http.Request, teamName string) bool {
+	p := auth.FromContext(r.Context())
+	if p == nil || p.HasRole(auth.RoleAdmin) {
+		return true
+	}
+	if p.UserID != 0 {
+		role, err := h.teamService.GetMemberRole(r.Context(), teamName, p.UserID)
+		if err != nil {
+			h.respondInternalError(w, r, err)
+			return false
+		}
+		if role == models.TeamRoleLead {
+			return true
+		}
+	}
+	h.respondError(w, r, http.StatusForbidden, "only admins and leads of the team can change it")
+	return false
+}
+
+// authorizePR проверяет, что клиент может изменять PR prID: это его автор, назначенный ревьювер, лид команды PR,
+// администратор или бот. Иначе отвечает 403 (404, если PR не найден) и возвращает false. Если аутентификация выключена,
+// проверка не выполняется
+func (h *Handlers) authorizePR(w http.ResponseWriter, r *http.Request, prID int) bool {
+	p := auth.FromContext(r.Context())
+	if p == nil || p.HasRole(auth.RoleAdmin, auth.RoleBot) {
+		return true
+	}
+	if p.UserID != 0 {
+		pr, err := h.prService.GetPR(r.Context(), prID)
+		if err != nil {
+			if errors.Is(err, service.ErrPRNotFound) {
+				h.respondError(w, r, http.StatusNotFound, err.Error())
+				return false
+			}
+			h.respondInternalError(w, r, err)
+			return false
+		}
+		if pr.AuthorID == p.UserID || slices.Contains(pr.Reviewers, p.UserID) {
+			return true
+		}
+
+		teamName, err := h.prTeam(r, pr)
+		if err != nil {
+			h.respondInternalError(w, r, err)
+			return false
+		}
+		if teamName != "" {
+			role, err := h.teamService.GetMemberRole(r.Context(), teamName, p.UserID)
+			if err != nil {
+				h.respondInternalError(w, r, err)
+				return false
+			}
+			if role == models.TeamRoleLead {
+				return true
+			}
+		}
+	}
+	h.respondError(w, r, http.StatusForbidden, "only the author, reviewers and leads of the PR team can change it")
+	return false
+}
+
+// prTeam возвращает команду PR. У PR, созданных до сохранения команды в PR, это основная команда автора
+func (h *Handlers) prTeam(r *http.Request, pr *models.PR) (string, error) {
+	if pr.TeamName != "" {
+		return pr.TeamName, nil
+	}
+	memberships, err := h.teamService.GetUserTeams(r.Context(), pr.AuthorID)
+	if err != nil {
+		return "", err
+	}
+	for _, membership := range memberships {
+		if membership.IsPrimary {
+			return membership.TeamName, nil
+		}
+	}
+	return "", nil
 }
@@ -1,62 +1,193 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+	"github.com/gorilla/mux"
 )
 
-type mockPRService2 struct{}
+// mockPRService2 возвращает pr из GetPR и err из операций, назначающих ревьюверов
+type mockPRService2 struct {
+	pr  *models.PR
+	err error
+}
 
-func (m *mockPRService2) CreatePR(title string, authorID int) (*models.PR, error) { return nil, nil }
-func (m *mockPRService2) GetPR(id int) (*models.PR, error)                        { return nil, nil }
-func (m *mockPRService2) GetAllPRs() ([]models.PR, error)                         { return nil, nil }
-func (m *mockPRService2) GetPRsByUserID(userID int) ([]models.PR, error)          { return nil, nil }
-func (m *mockPRService2) ReassignReviewer(prID, oldReviewerID int) (*models.PR, error) {
+func (m *mockPRService2) CreatePR(_ context.Context, title string, authorID int, teamName string, changedFiles []string) (*models.PR, error) {
+	return nil, m.err
+}
+func (m *mockPRService2) CreateDraftPR(_ context.Context, title string, authorID int, teamName string, changedFiles []string) (*models.PR, error) {
+	return nil, nil
+}
+func (m *mockPRService2) PreviewPR(_ context.Context, authorID int, teamName string, changedFiles []string, seed *int64) (*models.ReviewerPreview, error) {
+	return nil, m.err
+}
+func (m *mockPRService2) GetPR(_ context.Context, id int) (*models.PR, error) {
+	if m.pr == nil {
+		return nil, service.ErrPRNotFound
+	}
+	return m.pr, nil
+}
+func (m *mockPRService2) GetPREvents(_ context.Context, prID int) ([]models.PREvent, error) {
+	return nil, nil
+}
+func (m *mockPRService2) GetAllPRs(_ context.Context) ([]models.PR, error) { return nil, nil }
+func (m *mockPRService2) GetPRsByUserID(_ context.Context, userID int) ([]models.PR, error) {
 	return nil, nil
 }
-func (m *mockPRService2) MergePR(id int) (*models.PR, error) { return nil, nil }
+func (m *mockPRService2) ReassignReviewer(_ context.Context, prID, oldReviewerID int) (*models.PR, error) {
+	return nil, m.err
+}
+func (m *mockPRService2) MergePR(_ context.Context, id int, force bool) (*models.PR, error) {
+	return nil, nil
+}
+func (m *mockPRService2) SubmitReview(_ context.Context, prID, reviewerID int, state models.ReviewState) (*models.PR, error) {
+	return nil, nil
+}
+func (m *mockPRService2) ClosePR(_ context.Context, id int) (*models.PR, error)   { return nil, nil }
+func (m *mockPRService2) ReopenPR(_ context.Context, id int) (*models.PR, error)  { return nil, m.err }
+func (m *mockPRService2) MarkReady(_ context.Context, id int) (*models.PR, error) { return nil, nil }
 
 type mockUserService2 struct{}
 
-func (m *mockUserService2) CreateUser(name string, isActive bool) (*models.User, error) {
+func (m *mockUserService2) CreateUser(_ context.Context, name string, isActive bool, maxOpenReviews *int) (*models.User, error) {
+	return nil, nil
+}
+func (m *mockUserService2) GetUser(_ context.Context, id int) (*models.User, error) { return nil, nil }
+func (m *mockUserService2) GetAllUsers(_ context.Context) ([]models.User, error)    { return nil, nil }
+func (m *mockUserService2) UpdateUser(_ context.Context, id int, name *string, isActive *bool, maxOpenReviews *int) (*models.User, error) {
+	return nil, nil
+}
+func (m *mockUserService2) BulkDeactivateTeam(_ context.Context, teamName string) (*dto.BulkDeactivateTeamResponse, error) {
 	return nil, nil
 }
-func (m *mockUserService2) GetUser(id int) (*models.User, error) { return nil, nil }
-func (m *mockUserService2) GetAllUsers() ([]models.User, error)  { return nil, nil }
-func (m *mockUserService2) UpdateUser(id int, name *string, isActive *bool) (*models.User, error) {
+func (m *mockUserService2) AddUnavailability(_ context.Context, userID int, startsAt, endsAt time.Time, reason string) (*models.Unavailability, error) {
 	return nil, nil
 }
-func (m *mockUserService2) BulkDeactivateTeam(teamName string) (*dto.BulkDeactivateTeamResponse, error) {
+func (m *mockUserService2) GetUnavailabilities(_ context.Context, userID int) ([]models.Unavailability, error) {
+	return nil, nil
+}
+func (m *mockUserService2) DeleteUnavailability(_ context.Context, userID int, id int) error {
+	return nil
+}
+func (m *mockUserService2) AddIdentity(_ context.Context, userID int, provider models.IdentityProvider, externalID string) (*models.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockUserService2) GetIdentities(_ context.Context, userID int) ([]models.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockUserService2) DeleteIdentity(_ context.Context, userID int, id int) error { return nil }
+func (m *mockUserService2) GetUserByIdentity(_ context.Context, provider models.IdentityProvider, externalID string) (*models.User, error) {
 	return nil, nil
 }
 
-type mockTeamService2 struct{}
+type mockTeamService2 struct {
+	roles map[int]models.TeamRole
+}
 
-func (m *mockTeamService2) CreateTeam(name string) (*models.Team, error) { return nil, nil }
-func (m *mockTeamService2) GetTeam(name string) (*models.Team, error)    { return nil, nil }
-func (m *mockTeamService2) GetAllTeams() ([]models.Team, error)          { return nil, nil }
-func (m *mockTeamService2) AddMember(teamName string, userID int) (*models.Team, error) {
+func (m *mockTeamService2) CreateTeam(_ context.Context, name string) (*models.Team, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) GetTeam(_ context.Context, name string) (*models.Team, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) GetAllTeams(_ context.Context) ([]models.Team, error) { return nil, nil }
+func (m *mockTeamService2) AddMember(_ context.Context, teamName string, userID int) (*models.Team, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) RemoveMember(_ context.Context, teamName string, userID int) error {
+	return nil
+}
+func (m *mockTeamService2) GetSettings(_ context.Context, teamName string) (*models.TeamSettings, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) UpdateSettings(_ context.Context, settings *models.TeamSettings) (*models.TeamSettings, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) GetUserTeams(_ context.Context, userID int) ([]models.TeamMembership, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) SetPrimaryTeam(_ context.Context, userID int, teamName string) ([]models.TeamMembership, error) {
 	return nil, nil
 }
-func (m *mockTeamService2) RemoveMember(teamName string, userID int) error { return nil }
+func (m *mockTeamService2) SetMemberRole(_ context.Context, teamName string, userID int, role models.TeamRole) (*models.Team, error) {
+	return nil, nil
+}
+func (m *mockTeamService2) GetMemberRole(_ context.Context, teamName string, userID int) (models.TeamRole, error) {
+	return m.roles[userID], nil
+}
 
 type mockStatsService2 struct{}
 
-func (m *mockStatsService2) GetStats() (*dto.StatsResponse, error) {
+func (m *mockStatsService2) GetStats(_ context.Context) (*dto.StatsResponse, error) {
 	return &dto.StatsResponse{}, nil
 }
 
+type mockWebhookService2 struct{}
+
+func (m *mockWebhookService2) CreateSubscription(_ context.Context, url, secret string, events []models.WebhookEventType) (*models.WebhookSubscription, error) {
+	return nil, nil
+}
+func (m *mockWebhookService2) GetSubscription(_ context.Context, id int) (*models.WebhookSubscription, error) {
+	return nil, nil
+}
+func (m *mockWebhookService2) GetSubscriptions(_ context.Context) ([]models.WebhookSubscription, error) {
+	return nil, nil
+}
+func (m *mockWebhookService2) DeleteSubscription(_ context.Context, id int) error { return nil }
+func (m *mockWebhookService2) GetDeliveries(_ context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+type mockIntegrationService2 struct {
+	err error
+}
+
+func (m *mockIntegrationService2) HandleGitHub(_ context.Context, eventType, signature string, body []byte) (*dto.IntegrationResponse, error) {
+	return nil, m.err
+}
+func (m *mockIntegrationService2) HandleGitLab(_ context.Context, eventType, token string, body []byte) (*dto.IntegrationResponse, error) {
+	return nil, nil
+}
+
+type mockOwnershipService2 struct{}
+
+func (m *mockOwnershipService2) CreateRule(_ context.Context, pattern, teamName string, userID *int) (*models.OwnershipRule, error) {
+	return nil, nil
+}
+func (m *mockOwnershipService2) GetRules(_ context.Context) ([]models.OwnershipRule, error) {
+	return nil, nil
+}
+func (m *mockOwnershipService2) DeleteRule(_ context.Context, id int) error { return nil }
+
+type mockHealthService2 struct {
+	readiness models.HealthReport
+}
+
+func (m *mockHealthService2) Liveness() models.HealthReport {
+	return models.HealthReport{Status: models.HealthStatusUp}
+}
+func (m *mockHealthService2) Readiness(_ context.Context) models.HealthReport { return m.readiness }
+
 func TestRespondJSON2(t *testing.T) {
-	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{})
+	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
 
 	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	data := map[string]string{"test": "value"}
 
-	handler.respondJSON(rec, http.StatusOK, data)
+	handler.respondJSON(rec, req, http.StatusOK, data)
 
 	if rec.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", rec.Code)
@@ -64,13 +195,306 @@ func TestRespondJSON2(t *testing.T) {
 }
 
 func TestRespondError2(t *testing.T) {
-	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{})
+	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
 
 	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-	handler.respondError(rec, http.StatusBadRequest, "test error")
+	handler.respondError(rec, req, http.StatusBadRequest, "test error")
 
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected status 400, got %d", rec.Code)
 	}
 }
+
+func TestRespondInternalError_EchoesRequestID(t *testing.T) {
+	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/prs/1", nil)
+	req = req.WithContext(logging.WithRequestID(req.Context(), "req-1"))
+
+	handler.respondInternalError(rec, req, errors.New("connection refused"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["request_id"] != "req-1" {
+		t.Errorf("expected request_id req-1, got %q", body["request_id"])
+	}
+	if body["error"] != "internal server error" {
+		t.Errorf("expected the cause to be hidden, got %q", body["error"])
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   models.HealthStatus
+		wantCode int
+	}{
+		{name: "ready", status: models.HealthStatusUp, wantCode: http.StatusOK},
+		{name: "not ready", status: models.HealthStatusDown, wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &mockHealthService2{readiness: models.HealthReport{Status: tt.status}}
+			handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, health)
+
+			rec := httptest.NewRecorder()
+			handler.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestSubmitReview_OnlyReviewer(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		wantCode  int
+	}{
+		{name: "authentication disabled", principal: nil, wantCode: http.StatusOK},
+		{name: "reviewer", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusOK},
+		{name: "another user", principal: &auth.Principal{Subject: "bob", Role: auth.RoleMember, UserID: 8}, wantCode: http.StatusForbidden},
+		{name: "admin", principal: &auth.Principal{Subject: "root", Role: auth.RoleAdmin}, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+			req := httptest.NewRequest(http.MethodPost, "/prs/1/reviews", strings.NewReader(`{"reviewer_id":7,"state":"APPROVED"}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			handler.SubmitReview(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMergePR_ForceRequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		principal *auth.Principal
+		wantCode  int
+	}{
+		{name: "authentication disabled", query: "?force=true", principal: nil, wantCode: http.StatusOK},
+		{name: "admin", query: "?force=true", principal: &auth.Principal{Subject: "root", Role: auth.RoleAdmin}, wantCode: http.StatusOK},
+		{name: "member", query: "?force=true", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusForbidden},
+		{name: "bot", query: "?force=true", principal: &auth.Principal{Subject: "ci", Role: auth.RoleBot}, wantCode: http.StatusForbidden},
+		{name: "member without force", query: "", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := &mockPRService2{pr: &models.PR{ID: 1, AuthorID: 7}}
+			handler := NewHandlers(prs, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+			req := httptest.NewRequest(http.MethodPost, "/prs/1/merge"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			handler.MergePR(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPRMutations_RequireAccess(t *testing.T) {
+	prs := &mockPRService2{pr: &models.PR{ID: 1, AuthorID: 7, TeamName: "backend", Reviewers: []int{8}}}
+	teams := &mockTeamService2{roles: map[int]models.TeamRole{9: models.TeamRoleLead, 10: models.TeamRoleMember}}
+	handler := NewHandlers(prs, &mockUserService2{}, teams, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+	routes := []struct {
+		name    string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{name: "close", handler: handler.ClosePR},
+		{name: "reopen", handler: handler.ReopenPR},
+		{name: "ready", handler: handler.MarkPRReady},
+		{name: "merge", handler: handler.MergePR},
+		{name: "reassign", body: `{"old_reviewer_id":8}`, handler: handler.ReassignReviewer},
+	}
+	principals := []struct {
+		name      string
+		principal *auth.Principal
+		wantCode  int
+	}{
+		{name: "authentication disabled", principal: nil, wantCode: http.StatusOK},
+		{name: "author", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusOK},
+		{name: "reviewer", principal: &auth.Principal{Subject: "bob", Role: auth.RoleMember, UserID: 8}, wantCode: http.StatusOK},
+		{name: "team lead", principal: &auth.Principal{Subject: "carol", Role: auth.RoleMember, UserID: 9}, wantCode: http.StatusOK},
+		{name: "team member", principal: &auth.Principal{Subject: "dave", Role: auth.RoleMember, UserID: 10}, wantCode: http.StatusForbidden},
+		{name: "outsider", principal: &auth.Principal{Subject: "eve", Role: auth.RoleMember, UserID: 11}, wantCode: http.StatusForbidden},
+		{name: "team-lead client outside the team", principal: &auth.Principal{Subject: "frank", Role: auth.RoleTeamLead, UserID: 12}, wantCode: http.StatusForbidden},
+		{name: "member without user", principal: &auth.Principal{Subject: "svc", Role: auth.RoleMember}, wantCode: http.StatusForbidden},
+		{name: "bot", principal: &auth.Principal{Subject: "ci", Role: auth.RoleBot}, wantCode: http.StatusOK},
+		{name: "admin", principal: &auth.Principal{Subject: "root", Role: auth.RoleAdmin}, wantCode: http.StatusOK},
+	}
+
+	for _, route := range routes {
+		for _, tt := range principals {
+			t.Run(route.name+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/prs/1/"+route.name, strings.NewReader(route.body))
+				req = mux.SetURLVars(req, map[string]string{"id": "1"})
+				if tt.principal != nil {
+					req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+				}
+				rec := httptest.NewRecorder()
+				route.handler(rec, req)
+
+				if rec.Code != tt.wantCode {
+					t.Errorf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestPRMutations_PRNotFound(t *testing.T) {
+	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+	req := httptest.NewRequest(http.MethodPost, "/prs/1/close", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}))
+	rec := httptest.NewRecorder()
+	handler.ClosePR(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPreviewPR_OnlyOnBehalfOfCaller(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		wantCode  int
+	}{
+		{name: "authentication disabled", principal: nil, wantCode: http.StatusOK},
+		{name: "author", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusOK},
+		{name: "another user", principal: &auth.Principal{Subject: "bob", Role: auth.RoleMember, UserID: 8}, wantCode: http.StatusForbidden},
+		{name: "bot", principal: &auth.Principal{Subject: "ci", Role: auth.RoleBot}, wantCode: http.StatusOK},
+		{name: "admin", principal: &auth.Principal{Subject: "root", Role: auth.RoleAdmin}, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+			req := httptest.NewRequest(http.MethodPost, "/prs/preview", strings.NewReader(`{"author_id":7}`))
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			handler.PreviewPR(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBulkDeactivateTeam_RequiresTeamLead(t *testing.T) {
+	teams := &mockTeamService2{roles: map[int]models.TeamRole{7: models.TeamRoleLead, 8: models.TeamRoleMember}}
+	handler := NewHandlers(&mockPRService2{}, &mockUserService2{}, teams, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{}, &mockOwnershipService2{}, &mockHealthService2{})
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		wantCode  int
+	}{
+		{name: "authentication disabled", principal: nil, wantCode: http.StatusOK},
+		{name: "admin", principal: &auth.Principal{Subject: "root", Role: auth.RoleAdmin}, wantCode: http.StatusOK},
+		{name: "team lead", principal: &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}, wantCode: http.StatusOK},
+		{name: "team member", principal: &auth.Principal{Subject: "bob", Role: auth.RoleTeamLead, UserID: 8}, wantCode: http.StatusForbidden},
+		{name: "not in team", principal: &auth.Principal{Subject: "carol", Role: auth.RoleMember, UserID: 9}, wantCode: http.StatusForbidden},
+		{name: "bot", principal: &auth.Principal{Subject: "ci", Role: auth.RoleBot}, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/teams/backend/deactivate", nil)
+			req = mux.SetURLVars(req, map[string]string{"name": "backend"})
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+			handler.BulkDeactivateTeam(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReviewerShortage_ErrorCode(t *testing.T) {
+	endpoints := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		call   func(h *Handlers, w http.ResponseWriter, r *http.Request)
+	}{
+		{name: "create", method: http.MethodPost, path: "/prs", body: `{"title":"t","author_id":1}`, call: (*Handlers).CreatePR},
+		{name: "preview", method: http.MethodPost, path: "/prs/preview", body: `{"author_id":1}`, call: (*Handlers).PreviewPR},
+		{name: "reassign", method: http.MethodPost, path: "/prs/1/reassign", body: `{"old_reviewer_id":2}`, call: (*Handlers).ReassignReviewer},
+		{name: "reopen", method: http.MethodPost, path: "/prs/1/reopen", call: (*Handlers).ReopenPR},
+		{name: "integration", method: http.MethodPost, path: "/integrations/github", body: `{}`, call: (*Handlers).GitHubWebhook},
+	}
+	errs := []struct {
+		err  error
+		code string
+	}{
+		{err: service.ErrInsufficientReviewers, code: dto.ErrorCodeInsufficientReviewers},
+		{err: service.ErrReviewersAtCapacity, code: dto.ErrorCodeReviewersAtCapacity},
+	}
+
+	for _, e := range endpoints {
+		for _, tt := range errs {
+			t.Run(e.name+"/"+tt.code, func(t *testing.T) {
+				handler := NewHandlers(&mockPRService2{err: tt.err}, &mockUserService2{}, &mockTeamService2{}, &mockStatsService2{}, &mockWebhookService2{}, &mockIntegrationService2{err: tt.err}, &mockOwnershipService2{}, &mockHealthService2{})
+
+				req := httptest.NewRequest(e.method, e.path, strings.NewReader(e.body))
+				req = mux.SetURLVars(req, map[string]string{"id": "1"})
+				rec := httptest.NewRecorder()
+				e.call(handler, rec, req)
+
+				if rec.Code != http.StatusConflict {
+					t.Fatalf("expected status 409, got %d: %s", rec.Code, rec.Body.String())
+				}
+				var body dto.ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if body.Code != tt.code {
+					t.Errorf("expected code %q, got %q", tt.code, body.Code)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// Healthz godoc
+// @Summary Проверка liveness
+// @Description Сообщает, что процесс запущен. Зависимости не проверяются
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Router /healthz [get]
+func (h *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, r, http.StatusOK, h.healthService.Liveness())
+}
+
+// Readyz godoc
+// @Summary Проверка readiness
+// @Description Проверяет доступность БД, версию схемы и работу фоновых задач. Во время остановки сервиса возвращает 503
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Failure 503 {object} models.HealthReport
+// @Router /readyz [get]
+func (h *Handlers) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.healthService.Readiness(r.Context())
+	if report.Status != models.HealthStatusUp {
+		h.respondJSON(w, r, http.StatusServiceUnavailable, report)
+		return
+	}
+	h.respondJSON(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+)
+
+// maxIntegrationPayloadSize ограничивает размер тела вебхука провайдера
+const maxIntegrationPayloadSize = 10 << 20
+
+// GitHubWebhook godoc
+// @Summary Вебхук GitHub
+// @Description Принимает события pull_request из GitHub (подпись X-Hub-Signature-256): opened создает PR, closed закрывает или мержит его, reopened и ready_for_review снова открывают. Автор определяется по привязанному логину GitHub. Повторная доставка события не меняет PR, события, недопустимые для текущего статуса PR (например, closed для смерженного), игнорируются
+// @Tags Integrations
+// @Accept json
+// @Produce json
+// @Param X-GitHub-Event header string true "Тип события"
+// @Param X-Hub-Signature-256 header string true "Подпись тела запроса"
+// @Success 200 {object} dto.IntegrationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse "Неверная подпись"
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse "Автор не привязан к пользователю"
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse "Интеграция не настроена"
+// @Router /integrations/github [post]
+func (h *Handlers) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	h.handleIntegration(w, r, func(body []byte) (*dto.IntegrationResponse, error) {
+		return h.integrationService.HandleGitHub(r.Context(), r.Header.Get(service.GitHubEventHeader), r.Header.Get(service.GitHubSignatureHeader), body)
+	})
+}
+
+// GitLabWebhook godoc
+// @Summary Вебхук GitLab
+// @Description Принимает события Merge Request Hook из GitLab (токен X-Gitlab-Token): open создает PR, close и merge закрывают или мержат его, reopen и снятие draft снова открывают. Автор определяется по привязанному имени пользователя GitLab. Повторная доставка события не меняет PR, события, недопустимые для текущего статуса PR (например, close для смерженного), игнорируются
+// @Tags Integrations
+// @Accept json
+// @Produce json
+// @Param X-Gitlab-Event header string true "Тип события"
+// @Param X-Gitlab-Token header string true "Секретный токен"
+// @Success 200 {object} dto.IntegrationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse "Неверный токен"
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse "Автор не привязан к пользователю"
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse "Интеграция не настроена"
+// @Router /integrations/gitlab [post]
+func (h *Handlers) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	h.handleIntegration(w, r, func(body []byte) (*dto.IntegrationResponse, error) {
+		return h.integrationService.HandleGitLab(r.Context(), r.Header.Get(service.GitLabEventHeader), r.Header.Get(service.GitLabTokenHeader), body)
+	})
+}
+
+// handleIntegration читает тело вебхука провайдера, передает его в handle и отображает ошибки в HTTP-статусы
+func (h *Handlers) handleIntegration(w http.ResponseWriter, r *http.Request, handle func(body []byte) (*dto.IntegrationResponse, error)) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIntegrationPayloadSize))
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := handle(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIntegrationNotConfigured):
+			h.respondError(w, r, http.StatusServiceUnavailable, err.Error())
+		case errors.Is(err, service.ErrInvalidSignature):
+			h.respondError(w, r, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, service.ErrInvalidPayload):
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrIdentityNotFound),
+			errors.Is(err, service.ErrAuthorNotInTeam):
+			h.respondError(w, r, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrInvalidStatusTransition),
+			errors.Is(err, service.ErrInsufficientReviewers),
+			errors.Is(err, service.ErrReviewersAtCapacity):
+			h.respondServiceError(w, r, http.StatusConflict, err)
+		default:
+			h.respondInternalError(w, r, err)
+		}
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, result)
+}
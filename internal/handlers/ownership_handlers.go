@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// CreateOwnershipRule godoc
+// @Summary Добавить правило владения кодом
+// @Description Файлы, подходящие под шаблон в синтаксисе CODEOWNERS, принадлежат команде или пользователю; владельцы измененных файлов назначаются ревьюверами PR в первую очередь. Если файлу подходят несколько правил, действует последнее созданное
+// @Tags Ownership
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateOwnershipRuleRequest true "Правило владения"
+// @Success 201 {object} models.OwnershipRule
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse "Команда или пользователь не найдены"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /ownership [post]
+func (h *Handlers) CreateOwnershipRule(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOwnershipRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	rule, err := h.ownershipService.CreateRule(r.Context(), req.Pattern, req.TeamName, req.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidOwnershipRule):
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrTeamNotFound), errors.Is(err, service.ErrUserNotFound):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		default:
+			h.respondInternalError(w, r, err)
+		}
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusCreated, rule)
+}
+
+// ListOwnershipRules godoc
+// @Summary Получить правила владения кодом
+// @Description Возвращает правила владения в порядке создания
+// @Tags Ownership
+// @Produce json
+// @Success 200 {array} models.OwnershipRule
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /ownership [get]
+func (h *Handlers) ListOwnershipRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.ownershipService.GetRules(r.Context())
+	if err != nil {
+		h.respondInternalError(w, r, err)
+		return
+	}
+	h.respondJSON(w, r, http.StatusOK, rules)
+}
+
+// DeleteOwnershipRule godoc
+// @Summary Удалить правило владения кодом
+// @Description Удаляет правило владения; уже назначенные ревьюверы остаются на PR
+// @Tags Ownership
+// @Produce json
+// @Param id path int true "ID правила"
+// @Success 200 {object} dto.MessageResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /ownership/{id} [delete]
+func (h *Handlers) DeleteOwnershipRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid ownership rule ID")
+		return
+	}
+
+	if err := h.ownershipService.DeleteRule(r.Context(), id); err != nil {
+		if errors.Is(err, service.ErrOwnershipRuleNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, dto.MessageResponse{Message: "ownership rule deleted"})
+}
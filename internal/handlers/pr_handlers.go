@@ -1,52 +1,142 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
 	"github.com/Rodjolo/pr-reviewer-service/internal/service"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/validator"
 	"github.com/gorilla/mux"
 )
 
 // CreatePR godoc
 // @Summary Создать Pull Request
-// @Description Создает новый PR и автоматически назначает до 2 ревьюверов из команды автора
+// @Description Создает новый PR и автоматически назначает ревьюверов из команды автора (по умолчанию до 2).
+// @Description В ответе candidate_loads показывает, сколько открытых PR ревьюил каждый кандидат на момент назначения,
+// @Description exclusions - какие участники команд не стали кандидатами и почему (author, inactive, ooo, at_capacity).
+// @Description Если переданы changed_files, сначала назначаются владельцы затронутых путей по правилам /ownership, оставшиеся места заполняются из команды.
+// @Description Если у команды заданы пулы ревьюверов, дополнительно назначаются ревьюверы из команд пулов (pool_team в reviews).
+// @Description В ответе assignments объясняет, каким правилом выбран каждый ревьювер. Объяснение сохраняется вместе с PR.
+// @Description С draft=true создается черновик без ревьюверов.
+// @Description team_name выбирает команду, из которой назначаются ревьюверы (автор должен в ней состоять); по умолчанию - основная команда автора.
+// @Description Пользователи создают PR только от своего имени, администраторы и боты - от имени любого автора
 // @Tags PR
 // @Accept json
 // @Produce json
 // @Param request body dto.CreatePRRequest true "Данные PR"
 // @Success 201 {object} models.PR
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не может создать PR от имени другого автора"
 // @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Недостаточно кандидатов в команде или пуле ревьюверов (code insufficient_reviewers), либо все они достигли лимита открытых ревью (code reviewers_at_capacity)"
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /prs [post]
 func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreatePRRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
 		return
 	}
 
-	pr, err := h.prService.CreatePR(req.Title, req.AuthorID)
+	// Администраторы и боты создают PR от имени любого автора, остальные - только от своего
+	if !actsAs(r, req.AuthorID, auth.RoleAdmin, auth.RoleBot) {
+		h.respondError(w, r, http.StatusForbidden, "PR can only be created on behalf of the caller")
+		return
+	}
+
+	var pr *models.PR
+	var err error
+	if req.Draft {
+		pr, err = h.prService.CreateDraftPR(r.Context(), req.Title, req.AuthorID, req.TeamName, req.ChangedFiles)
+	} else {
+		pr, err = h.prService.CreatePR(r.Context(), req.Title, req.AuthorID, req.TeamName, req.ChangedFiles)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrAuthorNotFound) || errors.Is(err, service.ErrAuthorNotInTeam) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrAuthorNotTeamMember) {
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrReviewersAtCapacity) || errors.Is(err, service.ErrInsufficientReviewers) {
+			h.respondServiceError(w, r, http.StatusConflict, err)
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusCreated, pr)
+}
+
+// PreviewPR godoc
+// @Summary Предпросмотр назначения ревьюверов
+// @Description Подбирает ревьюверов так же, как POST /prs, но ничего не сохраняет и не сдвигает очередь round-robin.
+// @Description Возвращает выбранных ревьюверов, кандидатов с нагрузкой (candidate_loads), исключенных участников с причинами (exclusions)
+// @Description и правило, которым выбран каждый ревьювер (assignments). При случайных стратегиях реальный выбор может отличаться.
+// @Description seed воспроизводит назначение, сделанное с тем же зерном (assignment_seed в PR или зерно из лога).
+// @Description Пользователи просматривают назначение только для своих PR, администраторы и боты - для любого автора
+// @Tags PR
+// @Accept json
+// @Produce json
+// @Param request body dto.PreviewPRRequest true "Автор, команда и измененные файлы"
+// @Success 200 {object} models.ReviewerPreview
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не может просматривать назначение от имени другого автора"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Недостаточно кандидатов в команде или пуле ревьюверов (code insufficient_reviewers), либо все они достигли лимита открытых ревью (code reviewers_at_capacity)"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/preview [post]
+func (h *Handlers) PreviewPR(w http.ResponseWriter, r *http.Request) {
+	var req dto.PreviewPRRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	// Предпросмотр раскрывает кандидатов и их нагрузку, поэтому доступен тем же клиентам, что и создание PR
+	if !actsAs(r, req.AuthorID, auth.RoleAdmin, auth.RoleBot) {
+		h.respondError(w, r, http.StatusForbidden, "PR can only be previewed on behalf of the caller")
+		return
+	}
+
+	preview, err := h.prService.PreviewPR(r.Context(), req.AuthorID, req.TeamName, req.ChangedFiles, req.Seed)
 	if err != nil {
 		if errors.Is(err, service.ErrAuthorNotFound) || errors.Is(err, service.ErrAuthorNotInTeam) {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrAuthorNotTeamMember) {
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrReviewersAtCapacity) || errors.Is(err, service.ErrInsufficientReviewers) {
+			h.respondServiceError(w, r, http.StatusConflict, err)
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusCreated, pr)
+	h.respondJSON(w, r, http.StatusOK, preview)
 }
 
 // GetPR godoc
@@ -63,21 +153,53 @@ func (h *Handlers) GetPR(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid PR ID")
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
 		return
 	}
 
-	pr, err := h.prService.GetPR(id)
+	pr, err := h.prService.GetPR(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, service.ErrPRNotFound) {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, pr)
+	h.respondJSON(w, r, http.StatusOK, pr)
+}
+
+// GetPREvents godoc
+// @Summary Получить историю PR
+// @Description Возвращает события PR в порядке возникновения: создание, назначение и снятие ревьюверов (с причиной manual, deactivation, ooo или unavailable), смена статуса и мерж
+// @Tags PR
+// @Produce json
+// @Param id path int true "ID PR"
+// @Success 200 {array} models.PREvent
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/{id}/events [get]
+func (h *Handlers) GetPREvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
+		return
+	}
+
+	events, err := h.prService.GetPREvents(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrPRNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, events)
 }
 
 // ListPRs godoc
@@ -95,30 +217,31 @@ func (h *Handlers) ListPRs(w http.ResponseWriter, r *http.Request) {
 	if userIDStr != "" {
 		userID, err := strconv.Atoi(userIDStr)
 		if err != nil {
-			h.respondError(w, http.StatusBadRequest, "invalid user_id")
+			h.respondError(w, r, http.StatusBadRequest, "invalid user_id")
 			return
 		}
 
-		prs, err := h.prService.GetPRsByUserID(userID)
+		prs, err := h.prService.GetPRsByUserID(r.Context(), userID)
 		if err != nil {
-			h.respondError(w, http.StatusInternalServerError, "internal server error")
+			h.respondInternalError(w, r, err)
 			return
 		}
-		h.respondJSON(w, http.StatusOK, prs)
+		h.respondJSON(w, r, http.StatusOK, prs)
 		return
 	}
 
-	prs, err := h.prService.GetAllPRs()
+	prs, err := h.prService.GetAllPRs(r.Context())
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondInternalError(w, r, err)
 		return
 	}
-	h.respondJSON(w, http.StatusOK, prs)
+	h.respondJSON(w, r, http.StatusOK, prs)
 }
 
 // ReassignReviewer godoc
 // @Summary Переназначить ревьювера
-// @Description Заменяет одного ревьювера на случайного активного участника из команды заменяемого ревьювера
+// @Description Заменяет одного ревьювера на случайного активного участника из команды заменяемого ревьювера.
+// @Description Переназначать могут автор PR, его ревьюверы, лиды команды PR, администраторы и боты
 // @Tags PR
 // @Accept json
 // @Produce json
@@ -126,75 +249,258 @@ func (h *Handlers) ListPRs(w http.ResponseWriter, r *http.Request) {
 // @Param request body dto.ReassignRequest true "Данные для переназначения"
 // @Success 200 {object} models.PR
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не автор, не ревьювер и не лид команды PR"
 // @Failure 404 {object} dto.ErrorResponse
-// @Failure 409 {object} dto.ErrorResponse "PR уже мержен"
+// @Failure 409 {object} dto.ErrorResponse "PR не открыт, ревьювер уже одобрил PR или все кандидаты достигли лимита открытых ревью"
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /prs/{id}/reassign [patch]
 func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	prID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid PR ID")
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
 		return
 	}
 
 	var req dto.ReassignRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	if !h.authorizePR(w, r, prID) {
 		return
 	}
 
-	pr, err := h.prService.ReassignReviewer(prID, req.OldReviewerID)
+	pr, err := h.prService.ReassignReviewer(r.Context(), prID, req.OldReviewerID)
 	if err != nil {
 		if errors.Is(err, service.ErrPRNotFound) || errors.Is(err, service.ErrReviewerNotAssigned) || errors.Is(err, service.ErrReviewerNotInTeam) || errors.Is(err, service.ErrNoAvailableReviewers) {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		if errors.Is(err, service.ErrPRAlreadyMerged) {
-			h.respondError(w, http.StatusConflict, err.Error())
+		if errors.Is(err, service.ErrPRAlreadyMerged) || errors.Is(err, service.ErrPRNotOpen) || errors.Is(err, service.ErrReviewersAtCapacity) || errors.Is(err, service.ErrInsufficientReviewers) || errors.Is(err, service.ErrReviewerApproved) {
+			h.respondServiceError(w, r, http.StatusConflict, err)
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, pr)
+	h.respondJSON(w, r, http.StatusOK, pr)
 }
 
 // MergePR godoc
 // @Summary Мержить PR
-// @Description Изменяет статус PR на MERGED. После мержа изменения ревьюверов запрещены
+// @Description Изменяет статус PR на MERGED, если PR набрал требуемое командой количество одобрений и никто не запросил изменения. Параметр force позволяет смержить PR в обход проверки, это фиксируется в поле force_merged. После мержа изменения ревьюверов запрещены.
+// @Description Мержить могут автор PR, его ревьюверы, лиды команды PR, администраторы и боты, а с force - только администраторы
 // @Tags PR
 // @Produce json
 // @Param id path int true "ID PR"
+// @Param force query bool false "Смержить в обход проверки одобрений (только для администраторов)"
 // @Success 200 {object} models.PR
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} dto.ErrorResponse "Принудительный мерж запрошен не администратором или клиент не автор, не ревьювер и не лид команды PR"
 // @Failure 404 {object} map[string]string
-// @Failure 409 {object} map[string]string "PR уже мержен"
+// @Failure 409 {object} dto.MergeBlockedResponse "Не хватает одобрений, запрошены изменения или PR не открыт"
 // @Failure 500 {object} map[string]string
 // @Router /prs/{id}/merge [post]
 func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid PR ID")
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
+		return
+	}
+
+	force := false
+	if forceStr := r.URL.Query().Get("force"); forceStr != "" {
+		force, err = strconv.ParseBool(forceStr)
+		if err != nil {
+			h.respondError(w, r, http.StatusBadRequest, "invalid force")
+			return
+		}
+	}
+	// Мерж в обход проверки одобрений доступен только администраторам
+	if p := auth.FromContext(r.Context()); force && p != nil && !p.HasRole(auth.RoleAdmin) {
+		h.respondError(w, r, http.StatusForbidden, "only admins can force merge a PR")
+		return
+	}
+	if !h.authorizePR(w, r, id) {
 		return
 	}
 
-	pr, err := h.prService.MergePR(id)
+	pr, err := h.prService.MergePR(r.Context(), id, force)
 	if err != nil {
+		var blocked *service.MergeBlockedError
+		if errors.As(err, &blocked) {
+			h.respondJSON(w, r, http.StatusConflict, dto.MergeBlockedResponse{
+				Error:              err.Error(),
+				PendingReviewers:   blocked.PendingReviewers,
+				ChangesRequestedBy: blocked.ChangesRequestedBy,
+				RequiredApprovals:  blocked.RequiredApprovals,
+				Approvals:          blocked.Approvals,
+				MissingApprovals:   blocked.MissingApprovals(),
+			})
+			return
+		}
 		if errors.Is(err, service.ErrPRNotFound) {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidStatusTransition) {
+			h.respondError(w, r, http.StatusConflict, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, "internal server error")
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, pr)
+}
+
+// SubmitReview godoc
+// @Summary Оставить вердикт ревьювера
+// @Description Сохраняет вердикт назначенного ревьювера по открытому PR: APPROVED или CHANGES_REQUESTED. Повторный вызов заменяет предыдущий вердикт.
+// @Description Вердикт может оставить только сам ревьювер: reviewer_id должен совпадать с пользователем клиента (claim user_id)
+// @Tags PR
+// @Accept json
+// @Produce json
+// @Param id path int true "ID PR"
+// @Param request body dto.SubmitReviewRequest true "Вердикт ревьювера"
+// @Success 200 {object} models.PR
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Пользователь не назначен ревьювером PR или клиент не является этим ревьювером"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "PR не открыт"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/{id}/reviews [post]
+func (h *Handlers) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	prID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
+		return
+	}
+
+	var req dto.SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	if !actsAs(r, req.ReviewerID) {
+		h.respondError(w, r, http.StatusForbidden, "only the reviewer can submit their verdict")
+		return
+	}
+
+	pr, err := h.prService.SubmitReview(r.Context(), prID, req.ReviewerID, models.ReviewState(req.State))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPRNotFound):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrNotPRReviewer):
+			h.respondError(w, r, http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrReviewOnMergedPR), errors.Is(err, service.ErrPRNotOpen):
+			h.respondError(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrInvalidReviewState):
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			h.respondInternalError(w, r, err)
+		}
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, pr)
+}
+
+// ClosePR godoc
+// @Summary Закрыть PR
+// @Description Закрывает открытый PR или черновик без мержа. Закрытый PR можно открыть снова.
+// @Description Закрывать могут автор PR, его ревьюверы, лиды команды PR, администраторы и боты
+// @Tags PR
+// @Produce json
+// @Param id path int true "ID PR"
+// @Success 200 {object} models.PR
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не автор, не ревьювер и не лид команды PR"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Недопустимый переход статуса"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/{id}/close [post]
+func (h *Handlers) ClosePR(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prService.ClosePR)
+}
+
+// ReopenPR godoc
+// @Summary Открыть закрытый PR
+// @Description Снова открывает закрытый PR. Ревьюверы, ставшие недоступными, заменяются, недостающие назначаются по правилам создания PR.
+// @Description Открывать могут автор PR, его ревьюверы, лиды команды PR, администраторы и боты
+// @Tags PR
+// @Produce json
+// @Param id path int true "ID PR"
+// @Success 200 {object} models.PR
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не автор, не ревьювер и не лид команды PR"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Недопустимый переход статуса или не хватает доступных ревьюверов"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/{id}/reopen [post]
+func (h *Handlers) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prService.ReopenPR)
+}
+
+// MarkPRReady godoc
+// @Summary Перевести черновик в работу
+// @Description Переводит черновик PR в статус OPEN и назначает ревьюверов по правилам создания PR.
+// @Description Переводить могут автор PR, лиды команды PR, администраторы и боты
+// @Tags PR
+// @Produce json
+// @Param id path int true "ID PR"
+// @Success 200 {object} models.PR
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не автор, не ревьювер и не лид команды PR"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Недопустимый переход статуса или не хватает доступных ревьюверов"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /prs/{id}/ready [post]
+func (h *Handlers) MarkPRReady(w http.ResponseWriter, r *http.Request) {
+	h.transitionPR(w, r, h.prService.MarkReady)
+}
+
+// transitionPR проверяет доступ клиента к PR, выполняет переход статуса и отображает ошибки сервиса в HTTP статусы
+func (h *Handlers) transitionPR(w http.ResponseWriter, r *http.Request, transition func(ctx context.Context, id int) (*models.PR, error)) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid PR ID")
+		return
+	}
+	if !h.authorizePR(w, r, id) {
+		return
+	}
+
+	pr, err := transition(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPRNotFound), errors.Is(err, service.ErrAuthorNotFound), errors.Is(err, service.ErrAuthorNotInTeam):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, service.ErrInsufficientReviewers), errors.Is(err, service.ErrReviewersAtCapacity):
+			h.respondServiceError(w, r, http.StatusConflict, err)
+		default:
+			h.respondInternalError(w, r, err)
+		}
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, pr)
+	h.respondJSON(w, r, http.StatusOK, pr)
 }
@@ -12,11 +12,11 @@ import (
 // @Success 200 {object} map[string]int
 // @Failure 500 {object} map[string]string
 // @Router /stats [get]
-func (h *Handlers) GetStats(w http.ResponseWriter, _ *http.Request) {
-	stats, err := h.statsService.GetStats()
+func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.statsService.GetStats(r.Context())
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
-	h.respondJSON(w, http.StatusOK, stats)
+	h.respondJSON(w, r, http.StatusOK, stats)
 }
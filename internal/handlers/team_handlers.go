@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/validator"
 	"github.com/gorilla/mux"
 )
@@ -25,26 +28,26 @@ import (
 func (h *Handlers) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateTeamRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
 		return
 	}
 
-	team, err := h.teamService.CreateTeam(req.Name)
+	team, err := h.teamService.CreateTeam(r.Context(), req.Name)
 	if err != nil {
 		if err.Error() == "team already exists" {
-			h.respondError(w, http.StatusConflict, err.Error())
+			h.respondError(w, r, http.StatusConflict, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusCreated, team)
+	h.respondJSON(w, r, http.StatusCreated, team)
 }
 
 // GetTeam godoc
@@ -60,17 +63,17 @@ func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	name := vars["name"]
 
-	team, err := h.teamService.GetTeam(name)
+	team, err := h.teamService.GetTeam(r.Context(), name)
 	if err != nil {
 		if err.Error() == "team not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, team)
+	h.respondJSON(w, r, http.StatusOK, team)
 }
 
 // ListTeams godoc
@@ -81,13 +84,13 @@ func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {array} models.Team
 // @Failure 500 {object} map[string]string
 // @Router /teams [get]
-func (h *Handlers) ListTeams(w http.ResponseWriter, _ *http.Request) {
-	teams, err := h.teamService.GetAllTeams()
+func (h *Handlers) ListTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.teamService.GetAllTeams(r.Context())
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
-	h.respondJSON(w, http.StatusOK, teams)
+	h.respondJSON(w, r, http.StatusOK, teams)
 }
 
 // AddTeamMember godoc
@@ -100,35 +103,39 @@ func (h *Handlers) ListTeams(w http.ResponseWriter, _ *http.Request) {
 // @Param request body dto.AddMemberRequest true "ID пользователя"
 // @Success 200 {object} models.Team
 // @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не администратор и не лид команды"
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /teams/{name}/members [post]
 func (h *Handlers) AddTeamMember(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	teamName := vars["name"]
+	if !h.authorizeTeam(w, r, teamName) {
+		return
+	}
 
 	var req dto.AddMemberRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
 		return
 	}
 
-	team, err := h.teamService.AddMember(teamName, req.UserID)
+	team, err := h.teamService.AddMember(r.Context(), teamName, req.UserID)
 	if err != nil {
 		if err.Error() == "team not found" || err.Error() == "user not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, team)
+	h.respondJSON(w, r, http.StatusOK, team)
 }
 
 // RemoveTeamMember godoc
@@ -140,33 +147,189 @@ func (h *Handlers) AddTeamMember(w http.ResponseWriter, r *http.Request) {
 // @Param user_id query int true "ID пользователя"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string "Клиент не администратор и не лид команды"
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /teams/{name}/members [delete]
 func (h *Handlers) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	teamName := vars["name"]
+	if !h.authorizeTeam(w, r, teamName) {
+		return
+	}
 
 	userIDStr := r.URL.Query().Get("user_id")
 	if userIDStr == "" {
-		h.respondError(w, http.StatusBadRequest, "user_id is required")
+		h.respondError(w, r, http.StatusBadRequest, "user_id is required")
 		return
 	}
 
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid user_id")
+		h.respondError(w, r, http.StatusBadRequest, "invalid user_id")
 		return
 	}
 
-	if err := h.teamService.RemoveMember(teamName, userID); err != nil {
+	if err := h.teamService.RemoveMember(r.Context(), teamName, userID); err != nil {
 		if err.Error() == "team not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, dto.MessageResponse{Message: "member removed"})
+}
+
+// GetTeamSettings godoc
+// @Summary Получить настройки команды
+// @Description Возвращает настройки назначения ревьюверов для команды (значения по умолчанию, если команда их не задавала)
+// @Tags Teams
+// @Produce json
+// @Param name path string true "Имя команды"
+// @Success 200 {object} models.TeamSettings
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /teams/{name}/settings [get]
+func (h *Handlers) GetTeamSettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	teamName := vars["name"]
+
+	settings, err := h.teamService.GetSettings(r.Context(), teamName)
+	if err != nil {
+		if errors.Is(err, service.ErrTeamNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, settings)
+}
+
+// UpdateTeamSettings godoc
+// @Summary Обновить настройки команды
+// @Description Задает количество ревьюверов, назначаемых на PR команды, минимум, при котором PR создается с частичным назначением, количество одобрений, необходимое для мержа, и пулы ревьюверов из других команд
+// @Tags Teams
+// @Accept json
+// @Produce json
+// @Param name path string true "Имя команды"
+// @Param request body dto.UpdateTeamSettingsRequest true "Настройки команды"
+// @Success 200 {object} models.TeamSettings
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не администратор и не лид команды"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /teams/{name}/settings [put]
+func (h *Handlers) UpdateTeamSettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	teamName := vars["name"]
+	if !h.authorizeTeam(w, r, teamName) {
+		return
+	}
+
+	var req dto.UpdateTeamSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	settings := &models.TeamSettings{
+		TeamName:          teamName,
+		RequiredReviewers: req.RequiredReviewers,
+		MinReviewers:      req.RequiredReviewers,
+		RequiredApprovals: models.DefaultRequiredApprovals,
+	}
+	if req.MinReviewers != nil {
+		settings.MinReviewers = *req.MinReviewers
+	}
+	if req.RequiredApprovals != nil {
+		settings.RequiredApprovals = *req.RequiredApprovals
+	}
+	settings.ReviewerPools = make([]models.ReviewerPool, len(req.ReviewerPools))
+	for i, pool := range req.ReviewerPools {
+		settings.ReviewerPools[i] = models.ReviewerPool{TeamName: pool.TeamName, Reviewers: pool.Reviewers}
+	}
+
+	settings, err := h.teamService.UpdateSettings(r.Context(), settings)
+	if err != nil {
+		if errors.Is(err, service.ErrTeamNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTeamSettings) || errors.Is(err, service.ErrInvalidReviewerPool) {
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, settings)
+}
+
+// PromoteTeamMember godoc
+// @Summary Назначить участника лидом команды
+// @Description Дает участнику команды роль lead: лиды могут менять состав команды, ее настройки и деактивировать ее участников
+// @Tags Teams
+// @Produce json
+// @Param name path string true "Имя команды"
+// @Param userId path int true "ID пользователя"
+// @Success 200 {object} models.Team
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не администратор и не лид команды"
+// @Failure 404 {object} dto.ErrorResponse "Команда не найдена или пользователь не состоит в ней"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /teams/{name}/members/{userId}/promote [post]
+func (h *Handlers) PromoteTeamMember(w http.ResponseWriter, r *http.Request) {
+	h.setTeamMemberRole(w, r, models.TeamRoleLead)
+}
+
+// DemoteTeamMember godoc
+// @Summary Снять с участника роль лида команды
+// @Description Возвращает участнику команды роль member
+// @Tags Teams
+// @Produce json
+// @Param name path string true "Имя команды"
+// @Param userId path int true "ID пользователя"
+// @Success 200 {object} models.Team
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не администратор и не лид команды"
+// @Failure 404 {object} dto.ErrorResponse "Команда не найдена или пользователь не состоит в ней"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /teams/{name}/members/{userId}/demote [post]
+func (h *Handlers) DemoteTeamMember(w http.ResponseWriter, r *http.Request) {
+	h.setTeamMemberRole(w, r, models.TeamRoleMember)
+}
+
+func (h *Handlers) setTeamMemberRole(w http.ResponseWriter, r *http.Request, role models.TeamRole) {
+	vars := mux.Vars(r)
+	teamName := vars["name"]
+	userID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	if !h.authorizeTeam(w, r, teamName) {
+		return
+	}
+
+	team, err := h.teamService.SetMemberRole(r.Context(), teamName, userID, role)
+	if err != nil {
+		if errors.Is(err, service.ErrTeamNotFound) || errors.Is(err, service.ErrNotTeamMember) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, dto.MessageResponse{Message: "member removed"})
+	h.respondJSON(w, r, http.StatusOK, team)
 }
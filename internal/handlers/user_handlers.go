@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/validator"
 	"github.com/gorilla/mux"
 )
@@ -24,12 +27,12 @@ import (
 func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
 		return
 	}
 
@@ -38,13 +41,13 @@ func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 		isActive = *req.IsActive
 	}
 
-	user, err := h.userService.CreateUser(req.Name, isActive)
+	user, err := h.userService.CreateUser(r.Context(), req.Name, isActive, req.MaxOpenReviews)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusCreated, user)
+	h.respondJSON(w, r, http.StatusCreated, user)
 }
 
 // GetUser godoc
@@ -61,21 +64,21 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid user ID")
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
 		return
 	}
 
-	user, err := h.userService.GetUser(id)
+	user, err := h.userService.GetUser(r.Context(), id)
 	if err != nil {
 		if err.Error() == "user not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, user)
+	h.respondJSON(w, r, http.StatusOK, user)
 }
 
 // ListUsers godoc
@@ -86,13 +89,13 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {array} models.User
 // @Failure 500 {object} map[string]string
 // @Router /users [get]
-func (h *Handlers) ListUsers(w http.ResponseWriter, _ *http.Request) {
-	users, err := h.userService.GetAllUsers()
+func (h *Handlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userService.GetAllUsers(r.Context())
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
-	h.respondJSON(w, http.StatusOK, users)
+	h.respondJSON(w, r, http.StatusOK, users)
 }
 
 // UpdateUser godoc
@@ -112,57 +115,410 @@ func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid user ID")
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
 		return
 	}
 
 	var req dto.UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
 	if err := validator.Validate(&req); err != nil {
-		h.respondError(w, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
 		return
 	}
 
-	user, err := h.userService.UpdateUser(id, req.Name, req.IsActive)
+	user, err := h.userService.UpdateUser(r.Context(), id, req.Name, req.IsActive, req.MaxOpenReviews)
 	if err != nil {
 		if err.Error() == "user not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, user)
+	h.respondJSON(w, r, http.StatusOK, user)
 }
 
 // BulkDeactivateTeam godoc
 // @Summary Массовая деактивация пользователей команды
-// @Description Деактивирует всех пользователей команды и безопасно переназначает ревьюверов в открытых PR
+// @Description Деактивирует всех пользователей команды и безопасно переназначает ревьюверов в открытых PR.
+// @Description Доступно администраторам и лидам команды
 // @Tags Users
 // @Produce json
 // @Param name path string true "Имя команды"
 // @Success 200 {object} dto.BulkDeactivateTeamResponse
+// @Failure 403 {object} dto.ErrorResponse "Клиент не администратор и не лид команды"
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /teams/{name}/deactivate [post]
 func (h *Handlers) BulkDeactivateTeam(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	teamName := vars["name"]
+	if !h.authorizeTeam(w, r, teamName) {
+		return
+	}
 
-	result, err := h.userService.BulkDeactivateTeam(teamName)
+	result, err := h.userService.BulkDeactivateTeam(r.Context(), teamName)
 	if err != nil {
 		if err.Error() == "team not found" {
-			h.respondError(w, http.StatusNotFound, err.Error())
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, result)
+}
+
+// AddUserUnavailability godoc
+// @Summary Добавить период отсутствия пользователя
+// @Description Добавляет период (отпуск, больничный и т.п.), в течение которого пользователь не назначается ревьювером. Когда период начинается, его открытые ревью автоматически переназначаются
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param request body dto.CreateUnavailabilityRequest true "Период отсутствия"
+// @Success 201 {object} models.Unavailability
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/unavailability [post]
+func (h *Handlers) AddUserUnavailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req dto.CreateUnavailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	unavailability, err := h.userService.AddUnavailability(r.Context(), id, req.StartsAt, req.EndsAt, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrInvalidUnavailability):
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			h.respondInternalError(w, r, err)
+		}
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusCreated, unavailability)
+}
+
+// ListUserUnavailability godoc
+// @Summary Получить периоды отсутствия пользователя
+// @Description Возвращает текущие и запланированные периоды отсутствия пользователя
+// @Tags Users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} models.Unavailability
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/unavailability [get]
+func (h *Handlers) ListUserUnavailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	unavailabilities, err := h.userService.GetUnavailabilities(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, unavailabilities)
+}
+
+// DeleteUserUnavailability godoc
+// @Summary Удалить период отсутствия пользователя
+// @Description Удаляет период отсутствия; уже переназначенные ревью обратно не возвращаются
+// @Tags Users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param unavailabilityId path int true "ID периода отсутствия"
+// @Success 200 {object} dto.MessageResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/unavailability/{unavailabilityId} [delete]
+func (h *Handlers) DeleteUserUnavailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	unavailabilityID, err := strconv.Atoi(vars["unavailabilityId"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid unavailability ID")
+		return
+	}
+
+	if err := h.userService.DeleteUnavailability(r.Context(), id, unavailabilityID); err != nil {
+		if errors.Is(err, service.ErrUnavailabilityNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, dto.MessageResponse{Message: "unavailability removed"})
+}
+
+// AddUserIdentity godoc
+// @Summary Привязать внешнюю учетную запись
+// @Description Привязывает к пользователю учетную запись во внешней системе (логин GitHub/GitLab, email, ID в Slack). Учетная запись может принадлежать только одному пользователю
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param request body dto.CreateIdentityRequest true "Внешняя учетная запись"
+// @Success 201 {object} models.UserIdentity
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse "Учетная запись уже привязана"
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/identities [post]
+func (h *Handlers) AddUserIdentity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req dto.CreateIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	identity, err := h.userService.AddIdentity(r.Context(), id, models.IdentityProvider(req.Provider), req.ExternalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrIdentityAlreadyExists):
+			h.respondError(w, r, http.StatusConflict, err.Error())
+		default:
+			h.respondInternalError(w, r, err)
+		}
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusCreated, identity)
+}
+
+// ListUserIdentities godoc
+// @Summary Получить внешние учетные записи пользователя
+// @Description Возвращает учетные записи пользователя во внешних системах
+// @Tags Users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} models.UserIdentity
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/identities [get]
+func (h *Handlers) ListUserIdentities(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	identities, err := h.userService.GetIdentities(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, identities)
+}
+
+// DeleteUserIdentity godoc
+// @Summary Отвязать внешнюю учетную запись
+// @Description Отвязывает учетную запись во внешней системе от пользователя
+// @Tags Users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param identityId path int true "ID учетной записи"
+// @Success 200 {object} dto.MessageResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/identities/{identityId} [delete]
+func (h *Handlers) DeleteUserIdentity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	identityID, err := strconv.Atoi(vars["identityId"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid identity ID")
+		return
+	}
+
+	if err := h.userService.DeleteIdentity(r.Context(), id, identityID); err != nil {
+		if errors.Is(err, service.ErrUserIdentityNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error())
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, dto.MessageResponse{Message: "identity removed"})
+}
+
+// GetUserByIdentity godoc
+// @Summary Найти пользователя по внешней учетной записи
+// @Description Возвращает пользователя, к которому привязана учетная запись provider/id. Логины и email сравниваются без учета регистра
+// @Tags Users
+// @Produce json
+// @Param provider query string true "Внешняя система (github, gitlab, email, slack, ...)"
+// @Param id query string true "Логин, email или ID во внешней системе"
+// @Success 200 {object} models.User
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/by-identity [get]
+func (h *Handlers) GetUserByIdentity(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+	externalID := r.URL.Query().Get("id")
+	if provider == "" || externalID == "" {
+		h.respondError(w, r, http.StatusBadRequest, "provider and id are required")
+		return
+	}
+
+	user, err := h.userService.GetUserByIdentity(r.Context(), models.IdentityProvider(provider), externalID)
+	if err != nil {
+		if errors.Is(err, service.ErrIdentityNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, user)
+}
+
+// ListUserTeams godoc
+// @Summary Получить команды пользователя
+// @Description Возвращает все команды пользователя; основная команда идет первой и используется для PR, созданных без team_name
+// @Tags Users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} models.TeamMembership
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/teams [get]
+func (h *Handlers) ListUserTeams(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	memberships, err := h.teamService.GetUserTeams(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, memberships)
+}
+
+// SetUserPrimaryTeam godoc
+// @Summary Задать основную команду пользователя
+// @Description Делает одну из команд пользователя основной: из нее назначаются ревьюверы PR, созданных без team_name
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param request body dto.SetPrimaryTeamRequest true "Основная команда"
+// @Success 200 {array} models.TeamMembership
+// @Failure 400 {object} dto.ErrorResponse "Пользователь не состоит в команде"
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/{id}/primary-team [put]
+func (h *Handlers) SetUserPrimaryTeam(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req dto.SetPrimaryTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	memberships, err := h.teamService.SetPrimaryTeam(r.Context(), id, req.TeamName)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrNotTeamMember):
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+		default:
+			h.respondInternalError(w, r, err)
+		}
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, result)
+	h.respondJSON(w, r, http.StatusOK, memberships)
 }
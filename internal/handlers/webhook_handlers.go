@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/service"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// CreateWebhook godoc
+// @Summary Подписаться на вебхуки
+// @Description Создает подписку: события POST-запросом отправляются на url, тело подписывается HMAC-SHA256 с secret (заголовок X-Webhook-Signature). Без events подписка получает все события
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateWebhookRequest true "Данные подписки"
+// @Success 201 {object} models.WebhookSubscription
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks [post]
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, validator.FormatValidationErrors(err))
+		return
+	}
+
+	events := make([]models.WebhookEventType, len(req.Events))
+	for i, event := range req.Events {
+		events[i] = models.WebhookEventType(event)
+	}
+
+	sub, err := h.webhookService.CreateSubscription(r.Context(), req.URL, req.Secret, events)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookEvent) {
+			h.respondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusCreated, sub)
+}
+
+// ListWebhooks godoc
+// @Summary Получить список подписок на вебхуки
+// @Description Возвращает все подписки на вебхуки (без секретов)
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookSubscription
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks [get]
+func (h *Handlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.webhookService.GetSubscriptions(r.Context())
+	if err != nil {
+		h.respondInternalError(w, r, err)
+		return
+	}
+	h.respondJSON(w, r, http.StatusOK, subscriptions)
+}
+
+// GetWebhook godoc
+// @Summary Получить подписку на вебхуки
+// @Description Возвращает подписку на вебхуки по ID
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Success 200 {object} models.WebhookSubscription
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *Handlers) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid webhook ID")
+		return
+	}
+
+	sub, err := h.webhookService.GetSubscription(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, sub)
+}
+
+// DeleteWebhook godoc
+// @Summary Удалить подписку на вебхуки
+// @Description Удаляет подписку вместе с журналом ее доставок; недоставленные события больше не отправляются
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Success 200 {object} dto.MessageResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid webhook ID")
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), id); err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, dto.MessageResponse{Message: "webhook deleted"})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Журнал доставок вебхука
+// @Description Возвращает доставки событий подписке, начиная с последних: статус, количество попыток, код ответа и последнюю ошибку
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handlers) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, "invalid webhook ID")
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookNotFound) {
+			h.respondError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondInternalError(w, r, err)
+		return
+	}
+
+	h.respondJSON(w, r, http.StatusOK, deliveries)
+}
@@ -0,0 +1,137 @@
+// Package metrics exposes Prometheus metrics of the PR reviewer service.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pr_reviewer"
+
+// Metrics хранит метрики сервиса в собственном реестре. Счетчики назначения ревьюверов обновляются
+// сервисами через интерфейс service.AssignmentObserver
+type Metrics struct {
+	registry              *prometheus.Registry
+	httpRequests          *prometheus.CounterVec
+	httpDuration          *prometheus.HistogramVec
+	prsCreated            *prometheus.CounterVec
+	reviewersAssigned     *prometheus.CounterVec
+	reviewersReassigned   *prometheus.CounterVec
+	insufficientReviewers *prometheus.CounterVec
+}
+
+// New регистрирует метрики HTTP, назначения ревьюверов, рантайма Go и, если db задан, пула соединений с БД
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		prsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "prs_created_total",
+			Help:      "Pull requests created, by team of the PR.",
+		}, []string{"team"}),
+		reviewersAssigned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reviewers_assigned_total",
+			Help:      "Reviewers assigned automatically when a PR is opened, by team of the PR.",
+		}, []string{"team"}),
+		reviewersReassigned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reviewers_reassigned_total",
+			Help:      "Reviewers replaced on open PRs, by team the new reviewer was picked from.",
+		}, []string{"team"}),
+		insufficientReviewers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "insufficient_reviewers_total",
+			Help:      "PRs that could not be opened because the team had too few available reviewers.",
+		}, []string{"team"}),
+	}
+
+	m.registry.MustRegister(
+		m.httpRequests,
+		m.httpDuration,
+		m.prsCreated,
+		m.reviewersAssigned,
+		m.reviewersReassigned,
+		m.insufficientReviewers,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+	}
+	return m
+}
+
+// Handler отдает метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware считает запросы и время их обработки. Запросы группируются по шаблону маршрута gorilla/mux
+// (например, /prs/{id}), чтобы число серий не зависело от идентификаторов в пути
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		m.httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(recorder.status)).Inc()
+	})
+}
+
+func (m *Metrics) PRCreated(teamName string) {
+	m.prsCreated.WithLabelValues(teamName).Inc()
+}
+
+func (m *Metrics) ReviewersAssigned(teamName string, count int) {
+	m.reviewersAssigned.WithLabelValues(teamName).Add(float64(count))
+}
+
+func (m *Metrics) ReviewersReassigned(teamName string, count int) {
+	m.reviewersReassigned.WithLabelValues(teamName).Add(float64(count))
+}
+
+func (m *Metrics) InsufficientReviewers(teamName string) {
+	m.insufficientReviewers.WithLabelValues(teamName).Inc()
+}
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	m := New(nil)
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.HandleFunc("/prs/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/prs/1", "/prs/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`pr_reviewer_http_requests_total{method="GET",route="/prs/{id}",status="404"} 2`,
+		`pr_reviewer_http_request_duration_seconds_count{method="GET",route="/prs/{id}"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+}
+
+func TestAssignmentCounters(t *testing.T) {
+	m := New(nil)
+	m.PRCreated("backend")
+	m.ReviewersAssigned("backend", 2)
+	m.ReviewersReassigned("security", 1)
+	m.InsufficientReviewers("frontend")
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`pr_reviewer_prs_created_total{team="backend"} 1`,
+		`pr_reviewer_reviewers_assigned_total{team="backend"} 2`,
+		`pr_reviewer_reviewers_reassigned_total{team="security"} 1`,
+		`pr_reviewer_insufficient_reviewers_total{team="frontend"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+}
@@ -2,38 +2,103 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 )
 
+// ReviewerReplacement описывает замену ревьювера в PR.
+// NewReviewerID == 0 означает, что старый ревьювер снимается без замены.
+// Reason попадает в историю PR
+type ReviewerReplacement struct {
+	Reason        models.ReassignReason
+	PRID          int
+	OldReviewerID int
+	NewReviewerID int
+	// TeamName - команда, из которой выбран новый ревьювер; в базе не сохраняется
+	TeamName string
+	// ActorID - пользователь, запустивший замену; nil для системных действий
+	ActorID *int
+}
+
 // PRRepositoryInterface определяет интерфейс для работы с Pull Requests
 type PRRepositoryInterface interface {
-	Create(pr *models.PR) error
-	GetByID(id int) (*models.PR, error)
-	GetByUserID(userID int) ([]models.PR, error)
-	GetAll() ([]models.PR, error)
-	UpdateStatus(id int, status models.PRStatus) error
-	ReassignReviewer(prID int, oldReviewerID int, newReviewerID int) error
-	GetStats() (map[string]int, error)
-	GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, error)
-	BulkReassignReviewers(prReviewerMap map[int][]int, teamName string, excludeUserIDs []int) (int, error)
+	Create(ctx context.Context, pr *models.PR) error
+	GetByID(ctx context.Context, id int) (*models.PR, error)
+	GetByIDs(ctx context.Context, ids []int) ([]models.PR, error)
+	GetByExternalRef(ctx context.Context, ref models.ExternalRef) (*models.PR, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.PR, error)
+	GetAll(ctx context.Context) ([]models.PR, error)
+	UpdateStatus(ctx context.Context, id int, status models.PRStatus, changedAt time.Time, actorID *int) error
+	UpdateStatusWithReviewers(ctx context.Context, id int, status models.PRStatus, reviewers []models.Review, explanation models.AssignmentExplanation, actorID *int) error
+	Merge(ctx context.Context, id int, forced bool, requiredApprovals int, mergedAt time.Time, actorID *int) error
+	ReassignReviewer(ctx context.Context, prID int, oldReviewerID int, newReviewerID int, actorID *int) error
+	SetReviewState(ctx context.Context, prID int, reviewerID int, state models.ReviewState, reviewedAt time.Time) error
+	GetStats(ctx context.Context) (map[string]int, error)
+	GetOpenPRsWithReviewers(ctx context.Context, userIDs []int) (map[int][]int, error)
+	GetOpenReviewCounts(ctx context.Context, userIDs []int) (map[int]int, error)
+	BulkReassignReviewers(ctx context.Context, replacements []ReviewerReplacement) ([]ReviewerReplacement, error)
+	GetEvents(ctx context.Context, prID int) ([]models.PREvent, error)
 }
 
 // UserRepositoryInterface определяет интерфейс для работы с пользователями
 type UserRepositoryInterface interface {
-	Create(user *models.User) error
-	GetByID(id int) (*models.User, error)
-	GetAll() ([]models.User, error)
-	Update(user *models.User) error
-	GetActiveUsersByTeam(teamName string, excludeUserID int) ([]models.User, error)
-	BulkDeactivateByTeam(teamName string) (int, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	GetActiveUsersByTeam(ctx context.Context, teamName string, excludeUserID int, at time.Time) ([]models.User, error)
+	BulkDeactivateByTeam(ctx context.Context, teamName string) (int, error)
+	CreateUnavailability(ctx context.Context, u *models.Unavailability) error
+	GetUnavailabilities(ctx context.Context, userID int, at time.Time) ([]models.Unavailability, error)
+	DeleteUnavailability(ctx context.Context, userID int, id int) (bool, error)
+	GetStartedUnavailabilities(ctx context.Context, at time.Time) ([]models.Unavailability, error)
+	MarkUnavailabilityReassigned(ctx context.Context, id int, at time.Time) error
+	GetByIdentity(ctx context.Context, provider models.IdentityProvider, externalID string) (*models.User, error)
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error)
+	DeleteIdentity(ctx context.Context, userID int, id int) (bool, error)
 }
 
 // TeamRepositoryInterface определяет интерфейс для работы с командами
 type TeamRepositoryInterface interface {
-	Create(team *models.Team) error
-	GetByName(name string) (*models.Team, error)
-	GetAll() ([]models.Team, error)
-	AddMember(teamName string, userID int) error
-	RemoveMember(teamName string, userID int) error
-	GetUserTeam(userID int) (string, error)
+	Create(ctx context.Context, team *models.Team) error
+	GetByName(ctx context.Context, name string) (*models.Team, error)
+	GetAll(ctx context.Context) ([]models.Team, error)
+	AddMember(ctx context.Context, teamName string, userID int) error
+	RemoveMember(ctx context.Context, teamName string, userID int) error
+	GetUserTeam(ctx context.Context, userID int) (string, error)
+	GetUserTeams(ctx context.Context, userID int) ([]models.TeamMembership, error)
+	SetPrimaryTeam(ctx context.Context, userID int, teamName string) (bool, error)
+	GetMemberRole(ctx context.Context, teamName string, userID int) (models.TeamRole, error)
+	SetMemberRole(ctx context.Context, teamName string, userID int, role models.TeamRole) (bool, error)
+	GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error)
+	UpsertSettings(ctx context.Context, settings *models.TeamSettings) error
+}
+
+// WebhookRepositoryInterface определяет интерфейс для работы с подписками на вебхуки и их доставками
+type WebhookRepositoryInterface interface {
+	CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	GetSubscriptionByID(ctx context.Context, id int) (*models.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetSubscriptionsForEvent(ctx context.Context, eventType models.WebhookEventType) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int) (bool, error)
+	CreateDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error)
+}
+
+// OwnershipRepositoryInterface определяет интерфейс для работы с правилами владения кодом
+type OwnershipRepositoryInterface interface {
+	Create(ctx context.Context, rule *models.OwnershipRule) error
+	GetAll(ctx context.Context) ([]models.OwnershipRule, error)
+	Delete(ctx context.Context, id int) (bool, error)
+}
+
+// HealthRepositoryInterface определяет интерфейс для проверки состояния БД
+type HealthRepositoryInterface interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, bool, error)
 }
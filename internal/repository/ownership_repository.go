@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+type OwnershipRepository struct {
+	db *sql.DB
+}
+
+func NewOwnershipRepository(db *sql.DB) *OwnershipRepository {
+	return &OwnershipRepository{db: db}
+}
+
+func (r *OwnershipRepository) Create(ctx context.Context, rule *models.OwnershipRule) error {
+	var userID sql.NullInt64
+	if rule.UserID != nil {
+		userID = sql.NullInt64{Int64: int64(*rule.UserID), Valid: true}
+	}
+
+	return r.db.QueryRowContext(ctx,
+		"INSERT INTO ownership_rules (pattern, team_name, user_id) VALUES ($1, $2, $3) RETURNING id, created_at",
+		rule.Pattern, nullString(rule.TeamName), userID,
+	).Scan(&rule.ID, &rule.CreatedAt)
+}
+
+// GetAll возвращает все правила владения в порядке создания: при пересечении шаблонов побеждает последнее
+func (r *OwnershipRepository) GetAll(ctx context.Context) ([]models.OwnershipRule, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, pattern, team_name, user_id, created_at FROM ownership_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.OwnershipRule, 0)
+	for rows.Next() {
+		var rule models.OwnershipRule
+		var teamName sql.NullString
+		var userID sql.NullInt64
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &teamName, &userID, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		rule.TeamName = teamName.String
+		rule.UserID = nullIntPtr(userID)
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Delete удаляет правило владения. Возвращает false, если правила нет
+func (r *OwnershipRepository) Delete(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM ownership_rules WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
-	"math/rand"
+	"fmt"
 	"time"
 
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
@@ -11,6 +13,17 @@ import (
 	"github.com/lib/pq"
 )
 
+// Ошибки проверок, которые повторяются под блокировкой строки PR: между проверкой в сервисе и записью
+// PR или его ревьюверы могли измениться параллельным запросом. Сервис отдает те же ошибки
+var (
+	ErrInvalidStatusTransition = errors.New("invalid PR status transition")
+	ErrPRNotOpen               = errors.New("PR is not open")
+	ErrReviewerNotAssigned     = errors.New("old reviewer is not assigned to this PR")
+	ErrReviewerApproved        = errors.New("cannot reassign reviewer: reviewer has already approved this PR")
+	ErrNotPRReviewer           = errors.New("user is not assigned as a reviewer of this PR")
+	ErrMergeBlocked            = errors.New("cannot merge PR: review requirements are not met")
+)
+
 type PRRepository struct {
 	db *sql.DB
 }
@@ -19,27 +32,102 @@ func NewPRRepository(db *sql.DB) *PRRepository {
 	return &PRRepository{db: db}
 }
 
-func (r *PRRepository) Create(pr *models.PR) error {
-	tx, err := r.db.Begin()
+// prColumns - колонки pull_requests, которые считывает scanPR
+const prColumns = "id, title, author_id, status, force_merged, team_name, changed_files, assignment_explanation, external_provider, external_repo, external_number"
+
+// scanPR считывает колонки prColumns
+func scanPR(row rowScanner, pr *models.PR) error {
+	var teamName, provider, repo sql.NullString
+	var number sql.NullInt64
+	var changedFiles pq.StringArray
+	var explanation []byte
+	if err := row.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status, &pr.ForceMerged, &teamName, &changedFiles, &explanation, &provider, &repo, &number); err != nil {
+		return err
+	}
+
+	// Для PR, созданных до сохранения объяснения, а также черновиков объяснения нет
+	pr.SetExplanation(models.AssignmentExplanation{})
+	if explanation != nil {
+		var stored models.AssignmentExplanation
+		if err := json.Unmarshal(explanation, &stored); err != nil {
+			return err
+		}
+		pr.SetExplanation(stored)
+	}
+
+	pr.ChangedFiles = nil
+	if len(changedFiles) > 0 {
+		pr.ChangedFiles = changedFiles
+	}
+
+	pr.TeamName = teamName.String
+
+	pr.External = nil
+	if provider.Valid {
+		pr.External = &models.ExternalRef{
+			Provider: models.IdentityProvider(provider.String),
+			Repo:     repo.String,
+			Number:   int(number.Int64),
+		}
+	}
+	return nil
+}
+
+// scanPRs считывает PR из rows; ревьюверы инициализируются пустыми списками
+func scanPRs(rows *sql.Rows) ([]models.PR, error) {
+	var prs []models.PR
+	for rows.Next() {
+		var pr models.PR
+		if err := scanPR(rows, &pr); err != nil {
+			return nil, err
+		}
+		pr.Reviewers = []int{}
+		pr.Reviews = []models.Review{}
+		prs = append(prs, pr)
+	}
+	return prs, rows.Err()
+}
+
+func (r *PRRepository) Create(ctx context.Context, pr *models.PR) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	err = tx.QueryRow(
-		"INSERT INTO pull_requests (title, author_id, status) VALUES ($1, $2, $3) RETURNING id",
-		pr.Title, pr.AuthorID, pr.Status,
+	var provider, repo sql.NullString
+	var number sql.NullInt64
+	if pr.External != nil {
+		provider = sql.NullString{String: string(pr.External.Provider), Valid: true}
+		repo = sql.NullString{String: pr.External.Repo, Valid: true}
+		number = sql.NullInt64{Int64: int64(pr.External.Number), Valid: true}
+	}
+
+	explanation, err := marshalExplanation(pr.Explanation())
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO pull_requests (title, author_id, status, team_name, changed_files, assignment_explanation, external_provider, external_repo, external_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		pr.Title, pr.AuthorID, pr.Status, nullString(pr.TeamName), pq.StringArray(changedFiles(pr)), explanation, provider, repo, number,
 	).Scan(&pr.ID)
 	if err != nil {
 		return err
 	}
 
+	authorID := pr.AuthorID
+	err = insertEvent(ctx, tx, models.PREvent{PRID: pr.ID, Type: models.PREventCreated, ActorID: &authorID, ToStatus: pr.Status})
+	if err != nil {
+		return err
+	}
+
 	for _, reviewerID := range pr.Reviewers {
-		_, err = tx.Exec(
-			"INSERT INTO pr_reviewers (pr_id, reviewer_id) VALUES ($1, $2)",
-			pr.ID, reviewerID,
-		)
-		if err != nil {
+		if err := insertReviewer(ctx, tx, pr.ID, reviewerID, nullString(pr.PoolTeam(reviewerID))); err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, tx, reviewerEvent(pr.ID, models.PREventReviewerAssigned, reviewerID, "", &authorID)); err != nil {
 			return err
 		}
 	}
@@ -47,12 +135,9 @@ func (r *PRRepository) Create(pr *models.PR) error {
 	return tx.Commit()
 }
 
-func (r *PRRepository) GetByID(id int) (*models.PR, error) {
+func (r *PRRepository) GetByID(ctx context.Context, id int) (*models.PR, error) {
 	pr := &models.PR{}
-	err := r.db.QueryRow(
-		"SELECT id, title, author_id, status FROM pull_requests WHERE id = $1",
-		id,
-	).Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status)
+	err := scanPR(r.db.QueryRowContext(ctx, "SELECT "+prColumns+" FROM pull_requests WHERE id = $1", id), pr)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -60,9 +145,9 @@ func (r *PRRepository) GetByID(id int) (*models.PR, error) {
 		return nil, err
 	}
 
-	// Загружаем ревьюверов
-	rows, err := r.db.Query(
-		"SELECT reviewer_id FROM pr_reviewers WHERE pr_id = $1 ORDER BY reviewer_id",
+	// Загружаем ревьюверов вместе с их вердиктами
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT pr_id, reviewer_id, state, reviewed_at, pool_team FROM pr_reviewers WHERE pr_id = $1 ORDER BY reviewer_id",
 		id,
 	)
 	if err != nil {
@@ -70,43 +155,52 @@ func (r *PRRepository) GetByID(id int) (*models.PR, error) {
 	}
 	defer rows.Close()
 
-	var reviewers []int
+	pr.Reviewers = []int{}
+	pr.Reviews = []models.Review{}
 	for rows.Next() {
-		var reviewerID int
-		if err := rows.Scan(&reviewerID); err != nil {
+		var review models.Review
+		if _, err := scanReview(rows, &review); err != nil {
 			return nil, err
 		}
-		reviewers = append(reviewers, reviewerID)
+		pr.Reviewers = append(pr.Reviewers, review.ReviewerID)
+		pr.Reviews = append(pr.Reviews, review)
 	}
-	pr.Reviewers = reviewers
 
 	return pr, rows.Err()
 }
 
-func (r *PRRepository) GetByUserID(userID int) ([]models.PR, error) {
-	prRows, err := r.db.Query(`
-		SELECT DISTINCT pr.id, pr.title, pr.author_id, pr.status
-		FROM pull_requests pr
-		LEFT JOIN pr_reviewers prr ON pr.id = prr.pr_id
-		WHERE pr.author_id = $1 OR prr.reviewer_id = $1
-		ORDER BY pr.id
+// scanReview считывает колонки pr_id, reviewer_id, state, reviewed_at, pool_team и возвращает pr_id
+func scanReview(row rowScanner, review *models.Review) (int, error) {
+	var prID int
+	var reviewedAt sql.NullTime
+	var poolTeam sql.NullString
+	if err := row.Scan(&prID, &review.ReviewerID, &review.State, &reviewedAt, &poolTeam); err != nil {
+		return 0, err
+	}
+
+	review.PoolTeam = poolTeam.String
+
+	review.ReviewedAt = nil
+	if reviewedAt.Valid {
+		review.ReviewedAt = &reviewedAt.Time
+	}
+	return prID, nil
+}
+
+func (r *PRRepository) GetByUserID(ctx context.Context, userID int) ([]models.PR, error) {
+	prRows, err := r.db.QueryContext(ctx, `
+		SELECT `+prColumns+`
+		FROM pull_requests
+		WHERE author_id = $1 OR id IN (SELECT pr_id FROM pr_reviewers WHERE reviewer_id = $1)
+		ORDER BY id
 	`, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer prRows.Close()
 
-	var prs []models.PR
-	for prRows.Next() {
-		var pr models.PR
-		if err := prRows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status); err != nil {
-			return nil, err
-		}
-		pr.Reviewers = []int{}
-		prs = append(prs, pr)
-	}
-
-	if err := prRows.Err(); err != nil {
+	prs, err := scanPRs(prRows)
+	if err != nil {
 		return nil, err
 	}
 
@@ -114,58 +208,64 @@ func (r *PRRepository) GetByUserID(userID int) ([]models.PR, error) {
 		return prs, nil
 	}
 
-	prIDs := make([]int, len(prs))
-	for i, pr := range prs {
-		prIDs[i] = pr.ID
-	}
+	return prs, r.attachReviewers(ctx, prs)
+}
 
-	reviewerRows, err := r.db.Query(`
-		SELECT pr_id, reviewer_id
-		FROM pr_reviewers
-		WHERE pr_id = ANY($1::int[])
-		ORDER BY pr_id, reviewer_id
-	`, pq.Array(prIDs))
+func (r *PRRepository) GetAll(ctx context.Context) ([]models.PR, error) {
+	prRows, err := r.db.QueryContext(ctx, "SELECT "+prColumns+" FROM pull_requests ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
-	defer reviewerRows.Close()
+	defer prRows.Close()
 
-	prsMap := make(map[int]*models.PR)
-	for i := range prs {
-		prsMap[prs[i].ID] = &prs[i]
+	prs, err := scanPRs(prRows)
+	if err != nil {
+		return nil, err
 	}
 
-	for reviewerRows.Next() {
-		var prID, reviewerID int
-		if err := reviewerRows.Scan(&prID, &reviewerID); err != nil {
-			return nil, err
-		}
-		if pr, exists := prsMap[prID]; exists {
-			pr.Reviewers = append(pr.Reviewers, reviewerID)
-		}
+	if len(prs) == 0 {
+		return prs, nil
 	}
 
-	return prs, reviewerRows.Err()
+	if err := r.attachReviewers(ctx, prs); err != nil {
+		return nil, err
+	}
+	return prs, nil
 }
 
-func (r *PRRepository) GetAll() ([]models.PR, error) {
-	prRows, err := r.db.Query("SELECT id, title, author_id, status FROM pull_requests ORDER BY id")
+// GetByExternalRef возвращает PR, созданный для pull request'а из внешней системы, или nil
+func (r *PRRepository) GetByExternalRef(ctx context.Context, ref models.ExternalRef) (*models.PR, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id FROM pull_requests WHERE external_provider = $1 AND external_repo = $2 AND external_number = $3",
+		ref.Provider, ref.Repo, ref.Number,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer prRows.Close()
+	return r.GetByID(ctx, id)
+}
 
-	var prs []models.PR
-	for prRows.Next() {
-		var pr models.PR
-		if err := prRows.Scan(&pr.ID, &pr.Title, &pr.AuthorID, &pr.Status); err != nil {
-			return nil, err
-		}
-		pr.Reviewers = []int{}
-		prs = append(prs, pr)
+// GetByIDs получает PR по списку ID вместе с ревьюверами
+func (r *PRRepository) GetByIDs(ctx context.Context, ids []int) ([]models.PR, error) {
+	if len(ids) == 0 {
+		return []models.PR{}, nil
 	}
 
-	if err := prRows.Err(); err != nil {
+	prRows, err := r.db.QueryContext(ctx,
+		"SELECT "+prColumns+" FROM pull_requests WHERE id = ANY($1::int[]) ORDER BY id",
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer prRows.Close()
+
+	prs, err := scanPRs(prRows)
+	if err != nil {
 		return nil, err
 	}
 
@@ -173,19 +273,27 @@ func (r *PRRepository) GetAll() ([]models.PR, error) {
 		return prs, nil
 	}
 
+	if err := r.attachReviewers(ctx, prs); err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// attachReviewers загружает ревьюверов и их вердикты для списка PR одним запросом
+func (r *PRRepository) attachReviewers(ctx context.Context, prs []models.PR) error {
 	prIDs := make([]int, len(prs))
 	for i, pr := range prs {
 		prIDs[i] = pr.ID
 	}
 
-	reviewerRows, err := r.db.Query(`
-		SELECT pr_id, reviewer_id
+	reviewerRows, err := r.db.QueryContext(ctx, `
+		SELECT pr_id, reviewer_id, state, reviewed_at, pool_team
 		FROM pr_reviewers
 		WHERE pr_id = ANY($1::int[])
 		ORDER BY pr_id, reviewer_id
 	`, pq.Array(prIDs))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer reviewerRows.Close()
 
@@ -195,58 +303,244 @@ func (r *PRRepository) GetAll() ([]models.PR, error) {
 	}
 
 	for reviewerRows.Next() {
-		var prID, reviewerID int
-		if err := reviewerRows.Scan(&prID, &reviewerID); err != nil {
-			return nil, err
+		var review models.Review
+		prID, err := scanReview(reviewerRows, &review)
+		if err != nil {
+			return err
 		}
 		if pr, exists := prsMap[prID]; exists {
-			pr.Reviewers = append(pr.Reviewers, reviewerID)
+			pr.Reviewers = append(pr.Reviewers, review.ReviewerID)
+			pr.Reviews = append(pr.Reviews, review)
 		}
 	}
 
-	if err := reviewerRows.Err(); err != nil {
-		return nil, err
+	return reviewerRows.Err()
+}
+
+// UpdateStatus меняет статус PR без изменения ревьюверов. Для мержа используется Merge.
+// changedAt записывается в closed_at при закрытии, actorID - пользователь, сменивший статус; nil для системных действий
+func (r *PRRepository) UpdateStatus(ctx context.Context, id int, status models.PRStatus, changedAt time.Time, actorID *int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
-	return prs, nil
+	defer func() { _ = tx.Rollback() }()
+
+	fromStatus, err := lockStatus(ctx, tx, id, status)
+	if err != nil {
+		return err
+	}
+
+	var closedAt *time.Time
+	if status == models.PRStatusClosed {
+		closedAt = &changedAt
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE pull_requests SET status = $1, closed_at = $2 WHERE id = $3",
+		status, closedAt, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = insertEvent(ctx, tx, models.PREvent{PRID: id, Type: models.PREventStatusChanged, ActorID: actorID, FromStatus: fromStatus, ToStatus: status})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *PRRepository) UpdateStatus(id int, status models.PRStatus) error {
-	now := time.Now()
-	_, err := r.db.Exec(
-		"UPDATE pull_requests SET status = $1, merged_at = $2 WHERE id = $3",
-		status, now, id,
+// UpdateStatusWithReviewers меняет статус PR и в той же транзакции приводит список ревьюверов к reviewers.
+// Вердикты ревьюверов, оставшихся в списке, сохраняются; новые ревьюверы добавляются с пулом из reviewers.
+// explanation заменяет объяснение предыдущего назначения, actorID записывается во все события изменения
+func (r *PRRepository) UpdateStatusWithReviewers(ctx context.Context, id int, status models.PRStatus, reviewers []models.Review, explanation models.AssignmentExplanation, actorID *int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	fromStatus, err := lockStatus(ctx, tx, id, status)
+	if err != nil {
+		return err
+	}
+
+	stored, err := marshalExplanation(explanation)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE pull_requests SET status = $1, closed_at = NULL, assignment_explanation = $2 WHERE id = $3",
+		status, stored, id,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	err = insertEvent(ctx, tx, models.PREvent{PRID: id, Type: models.PREventStatusChanged, ActorID: actorID, FromStatus: fromStatus, ToStatus: status})
+	if err != nil {
+		return err
+	}
+
+	current, err := selectReviewerIDs(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[int]struct{}, len(reviewers))
+	for _, review := range reviewers {
+		keep[review.ReviewerID] = struct{}{}
+	}
+	for reviewerID := range current {
+		if _, ok := keep[reviewerID]; ok {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
+			id, reviewerID,
+		)
+		if err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, tx, reviewerEvent(id, models.PREventReviewerRemoved, reviewerID, models.ReassignReasonUnavailable, actorID)); err != nil {
+			return err
+		}
+	}
+
+	for _, review := range reviewers {
+		if _, ok := current[review.ReviewerID]; ok {
+			continue
+		}
+		if err := insertReviewer(ctx, tx, id, review.ReviewerID, nullString(review.PoolTeam)); err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, tx, reviewerEvent(id, models.PREventReviewerAssigned, review.ReviewerID, "", actorID)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (r *PRRepository) ReassignReviewer(prID int, oldReviewerID int, newReviewerID int) error {
-	tx, err := r.db.Begin()
+// Merge переводит PR в статус MERGED. Без forced под блокировкой проверяется, что PR набрал requiredApprovals
+// одобрений и никто не запросил изменения, иначе возвращается ErrMergeBlocked. forced отмечает, что мерж
+// выполнен в обход проверки одобрений, mergedAt записывается в merged_at, actorID - пользователь, выполнивший мерж
+func (r *PRRepository) Merge(ctx context.Context, id int, forced bool, requiredApprovals int, mergedAt time.Time, actorID *int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	_, err = tx.Exec(
-		"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
-		prID, oldReviewerID,
+	fromStatus, err := lockStatus(ctx, tx, id, models.PRStatusMerged)
+	if err != nil {
+		return err
+	}
+
+	// Ревью меняются под той же блокировкой строки PR, поэтому одобрения считаются по состоянию на момент мержа
+	if !forced {
+		var approvals, changesRequested int
+		err = tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FILTER (WHERE state = $2), COUNT(*) FILTER (WHERE state = $3)
+			 FROM pr_reviewers WHERE pr_id = $1`,
+			id, models.ReviewStateApproved, models.ReviewStateChangesRequested,
+		).Scan(&approvals, &changesRequested)
+		if err != nil {
+			return err
+		}
+		if approvals < requiredApprovals || changesRequested > 0 {
+			return ErrMergeBlocked
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE pull_requests SET status = $1, merged_at = $2, force_merged = $3 WHERE id = $4",
+		models.PRStatusMerged, mergedAt, forced, id,
 	)
 	if err != nil {
 		return err
 	}
 
-	// Добавляем нового ревьювера
-	_, err = tx.Exec(
-		"INSERT INTO pr_reviewers (pr_id, reviewer_id) VALUES ($1, $2)",
-		prID, newReviewerID,
+	err = insertEvent(ctx, tx, models.PREvent{
+		PRID:       id,
+		Type:       models.PREventMerged,
+		ActorID:    actorID,
+		FromStatus: fromStatus,
+		ToStatus:   models.PRStatusMerged,
+		Forced:     forced,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReassignReviewer заменяет ревьювера oldReviewerID на newReviewerID по запросу пользователя actorID
+func (r *PRRepository) ReassignReviewer(ctx context.Context, prID int, oldReviewerID int, newReviewerID int, actorID *int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockReplaceableReviewer(ctx, tx, prID, oldReviewerID); err != nil {
+		return err
+	}
+	poolTeam, err := deleteReviewer(ctx, tx, prID, oldReviewerID)
+	if err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, reviewerEvent(prID, models.PREventReviewerRemoved, oldReviewerID, models.ReassignReasonManual, actorID)); err != nil {
+		return err
+	}
+
+	// Новый ревьювер занимает место старого в том же пуле
+	if err := insertReviewer(ctx, tx, prID, newReviewerID, poolTeam); err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, reviewerEvent(prID, models.PREventReviewerAssigned, newReviewerID, models.ReassignReasonManual, actorID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetReviewState сохраняет вердикт ревьювера по PR. Возвращает ErrPRNotOpen, если PR уже не открыт,
+// и ErrNotPRReviewer, если пользователь не назначен ревьювером
+func (r *PRRepository) SetReviewState(ctx context.Context, prID int, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockOpen(ctx, tx, prID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE pr_reviewers SET state = $1, reviewed_at = $2 WHERE pr_id = $3 AND reviewer_id = $4",
+		state, reviewedAt, prID, reviewerID,
 	)
 	if err != nil {
 		return err
 	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotPRReviewer
+	}
 
 	return tx.Commit()
 }
 
-func (r *PRRepository) GetStats() (map[string]int, error) {
+func (r *PRRepository) GetStats(ctx context.Context) (map[string]int, error) {
 	// Используем один запрос с подзапросами для получения всей статистики
 	stats := make(map[string]int, 6)
 
@@ -259,7 +553,7 @@ func (r *PRRepository) GetStats() (map[string]int, error) {
 		mergedPRs   int
 	)
 
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		SELECT 
 			(SELECT COUNT(*) FROM users) as total_users,
 			(SELECT COUNT(*) FROM users WHERE is_active = true) as active_users,
@@ -292,7 +586,7 @@ func (r *PRRepository) GetStats() (map[string]int, error) {
 
 // GetOpenPRsWithReviewers получает открытые PR с ревьюверами из указанного списка пользователей
 // Возвращает map[prID][]reviewerID для эффективной обработки
-func (r *PRRepository) GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, error) {
+func (r *PRRepository) GetOpenPRsWithReviewers(ctx context.Context, userIDs []int) (map[int][]int, error) {
 	if len(userIDs) == 0 {
 		return make(map[int][]int), nil
 	}
@@ -306,7 +600,7 @@ func (r *PRRepository) GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, er
 		ORDER BY pr.id, prr.reviewer_id
 	`
 
-	rows, err := r.db.Query(query, models.PRStatusOpen, pq.Array(userIDs))
+	rows, err := r.db.QueryContext(ctx, query, models.PRStatusOpen, pq.Array(userIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -324,149 +618,270 @@ func (r *PRRepository) GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, er
 	return result, rows.Err()
 }
 
-// BulkReassignReviewers safely reassigns reviewers in open PRs.
-func (r *PRRepository) BulkReassignReviewers(prReviewerMap map[int][]int, teamName string, excludeUserIDs []int) (int, error) {
-	if len(prReviewerMap) == 0 {
-		return 0, nil
+// GetOpenReviewCounts возвращает количество открытых PR, в которых каждый из пользователей назначен ревьювером.
+// Пользователи без открытых ревью в результат не попадают
+func (r *PRRepository) GetOpenReviewCounts(ctx context.Context, userIDs []int) (map[int]int, error) {
+	counts := make(map[int]int, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
 	}
 
-	tx, err := r.db.Begin()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT prr.reviewer_id, COUNT(*)
+		FROM pr_reviewers prr
+		INNER JOIN pull_requests pr ON pr.id = prr.pr_id
+		WHERE pr.status = $1 AND prr.reviewer_id = ANY($2::int[])
+		GROUP BY prr.reviewer_id
+	`, models.PRStatusOpen, pq.Array(userIDs))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer func() { _ = tx.Rollback() }()
+	defer rows.Close()
 
-	var candidates []int
-	var candidatesRows *sql.Rows
-	if len(excludeUserIDs) > 0 {
-		candidatesRows, err = tx.Query(`
-			SELECT u.id
-			FROM users u
-			INNER JOIN team_members tm ON u.id = tm.user_id
-			WHERE tm.team_name = $1 AND u.is_active = true AND u.id != ALL($2::int[])
-			ORDER BY RANDOM()
-			LIMIT 100
-		`, teamName, pq.Array(excludeUserIDs))
-	} else {
-		candidatesRows, err = tx.Query(`
-			SELECT u.id
-			FROM users u
-			INNER JOIN team_members tm ON u.id = tm.user_id
-			WHERE tm.team_name = $1 AND u.is_active = true
-			ORDER BY RANDOM()
-			LIMIT 100
-		`, teamName)
+	for rows.Next() {
+		var reviewerID, count int
+		if err := rows.Scan(&reviewerID, &count); err != nil {
+			return nil, err
+		}
+		counts[reviewerID] = count
 	}
 
+	return counts, rows.Err()
+}
+
+// BulkReassignReviewers safely applies reviewer replacements in a single transaction and returns the applied ones.
+// A replacement with NewReviewerID == 0 only removes the old reviewer. Replacements that no longer apply because
+// the PR was closed, the old reviewer was removed or has approved the PR in the meantime are skipped.
+func (r *PRRepository) BulkReassignReviewers(ctx context.Context, replacements []ReviewerReplacement) ([]ReviewerReplacement, error) {
+	if len(replacements) == 0 {
+		return []ReviewerReplacement{}, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer candidatesRows.Close()
+	defer func() { _ = tx.Rollback() }()
 
-	for candidatesRows.Next() {
-		var candidateID int
-		if err := candidatesRows.Scan(&candidateID); err != nil {
-			return 0, err
+	applied := make([]ReviewerReplacement, 0, len(replacements))
+	for _, replacement := range replacements {
+		err := lockReplaceableReviewer(ctx, tx, replacement.PRID, replacement.OldReviewerID)
+		if errors.Is(err, ErrPRNotOpen) || errors.Is(err, ErrReviewerNotAssigned) || errors.Is(err, ErrReviewerApproved) {
+			continue
+		}
+		if err != nil {
+			return nil, err
 		}
-		candidates = append(candidates, candidateID)
-	}
-	if err := candidatesRows.Err(); err != nil {
-		return 0, err
-	}
 
-	if len(candidates) == 0 {
-		reassignments := 0
-		for prID, oldReviewerIDs := range prReviewerMap {
-			for _, oldReviewerID := range oldReviewerIDs {
-				_, err = tx.Exec(
-					"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
-					prID, oldReviewerID,
-				)
+		poolTeam, err := deleteReviewer(ctx, tx, replacement.PRID, replacement.OldReviewerID)
+		if err != nil {
+			return nil, err
+		}
+		err = insertEvent(ctx, tx, reviewerEvent(replacement.PRID, models.PREventReviewerRemoved, replacement.OldReviewerID, replacement.Reason, replacement.ActorID))
+		if err != nil {
+			return nil, err
+		}
+
+		if replacement.NewReviewerID != 0 {
+			result, err := tx.ExecContext(ctx,
+				"INSERT INTO pr_reviewers (pr_id, reviewer_id, pool_team) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+				replacement.PRID, replacement.NewReviewerID, poolTeam,
+			)
+			if err != nil {
+				return nil, err
+			}
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			// Новый ревьювер мог уже быть назначен на этот PR, тогда событие назначения не пишем
+			if inserted > 0 {
+				err = insertEvent(ctx, tx, reviewerEvent(replacement.PRID, models.PREventReviewerAssigned, replacement.NewReviewerID, replacement.Reason, replacement.ActorID))
 				if err != nil {
-					return 0, err
+					return nil, err
 				}
-				reassignments++
 			}
 		}
-		return reassignments, tx.Commit()
+		applied = append(applied, replacement)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
+	return applied, nil
+}
 
-	prIDs := make([]int, 0, len(prReviewerMap))
-	for prID := range prReviewerMap {
-		prIDs = append(prIDs, prID)
+// GetEvents возвращает историю PR в порядке возникновения событий
+func (r *PRRepository) GetEvents(ctx context.Context, prID int) ([]models.PREvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, pr_id, type, actor_id, reviewer_id, reason, from_status, to_status, forced, created_at
+		FROM pr_events
+		WHERE pr_id = $1
+		ORDER BY id
+	`, prID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	authorsMap := make(map[int]int)
-	if len(prIDs) > 0 {
-		rows, err := tx.Query(`
-			SELECT id, author_id FROM pull_requests WHERE id = ANY($1::int[])
-		`, pq.Array(prIDs))
+	events := make([]models.PREvent, 0)
+	for rows.Next() {
+		var event models.PREvent
+		var actorID, reviewerID sql.NullInt64
+		err := rows.Scan(
+			&event.ID, &event.PRID, &event.Type, &actorID, &reviewerID,
+			&event.Reason, &event.FromStatus, &event.ToStatus, &event.Forced, &event.CreatedAt,
+		)
 		if err != nil {
-			return 0, err
-		}
-		defer func() {
-			_ = rows.Close()
-		}()
-		for rows.Next() {
-			var prID, authorID int
-			if err := rows.Scan(&prID, &authorID); err != nil {
-				return 0, err
-			}
-			authorsMap[prID] = authorID
-		}
-		if err := rows.Err(); err != nil {
-			return 0, err
+			return nil, err
 		}
+		event.ActorID = nullIntPtr(actorID)
+		event.ReviewerID = nullIntPtr(reviewerID)
+		events = append(events, event)
 	}
 
-	reassignments := 0
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return events, rows.Err()
+}
 
-	for prID, oldReviewerIDs := range prReviewerMap {
-		authorID := authorsMap[prID]
+// insertEvent записывает событие в историю PR в рамках транзакции изменения
+func insertEvent(ctx context.Context, tx *sql.Tx, event models.PREvent) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO pr_events (pr_id, type, actor_id, reviewer_id, reason, from_status, to_status, forced)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, event.PRID, event.Type, event.ActorID, event.ReviewerID, event.Reason, event.FromStatus, event.ToStatus, event.Forced)
+	return err
+}
 
-		availableCandidates := make([]int, 0)
-		for _, candidateID := range candidates {
-			if candidateID != authorID {
-				availableCandidates = append(availableCandidates, candidateID)
-			}
-		}
+func reviewerEvent(prID int, eventType models.PREventType, reviewerID int, reason models.ReassignReason, actorID *int) models.PREvent {
+	return models.PREvent{PRID: prID, Type: eventType, ActorID: actorID, ReviewerID: &reviewerID, Reason: reason}
+}
 
-		if len(availableCandidates) == 0 {
-			for _, oldReviewerID := range oldReviewerIDs {
-				_, err = tx.Exec(
-					"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
-					prID, oldReviewerID,
-				)
-				if err != nil {
-					return 0, err
-				}
-				reassignments++
-			}
-			continue
-		}
+// lockStatus блокирует строку PR до конца транзакции и возвращает его текущий статус. Если из него
+// нельзя перейти в статус to, возвращает ErrInvalidStatusTransition
+func lockStatus(ctx context.Context, tx *sql.Tx, id int, to models.PRStatus) (models.PRStatus, error) {
+	status, err := selectStatusForUpdate(ctx, tx, id)
+	if err != nil {
+		return "", err
+	}
+	if !status.CanTransitionTo(to) {
+		return "", fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, status, to)
+	}
+	return status, nil
+}
 
-		for _, oldReviewerID := range oldReviewerIDs {
-			newReviewerID := availableCandidates[rng.Intn(len(availableCandidates))]
+// lockOpen блокирует строку PR до конца транзакции и возвращает ErrPRNotOpen, если PR не открыт
+func lockOpen(ctx context.Context, tx *sql.Tx, id int) error {
+	status, err := selectStatusForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if status != models.PRStatusOpen {
+		return ErrPRNotOpen
+	}
+	return nil
+}
 
-			_, err = tx.Exec(
-				"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
-				prID, oldReviewerID,
-			)
-			if err != nil {
-				return 0, err
-			}
+// lockReplaceableReviewer блокирует строку PR и проверяет, что ревьювера reviewerID еще можно заменить:
+// PR открыт, ревьювер назначен на него и не одобрил его
+func lockReplaceableReviewer(ctx context.Context, tx *sql.Tx, prID, reviewerID int) error {
+	if err := lockOpen(ctx, tx, prID); err != nil {
+		return err
+	}
+	var state models.ReviewState
+	err := tx.QueryRowContext(ctx,
+		"SELECT state FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2",
+		prID, reviewerID,
+	).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReviewerNotAssigned
+	}
+	if err != nil {
+		return err
+	}
+	if state == models.ReviewStateApproved {
+		return ErrReviewerApproved
+	}
+	return nil
+}
 
-			_, err = tx.Exec(
-				"INSERT INTO pr_reviewers (pr_id, reviewer_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
-				prID, newReviewerID,
-			)
-			if err != nil {
-				return 0, err
-			}
-			reassignments++
+func selectStatusForUpdate(ctx context.Context, tx *sql.Tx, id int) (models.PRStatus, error) {
+	var status models.PRStatus
+	err := tx.QueryRowContext(ctx, "SELECT status FROM pull_requests WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	return status, err
+}
+
+// selectReviewerIDs возвращает множество ревьюверов PR внутри транзакции
+func selectReviewerIDs(ctx context.Context, tx *sql.Tx, prID int) (map[int]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT reviewer_id FROM pr_reviewers WHERE pr_id = $1", prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviewers := make(map[int]struct{})
+	for rows.Next() {
+		var reviewerID int
+		if err := rows.Scan(&reviewerID); err != nil {
+			return nil, err
 		}
+		reviewers[reviewerID] = struct{}{}
+	}
+	return reviewers, rows.Err()
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
 	}
+	v := int(value.Int64)
+	return &v
+}
+
+// nullString превращает пустую строку в NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// insertReviewer назначает ревьювера на PR внутри транзакции. poolTeam - пул, из которого он выбран
+func insertReviewer(ctx context.Context, tx *sql.Tx, prID, reviewerID int, poolTeam sql.NullString) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO pr_reviewers (pr_id, reviewer_id, pool_team) VALUES ($1, $2, $3)",
+		prID, reviewerID, poolTeam,
+	)
+	return err
+}
 
-	return reassignments, tx.Commit()
+// deleteReviewer снимает ревьювера с PR внутри транзакции и возвращает пул, из которого он был назначен.
+// Если ревьювер не назначен на PR, возвращает ErrReviewerNotAssigned
+func deleteReviewer(ctx context.Context, tx *sql.Tx, prID, reviewerID int) (sql.NullString, error) {
+	var poolTeam sql.NullString
+	err := tx.QueryRowContext(ctx,
+		"DELETE FROM pr_reviewers WHERE pr_id = $1 AND reviewer_id = $2 RETURNING pool_team",
+		prID, reviewerID,
+	).Scan(&poolTeam)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, ErrReviewerNotAssigned
+	}
+	return poolTeam, err
+}
+
+// changedFiles возвращает измененные файлы PR; NULL в колонку changed_files не пишется
+func changedFiles(pr *models.PR) []string {
+	if pr.ChangedFiles == nil {
+		return []string{}
+	}
+	return pr.ChangedFiles
+}
+
+// marshalExplanation кодирует объяснение назначения ревьюверов для колонки assignment_explanation.
+// Пустое объяснение (например, у черновика) хранится как NULL
+func marshalExplanation(explanation models.AssignmentExplanation) (sql.NullString, error) {
+	if explanation.CandidateLoads == nil && explanation.Exclusions == nil && explanation.Assignments == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(explanation)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -17,16 +18,16 @@ func NewTeamRepository(db *sql.DB) *TeamRepository {
 	return &TeamRepository{db: db}
 }
 
-func (r *TeamRepository) Create(team *models.Team) error {
-	_, err := r.db.Exec("INSERT INTO teams (name) VALUES ($1)", team.Name)
+func (r *TeamRepository) Create(ctx context.Context, team *models.Team) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO teams (name) VALUES ($1)", team.Name)
 	return err
 }
 
-func (r *TeamRepository) GetByName(name string) (*models.Team, error) {
+func (r *TeamRepository) GetByName(ctx context.Context, name string) (*models.Team, error) {
 	team := &models.Team{Name: name}
 
-	rows, err := r.db.Query(`
-		SELECT u.id, u.name, u.is_active 
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tm.role, u.id, u.name, u.is_active, u.max_open_reviews
 		FROM users u
 		INNER JOIN team_members tm ON u.id = tm.user_id
 		WHERE tm.team_name = $1
@@ -38,12 +39,17 @@ func (r *TeamRepository) GetByName(name string) (*models.Team, error) {
 	defer rows.Close()
 
 	var members []models.User
+	leads := []int{}
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.IsActive); err != nil {
+		var role models.TeamRole
+		if err := scanUser(rows, &user, &role); err != nil {
 			return nil, err
 		}
 		members = append(members, user)
+		if role == models.TeamRoleLead {
+			leads = append(leads, user.ID)
+		}
 	}
 
 	if err := rows.Err(); err != nil {
@@ -51,7 +57,7 @@ func (r *TeamRepository) GetByName(name string) (*models.Team, error) {
 	}
 
 	var exists bool
-	err = r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", name).Scan(&exists)
+	err = r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", name).Scan(&exists)
 	if err != nil {
 		return nil, err
 	}
@@ -60,11 +66,12 @@ func (r *TeamRepository) GetByName(name string) (*models.Team, error) {
 	}
 
 	team.Members = members
+	team.Leads = leads
 	return team, nil
 }
 
-func (r *TeamRepository) GetAll() ([]models.Team, error) {
-	teamRows, err := r.db.Query("SELECT name FROM teams ORDER BY name")
+func (r *TeamRepository) GetAll(ctx context.Context) ([]models.Team, error) {
+	teamRows, err := r.db.QueryContext(ctx, "SELECT name FROM teams ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -92,8 +99,8 @@ func (r *TeamRepository) GetAll() ([]models.Team, error) {
 		teamNames[i] = team.Name
 	}
 
-	memberRows, err := r.db.Query(`
-		SELECT tm.team_name, u.id, u.name, u.is_active
+	memberRows, err := r.db.QueryContext(ctx, `
+		SELECT tm.team_name, tm.role, u.id, u.name, u.is_active, u.max_open_reviews
 		FROM team_members tm
 		INNER JOIN users u ON tm.user_id = u.id
 		WHERE tm.team_name = ANY($1::text[])
@@ -107,17 +114,22 @@ func (r *TeamRepository) GetAll() ([]models.Team, error) {
 	teamsMap := make(map[string]*models.Team)
 	for i := range teams {
 		teams[i].Members = []models.User{}
+		teams[i].Leads = []int{}
 		teamsMap[teams[i].Name] = &teams[i]
 	}
 
 	for memberRows.Next() {
 		var teamName string
+		var role models.TeamRole
 		var user models.User
-		if err := memberRows.Scan(&teamName, &user.ID, &user.Name, &user.IsActive); err != nil {
+		if err := scanUser(memberRows, &user, &teamName, &role); err != nil {
 			return nil, err
 		}
 		if team, exists := teamsMap[teamName]; exists {
 			team.Members = append(team.Members, user)
+			if role == models.TeamRoleLead {
+				team.Leads = append(team.Leads, user.ID)
+			}
 		}
 	}
 
@@ -127,26 +139,55 @@ func (r *TeamRepository) GetAll() ([]models.Team, error) {
 	return teams, nil
 }
 
-func (r *TeamRepository) AddMember(teamName string, userID int) error {
-	_, err := r.db.Exec(
-		"INSERT INTO team_members (team_name, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
-		teamName, userID,
-	)
+// AddMember добавляет пользователя в команду. Первая команда пользователя становится основной
+func (r *TeamRepository) AddMember(ctx context.Context, teamName string, userID int) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO team_members (team_name, user_id, is_primary)
+		VALUES ($1, $2, NOT EXISTS (SELECT 1 FROM team_members WHERE user_id = $2 AND is_primary))
+		ON CONFLICT DO NOTHING
+	`, teamName, userID)
 	return err
 }
 
-func (r *TeamRepository) RemoveMember(teamName string, userID int) error {
-	_, err := r.db.Exec(
-		"DELETE FROM team_members WHERE team_name = $1 AND user_id = $2",
+// RemoveMember удаляет пользователя из команды. Если команда была основной,
+// основной становится первая по имени из оставшихся команд пользователя
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamName string, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var wasPrimary bool
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM team_members WHERE team_name = $1 AND user_id = $2 RETURNING is_primary",
 		teamName, userID,
-	)
-	return err
+	).Scan(&wasPrimary)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if wasPrimary {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE team_members SET is_primary = true
+			WHERE user_id = $1 AND team_name = (SELECT MIN(team_name) FROM team_members WHERE user_id = $1)
+		`, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (r *TeamRepository) GetUserTeam(userID int) (string, error) {
+// GetUserTeam возвращает основную команду пользователя или пустую строку, если он не состоит в командах
+func (r *TeamRepository) GetUserTeam(ctx context.Context, userID int) (string, error) {
 	var teamName string
-	err := r.db.QueryRow(
-		"SELECT team_name FROM team_members WHERE user_id = $1 LIMIT 1",
+	err := r.db.QueryRowContext(ctx,
+		"SELECT team_name FROM team_members WHERE user_id = $1 ORDER BY is_primary DESC, team_name LIMIT 1",
 		userID,
 	).Scan(&teamName)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -154,3 +195,157 @@ func (r *TeamRepository) GetUserTeam(userID int) (string, error) {
 	}
 	return teamName, err
 }
+
+// GetUserTeams возвращает все команды пользователя, основная - первой
+func (r *TeamRepository) GetUserTeams(ctx context.Context, userID int) ([]models.TeamMembership, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT team_name, role, is_primary FROM team_members WHERE user_id = $1 ORDER BY is_primary DESC, team_name",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]models.TeamMembership, 0)
+	for rows.Next() {
+		var membership models.TeamMembership
+		if err := rows.Scan(&membership.TeamName, &membership.Role, &membership.IsPrimary); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, rows.Err()
+}
+
+// SetPrimaryTeam делает команду основной для пользователя.
+// Возвращает false, если пользователь не состоит в этой команде
+func (r *TeamRepository) SetPrimaryTeam(ctx context.Context, userID int, teamName string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Снимаем флаг отдельным запросом, чтобы не нарушить уникальный индекс по основной команде
+	if _, err := tx.ExecContext(ctx, "UPDATE team_members SET is_primary = false WHERE user_id = $1 AND is_primary", userID); err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE team_members SET is_primary = true WHERE user_id = $1 AND team_name = $2",
+		userID, teamName,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	return true, tx.Commit()
+}
+
+// GetMemberRole возвращает роль пользователя в команде или пустую строку, если он в ней не состоит
+func (r *TeamRepository) GetMemberRole(ctx context.Context, teamName string, userID int) (models.TeamRole, error) {
+	var role models.TeamRole
+	err := r.db.QueryRowContext(ctx,
+		"SELECT role FROM team_members WHERE team_name = $1 AND user_id = $2",
+		teamName, userID,
+	).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// SetMemberRole меняет роль пользователя в команде. Возвращает false, если пользователь не состоит в этой команде
+func (r *TeamRepository) SetMemberRole(ctx context.Context, teamName string, userID int, role models.TeamRole) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE team_members SET role = $3 WHERE team_name = $1 AND user_id = $2",
+		teamName, userID, role,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetSettings возвращает настройки команды вместе с пулами ревьюверов или nil, если команда их не задавала
+func (r *TeamRepository) GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error) {
+	settings := &models.TeamSettings{TeamName: teamName}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT required_reviewers, min_reviewers, required_approvals FROM team_settings WHERE team_name = $1",
+		teamName,
+	).Scan(&settings.RequiredReviewers, &settings.MinReviewers, &settings.RequiredApprovals)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT pool_team, reviewers FROM team_reviewer_pools WHERE team_name = $1 ORDER BY pool_team",
+		teamName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings.ReviewerPools = []models.ReviewerPool{}
+	for rows.Next() {
+		var pool models.ReviewerPool
+		if err := rows.Scan(&pool.TeamName, &pool.Reviewers); err != nil {
+			return nil, err
+		}
+		settings.ReviewerPools = append(settings.ReviewerPools, pool)
+	}
+	return settings, rows.Err()
+}
+
+// UpsertSettings сохраняет настройки команды и заменяет ее пулы ревьюверов в одной транзакции
+func (r *TeamRepository) UpsertSettings(ctx context.Context, settings *models.TeamSettings) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO team_settings (team_name, required_reviewers, min_reviewers, required_approvals, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (team_name) DO UPDATE
+		SET required_reviewers = EXCLUDED.required_reviewers,
+			min_reviewers = EXCLUDED.min_reviewers,
+			required_approvals = EXCLUDED.required_approvals,
+			updated_at = EXCLUDED.updated_at
+	`, settings.TeamName, settings.RequiredReviewers, settings.MinReviewers, settings.RequiredApprovals)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM team_reviewer_pools WHERE team_name = $1", settings.TeamName); err != nil {
+		return err
+	}
+	for _, pool := range settings.ReviewerPools {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO team_reviewer_pools (team_name, pool_team, reviewers) VALUES ($1, $2, $3)",
+			settings.TeamName, pool.TeamName, pool.Reviewers,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -1,11 +1,22 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
+
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+
+	"github.com/lib/pq"
 )
 
+// ErrIdentityAlreadyExists возвращается, если учетная запись внешней системы уже привязана к пользователю
+var ErrIdentityAlreadyExists = errors.New("external identity is already linked to a user")
+
+// pqUniqueViolation - код ошибки Postgres при нарушении уникального индекса
+const pqUniqueViolation = "23505"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -14,28 +25,49 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(user *models.User) error {
-	err := r.db.QueryRow(
-		"INSERT INTO users (name, is_active) VALUES ($1, $2) RETURNING id",
-		user.Name, user.IsActive,
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser считывает колонки id, name, is_active, max_open_reviews.
+// prefix - указатели для колонок, выбранных перед колонками пользователя
+func scanUser(row rowScanner, user *models.User, prefix ...interface{}) error {
+	var maxOpenReviews sql.NullInt64
+	dest := append(prefix, &user.ID, &user.Name, &user.IsActive, &maxOpenReviews)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	user.MaxOpenReviews = nil
+	if maxOpenReviews.Valid {
+		limit := int(maxOpenReviews.Int64)
+		user.MaxOpenReviews = &limit
+	}
+	return nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO users (name, is_active, max_open_reviews) VALUES ($1, $2, $3) RETURNING id",
+		user.Name, user.IsActive, user.MaxOpenReviews,
 	).Scan(&user.ID)
 	return err
 }
 
-func (r *UserRepository) GetByID(id int) (*models.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRow(
-		"SELECT id, name, is_active FROM users WHERE id = $1",
+	err := scanUser(r.db.QueryRowContext(ctx,
+		"SELECT id, name, is_active, max_open_reviews FROM users WHERE id = $1",
 		id,
-	).Scan(&user.ID, &user.Name, &user.IsActive)
+	), user)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	return user, err
 }
 
-func (r *UserRepository) GetAll() ([]models.User, error) {
-	rows, err := r.db.Query("SELECT id, name, is_active FROM users ORDER BY id")
+func (r *UserRepository) GetAll(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, is_active, max_open_reviews FROM users ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +76,7 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.IsActive); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -52,23 +84,28 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	return users, rows.Err()
 }
 
-func (r *UserRepository) Update(user *models.User) error {
-	_, err := r.db.Exec(
-		"UPDATE users SET name = $1, is_active = $2 WHERE id = $3",
-		user.Name, user.IsActive, user.ID,
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET name = $1, is_active = $2, max_open_reviews = $3 WHERE id = $4",
+		user.Name, user.IsActive, user.MaxOpenReviews, user.ID,
 	)
 	return err
 }
 
-func (r *UserRepository) GetActiveUsersByTeam(teamName string, excludeUserID int) ([]models.User, error) {
+// GetActiveUsersByTeam возвращает активных участников команды, которые не находятся в отсутствии в момент at
+func (r *UserRepository) GetActiveUsersByTeam(ctx context.Context, teamName string, excludeUserID int, at time.Time) ([]models.User, error) {
 	query := `
-		SELECT u.id, u.name, u.is_active 
+		SELECT u.id, u.name, u.is_active, u.max_open_reviews
 		FROM users u
 		INNER JOIN team_members tm ON u.id = tm.user_id
 		WHERE tm.team_name = $1 AND u.is_active = true AND u.id != $2
-		ORDER BY RANDOM()
+			AND NOT EXISTS (
+				SELECT 1 FROM user_availability ua
+				WHERE ua.user_id = u.id AND ua.starts_at <= $3 AND ua.ends_at > $3
+			)
+		ORDER BY u.id
 	`
-	rows, err := r.db.Query(query, teamName, excludeUserID)
+	rows, err := r.db.QueryContext(ctx, query, teamName, excludeUserID, at)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +114,7 @@ func (r *UserRepository) GetActiveUsersByTeam(teamName string, excludeUserID int
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.IsActive); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -87,8 +124,8 @@ func (r *UserRepository) GetActiveUsersByTeam(teamName string, excludeUserID int
 
 // BulkDeactivateByTeam деактивирует всех пользователей команды
 // Возвращает количество деактивированных пользователей
-func (r *UserRepository) BulkDeactivateByTeam(teamName string) (int, error) {
-	result, err := r.db.Exec(`
+func (r *UserRepository) BulkDeactivateByTeam(ctx context.Context, teamName string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
 		UPDATE users 
 		SET is_active = false 
 		WHERE id IN (
@@ -106,3 +143,168 @@ func (r *UserRepository) BulkDeactivateByTeam(teamName string) (int, error) {
 
 	return int(rowsAffected), nil
 }
+
+func scanUnavailability(row rowScanner, u *models.Unavailability) error {
+	var reassignedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.UserID, &u.StartsAt, &u.EndsAt, &u.Reason, &reassignedAt); err != nil {
+		return err
+	}
+
+	u.ReassignedAt = nil
+	if reassignedAt.Valid {
+		u.ReassignedAt = &reassignedAt.Time
+	}
+	return nil
+}
+
+func (r *UserRepository) CreateUnavailability(ctx context.Context, u *models.Unavailability) error {
+	return r.db.QueryRowContext(ctx,
+		"INSERT INTO user_availability (user_id, starts_at, ends_at, reason) VALUES ($1, $2, $3, $4) RETURNING id",
+		u.UserID, u.StartsAt, u.EndsAt, u.Reason,
+	).Scan(&u.ID)
+}
+
+// GetUnavailabilities возвращает периоды отсутствия пользователя, которые не закончились к моменту at
+func (r *UserRepository) GetUnavailabilities(ctx context.Context, userID int, at time.Time) ([]models.Unavailability, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, starts_at, ends_at, reason, reassigned_at
+		FROM user_availability
+		WHERE user_id = $1 AND ends_at > $2
+		ORDER BY starts_at, id
+	`, userID, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unavailabilities := make([]models.Unavailability, 0)
+	for rows.Next() {
+		var u models.Unavailability
+		if err := scanUnavailability(rows, &u); err != nil {
+			return nil, err
+		}
+		unavailabilities = append(unavailabilities, u)
+	}
+	return unavailabilities, rows.Err()
+}
+
+// DeleteUnavailability удаляет период отсутствия пользователя.
+// Возвращает false, если такого периода у пользователя нет
+func (r *UserRepository) DeleteUnavailability(ctx context.Context, userID int, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM user_availability WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetStartedUnavailabilities возвращает периоды отсутствия, которые идут в момент at
+// и для которых ревью пользователя еще не были переназначены
+func (r *UserRepository) GetStartedUnavailabilities(ctx context.Context, at time.Time) ([]models.Unavailability, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, starts_at, ends_at, reason, reassigned_at
+		FROM user_availability
+		WHERE starts_at <= $1 AND ends_at > $1 AND reassigned_at IS NULL
+		ORDER BY starts_at, id
+	`, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unavailabilities []models.Unavailability
+	for rows.Next() {
+		var u models.Unavailability
+		if err := scanUnavailability(rows, &u); err != nil {
+			return nil, err
+		}
+		unavailabilities = append(unavailabilities, u)
+	}
+	return unavailabilities, rows.Err()
+}
+
+func (r *UserRepository) MarkUnavailabilityReassigned(ctx context.Context, id int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE user_availability SET reassigned_at = $1 WHERE id = $2",
+		at, id,
+	)
+	return err
+}
+
+// GetByIdentity возвращает пользователя, привязанного к учетной записи во внешней системе, или nil
+func (r *UserRepository) GetByIdentity(ctx context.Context, provider models.IdentityProvider, externalID string) (*models.User, error) {
+	user := &models.User{}
+	err := scanUser(r.db.QueryRowContext(ctx, `
+		SELECT u.id, u.name, u.is_active, u.max_open_reviews
+		FROM users u
+		JOIN user_identities ui ON ui.user_id = u.id
+		WHERE ui.provider = $1 AND LOWER(ui.external_id) = LOWER($2)
+	`, provider, externalID), user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// CreateIdentity привязывает учетную запись к пользователю. Если ее параллельно привязали к другому
+// пользователю, уникальный индекс по (provider, LOWER(external_id)) отклоняет вставку и возвращается ErrIdentityAlreadyExists
+func (r *UserRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO user_identities (user_id, provider, external_id) VALUES ($1, $2, $3) RETURNING id, created_at",
+		identity.UserID, identity.Provider, identity.ExternalID,
+	).Scan(&identity.ID, &identity.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return ErrIdentityAlreadyExists
+	}
+	return err
+}
+
+func (r *UserRepository) GetIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, provider, external_id, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY provider, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]models.UserIdentity, 0)
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.ExternalID, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity отвязывает учетную запись во внешней системе от пользователя.
+// Возвращает false, если такой записи у пользователя нет
+func (r *UserRepository) DeleteIdentity(ctx context.Context, userID int, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM user_identities WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_type, payload, status, attempts,
+	next_attempt_at, last_error, response_status, delivered_at, created_at`
+
+func scanWebhookSubscription(row rowScanner, sub *models.WebhookSubscription) error {
+	var events []string
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&events), &sub.CreatedAt); err != nil {
+		return err
+	}
+
+	sub.Events = make([]models.WebhookEventType, len(events))
+	for i, event := range events {
+		sub.Events[i] = models.WebhookEventType(event)
+	}
+	return nil
+}
+
+func scanWebhookDelivery(row rowScanner, d *models.WebhookDelivery) error {
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var payload []byte
+	if err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &d.LastError, &d.ResponseStatus, &deliveredAt, &d.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	d.Payload = payload
+	d.NextAttemptAt = nil
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	d.DeliveredAt = nil
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	events := make([]string, len(sub.Events))
+	for i, event := range sub.Events {
+		events[i] = string(event)
+	}
+
+	return r.db.QueryRowContext(ctx,
+		"INSERT INTO webhook_subscriptions (url, secret, events) VALUES ($1, $2, $3) RETURNING id, created_at",
+		sub.URL, sub.Secret, pq.Array(events),
+	).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+func (r *WebhookRepository) GetSubscriptionByID(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{}
+	err := scanWebhookSubscription(r.db.QueryRowContext(ctx,
+		"SELECT id, url, secret, events, created_at FROM webhook_subscriptions WHERE id = $1",
+		id,
+	), sub)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sub, err
+}
+
+func (r *WebhookRepository) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, "SELECT id, url, secret, events, created_at FROM webhook_subscriptions ORDER BY id")
+}
+
+// GetSubscriptionsForEvent возвращает подписки, которые получают события данного типа
+func (r *WebhookRepository) GetSubscriptionsForEvent(ctx context.Context, eventType models.WebhookEventType) ([]models.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, `
+		SELECT id, url, secret, events, created_at
+		FROM webhook_subscriptions
+		WHERE cardinality(events) = 0 OR $1 = ANY(events)
+		ORDER BY id
+	`, string(eventType))
+}
+
+func (r *WebhookRepository) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]models.WebhookSubscription, 0)
+	for rows.Next() {
+		var sub models.WebhookSubscription
+		if err := scanWebhookSubscription(rows, &sub); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+// DeleteSubscription удаляет подписку вместе с журналом ее доставок.
+// Возвращает false, если подписки нет
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_type, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		d.SubscriptionID, d.EventType, []byte(d.Payload), d.Status, d.NextAttemptAt,
+	).Scan(&d.ID, &d.CreatedAt)
+}
+
+// ClaimDueDeliveries выбирает до limit ожидающих доставок, время попытки которых наступило к now,
+// и откладывает их следующую попытку до leaseUntil. Так одну доставку не возьмут
+// одновременно несколько экземпляров сервиса, а при падении воркера она будет повторена
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns,
+		now, leaseUntil, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateDelivery сохраняет результат попытки доставки
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, response_status = $6, delivered_at = $7
+		WHERE id = $1
+	`, d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastError, d.ResponseStatus, d.DeliveredAt)
+	return err
+}
+
+// GetDeliveries возвращает журнал доставок подписки, начиная с последних
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY id DESC",
+		subscriptionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+	"github.com/gorilla/mux"
+)
+
+// rule разрешает запрос аутентифицированному клиенту
+type rule func(p *auth.Principal, r *http.Request) bool
+
+// roles разрешает запрос клиентам с одной из ролей
+func roles(allowed ...auth.Role) rule {
+	return func(p *auth.Principal, _ *http.Request) bool {
+		return p.HasRole(allowed...)
+	}
+}
+
+// admin разрешает запрос только администраторам
+var admin = roles(auth.RoleAdmin)
+
+// self разрешает запрос пользователю, указанному в пути (/users/{id}/...)
+func self(p *auth.Principal, r *http.Request) bool {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	return err == nil && p.IsUser(id)
+}
+
+// guard аутентифицирует запросы к защищенным маршрутам и проверяет политики доступа
+type guard struct {
+	authenticator *auth.Authenticator
+}
+
+// allow пропускает запрос к next, если клиент аутентифицирован и подходит хотя бы под одно из правил.
+// Без правил маршрут доступен любому аутентифицированному клиенту. Если аутентификация выключена,
+// запрос пропускается без проверок и клиента в контексте нет
+func (g guard) allow(next http.HandlerFunc, rules ...rule) http.Handler {
+	if !g.authenticator.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := g.authenticator.Authenticate(r)
+		if err != nil {
+			if !errors.Is(err, auth.ErrUnauthenticated) {
+				slog.WarnContext(r.Context(), "authentication failed", "route", routeTemplate(r), "error", err)
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pr-reviewer-service"`)
+			writeError(w, r, http.StatusUnauthorized, auth.ErrUnauthenticated.Error())
+			return
+		}
+
+		if len(rules) > 0 && !allowed(p, r, rules) {
+			slog.WarnContext(r.Context(), "access denied", "route", routeTemplate(r), "subject", p.Subject, "role", p.Role)
+			writeError(w, r, http.StatusForbidden, "forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+func allowed(p *auth.Principal, r *http.Request, rules []rule) bool {
+	for _, allow := range rules {
+		if allow(p, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError отвечает ошибкой в том же формате, что и обработчики
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	body := map[string]string{"error": message}
+	if id := logging.RequestID(r.Context()); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
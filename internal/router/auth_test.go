@@ -0,0 +1,100 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+	"github.com/gorilla/mux"
+)
+
+func TestGuard_Allow(t *testing.T) {
+	a, err := auth.NewAuthenticator(auth.Config{APIKeys: "ops:admin:admin-key,ci:bot:bot-key,dev:member:member-key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := guard{authenticator: a}
+
+	var principal *auth.Principal
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		principal = auth.FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}
+	r := mux.NewRouter()
+	r.Handle("/prs", g.allow(ok)).Methods("GET")
+	r.Handle("/teams", g.allow(ok, admin)).Methods("POST")
+	r.Handle("/users/{id}/primary-team", g.allow(ok, admin, self)).Methods("PUT")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		wantCode int
+	}{
+		{name: "no credentials", method: http.MethodGet, path: "/prs", wantCode: http.StatusUnauthorized},
+		{name: "invalid key", method: http.MethodGet, path: "/prs", key: "nope", wantCode: http.StatusUnauthorized},
+		{name: "any role", method: http.MethodGet, path: "/prs", key: "bot-key", wantCode: http.StatusNoContent},
+		{name: "admin only as admin", method: http.MethodPost, path: "/teams", key: "admin-key", wantCode: http.StatusNoContent},
+		{name: "admin only as member", method: http.MethodPost, path: "/teams", key: "member-key", wantCode: http.StatusForbidden},
+		{name: "admin or self as admin", method: http.MethodPut, path: "/users/8/primary-team", key: "admin-key", wantCode: http.StatusNoContent},
+		{name: "admin or self as another client", method: http.MethodPut, path: "/users/8/primary-team", key: "member-key", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rr.Code)
+			}
+			if tt.wantCode == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+			if tt.wantCode == http.StatusNoContent && principal == nil {
+				t.Error("expected principal in request context")
+			}
+		})
+	}
+}
+
+func TestGuard_AllowDisabled(t *testing.T) {
+	a, err := auth.NewAuthenticator(auth.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := guard{authenticator: a}.allow(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, admin)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/teams", nil))
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
+
+func TestSelf(t *testing.T) {
+	user := &auth.Principal{Subject: "bob", Role: auth.RoleMember, UserID: 8}
+	other := &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7}
+	key := &auth.Principal{Subject: "ci", Role: auth.RoleBot}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/users/8/unavailability", nil), map[string]string{"id": "8"})
+	if !self(user, req) {
+		t.Error("expected user 8 to pass self")
+	}
+	if self(other, req) {
+		t.Error("expected user 7 to fail self for user 8")
+	}
+	if self(key, req) {
+		t.Error("expected a principal without user to fail self")
+	}
+}
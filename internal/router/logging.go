@@ -0,0 +1,56 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+	"github.com/gorilla/mux"
+)
+
+// withRequestLog присваивает запросу идентификатор (из заголовка X-Request-ID или новый), возвращает его
+// в ответе и после обработки пишет в лог маршрут, статус и время обработки
+func withRequestLog() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(logging.RequestIDHeader)
+			if !logging.ValidRequestID(id) {
+				id = logging.NewRequestID()
+			}
+			w.Header().Set(logging.RequestIDHeader, id)
+			ctx := logging.WithRequestID(r.Context(), id)
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			if recorder.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			slog.Log(ctx, level, "request",
+				"method", r.Method,
+				"route", routeTemplate(r),
+				"path", r.URL.Path,
+				"status", recorder.status,
+				"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			)
+		})
+	}
+}
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+)
+
+func TestWithRequestLog_RequestID(t *testing.T) {
+	var seen string
+	handler := withRequestLog()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	t.Run("propagates client ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/prs", nil)
+		req.Header.Set(logging.RequestIDHeader, "client-42")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if seen != "client-42" {
+			t.Errorf("expected request ID client-42 in context, got %q", seen)
+		}
+		if got := rr.Header().Get(logging.RequestIDHeader); got != "client-42" {
+			t.Errorf("expected request ID client-42 in response, got %q", got)
+		}
+	})
+
+	t.Run("generates ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/prs", nil)
+		req.Header.Set(logging.RequestIDHeader, "not valid")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if seen == "" || seen == "not valid" {
+			t.Errorf("expected a generated request ID, got %q", seen)
+		}
+		if got := rr.Header().Get(logging.RequestIDHeader); got != seen {
+			t.Errorf("expected request ID %q in response, got %q", seen, got)
+		}
+	})
+}
@@ -2,38 +2,92 @@
 package router
 
 import (
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
 	"github.com/Rodjolo/pr-reviewer-service/internal/handlers"
+	"github.com/Rodjolo/pr-reviewer-service/internal/metrics"
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func NewRouter(h *handlers.Handlers) *mux.Router {
+// NewRouter регистрирует маршруты API. Обработка каждого запроса ограничена requestTimeout,
+// запросы учитываются в метриках m, трассируются через глобальный провайдер OpenTelemetry
+// и записываются в лог с идентификатором запроса. Маршруты API требуют аутентификации через a и проверяют
+// роль клиента; проверки состояния, метрики, документация и вебхуки GitHub/GitLab (они проверяют подпись) открыты
+func NewRouter(h *handlers.Handlers, requestTimeout time.Duration, m *metrics.Metrics, a *auth.Authenticator) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(withTracing(), withRequestLog(), m.Middleware, withTimeout(requestTimeout))
+	g := guard{authenticator: a}
 
-	// PR routes
-	r.HandleFunc("/prs", h.CreatePR).Methods("POST")
-	r.HandleFunc("/prs", h.ListPRs).Methods("GET")
-	r.HandleFunc("/prs/{id}", h.GetPR).Methods("GET")
-	r.HandleFunc("/prs/{id}/reassign", h.ReassignReviewer).Methods("PATCH")
-	r.HandleFunc("/prs/{id}/merge", h.MergePR).Methods("POST")
+	// PR routes. Изменять PR могут его автор, ревьюверы, лиды команды PR, администраторы и боты:
+	// доступ к конкретному PR проверяется обработчиками
+	r.Handle("/prs", g.allow(h.CreatePR)).Methods("POST")
+	r.Handle("/prs", g.allow(h.ListPRs)).Methods("GET")
+	r.Handle("/prs/preview", g.allow(h.PreviewPR)).Methods("POST")
+	r.Handle("/prs/{id}", g.allow(h.GetPR)).Methods("GET")
+	r.Handle("/prs/{id}/events", g.allow(h.GetPREvents)).Methods("GET")
+	r.Handle("/prs/{id}/reassign", g.allow(h.ReassignReviewer)).Methods("PATCH")
+	r.Handle("/prs/{id}/merge", g.allow(h.MergePR)).Methods("POST")
+	r.Handle("/prs/{id}/reviews", g.allow(h.SubmitReview)).Methods("POST")
+	r.Handle("/prs/{id}/close", g.allow(h.ClosePR)).Methods("POST")
+	r.Handle("/prs/{id}/reopen", g.allow(h.ReopenPR)).Methods("POST")
+	r.Handle("/prs/{id}/ready", g.allow(h.MarkPRReady)).Methods("POST")
 
 	// User routes
-	r.HandleFunc("/users", h.CreateUser).Methods("POST")
-	r.HandleFunc("/users", h.ListUsers).Methods("GET")
-	r.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
-	r.HandleFunc("/users/{id}", h.UpdateUser).Methods("PATCH")
-
-	// Team routes
-	r.HandleFunc("/teams", h.CreateTeam).Methods("POST")
-	r.HandleFunc("/teams", h.ListTeams).Methods("GET")
-	r.HandleFunc("/teams/{name}", h.GetTeam).Methods("GET")
-	r.HandleFunc("/teams/{name}/members", h.AddTeamMember).Methods("POST")
-	r.HandleFunc("/teams/{name}/members", h.RemoveTeamMember).Methods("DELETE")
-	r.HandleFunc("/teams/{name}/deactivate", h.BulkDeactivateTeam).Methods("POST")
+	r.Handle("/users", g.allow(h.CreateUser, admin)).Methods("POST")
+	r.Handle("/users", g.allow(h.ListUsers)).Methods("GET")
+	r.Handle("/users/by-identity", g.allow(h.GetUserByIdentity)).Methods("GET")
+	r.Handle("/users/{id}", g.allow(h.GetUser)).Methods("GET")
+	r.Handle("/users/{id}", g.allow(h.UpdateUser, admin)).Methods("PATCH")
+	r.Handle("/users/{id}/unavailability", g.allow(h.AddUserUnavailability, admin, self)).Methods("POST")
+	r.Handle("/users/{id}/unavailability", g.allow(h.ListUserUnavailability)).Methods("GET")
+	r.Handle("/users/{id}/unavailability/{unavailabilityId}", g.allow(h.DeleteUserUnavailability, admin, self)).Methods("DELETE")
+	r.Handle("/users/{id}/teams", g.allow(h.ListUserTeams)).Methods("GET")
+	r.Handle("/users/{id}/primary-team", g.allow(h.SetUserPrimaryTeam, admin, self)).Methods("PUT")
+	r.Handle("/users/{id}/identities", g.allow(h.AddUserIdentity, admin, self)).Methods("POST")
+	r.Handle("/users/{id}/identities", g.allow(h.ListUserIdentities)).Methods("GET")
+	r.Handle("/users/{id}/identities/{identityId}", g.allow(h.DeleteUserIdentity, admin, self)).Methods("DELETE")
+
+	// Team routes. Изменять состав и настройки команды могут администраторы и ее лиды: роль в команде
+	// проверяется обработчиками
+	r.Handle("/teams", g.allow(h.CreateTeam, admin)).Methods("POST")
+	r.Handle("/teams", g.allow(h.ListTeams)).Methods("GET")
+	r.Handle("/teams/{name}", g.allow(h.GetTeam)).Methods("GET")
+	r.Handle("/teams/{name}/members", g.allow(h.AddTeamMember)).Methods("POST")
+	r.Handle("/teams/{name}/members", g.allow(h.RemoveTeamMember)).Methods("DELETE")
+	r.Handle("/teams/{name}/members/{userId}/promote", g.allow(h.PromoteTeamMember)).Methods("POST")
+	r.Handle("/teams/{name}/members/{userId}/demote", g.allow(h.DemoteTeamMember)).Methods("POST")
+	r.Handle("/teams/{name}/deactivate", g.allow(h.BulkDeactivateTeam)).Methods("POST")
+	r.Handle("/teams/{name}/settings", g.allow(h.GetTeamSettings)).Methods("GET")
+	r.Handle("/teams/{name}/settings", g.allow(h.UpdateTeamSettings)).Methods("PUT")
+
+	// Ownership routes
+	r.Handle("/ownership", g.allow(h.CreateOwnershipRule, admin)).Methods("POST")
+	r.Handle("/ownership", g.allow(h.ListOwnershipRules)).Methods("GET")
+	r.Handle("/ownership/{id}", g.allow(h.DeleteOwnershipRule, admin)).Methods("DELETE")
+
+	// Webhook routes
+	r.Handle("/webhooks", g.allow(h.CreateWebhook, admin)).Methods("POST")
+	r.Handle("/webhooks", g.allow(h.ListWebhooks, admin)).Methods("GET")
+	r.Handle("/webhooks/{id}", g.allow(h.GetWebhook, admin)).Methods("GET")
+	r.Handle("/webhooks/{id}", g.allow(h.DeleteWebhook, admin)).Methods("DELETE")
+	r.Handle("/webhooks/{id}/deliveries", g.allow(h.ListWebhookDeliveries, admin)).Methods("GET")
+
+	// VCS integration routes
+	r.HandleFunc("/integrations/github", h.GitHubWebhook).Methods("POST")
+	r.HandleFunc("/integrations/gitlab", h.GitLabWebhook).Methods("POST")
+
+	// Health routes
+	r.HandleFunc("/healthz", h.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", h.Readyz).Methods("GET")
+
+	// Metrics route
+	r.Handle("/metrics", m.Handler()).Methods("GET")
 
 	// Stats route
-	r.HandleFunc("/stats", h.GetStats).Methods("GET")
+	r.Handle("/stats", g.allow(h.GetStats)).Methods("GET")
 
 	// Swagger documentation
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/logging"
+	"github.com/gorilla/mux"
+)
+
+// DefaultRequestTimeout - время, за которое должен быть обработан запрос, включая все запросы к БД
+const DefaultRequestTimeout = 10 * time.Second
+
+// withTimeout ограничивает время обработки запроса: контекст запроса отменяется по истечении timeout,
+// и незавершенные запросы к БД прерываются. Если из-за этого обработчик ответил ошибкой сервера,
+// клиент получает 504 вместо 500
+func withTimeout(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+		})
+	}
+}
+
+// timeoutWriter подменяет ответ 500 на 504, если к моменту ответа истек таймаут запроса
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if status == http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		w.ResponseWriter.Header().Set("Content-Type", "application/json")
+		w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+		body := map[string]string{"error": "request timed out"}
+		if id := logging.RequestID(w.ctx); id != "" {
+			body["request_id"] = id
+		}
+		_ = json.NewEncoder(w.ResponseWriter).Encode(body)
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		// Тело ошибки обработчика заменено ответом 504
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,34 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// withTracing создает спан на каждый запрос. Спан называется по методу и шаблону маршрута gorilla/mux
+// (например, "GET /prs/{id}"), чтобы идентификаторы из пути не размножали имена операций
+func withTracing() mux.MiddlewareFunc {
+	traced := otelhttp.NewMiddleware("http.server", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + routeTemplate(r)
+	}))
+	return func(next http.Handler) http.Handler {
+		return traced(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.route", routeTemplate(r)))
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// routeTemplate возвращает шаблон маршрута, с которым совпал запрос
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
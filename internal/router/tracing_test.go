@@ -0,0 +1,40 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/tracing/tracingtest"
+	"github.com/gorilla/mux"
+)
+
+func TestWithTracing_NamesSpansByRoute(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	r := mux.NewRouter()
+	r.Use(withTracing())
+	r.HandleFunc("/prs/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/prs/42", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /prs/{id}" {
+		t.Errorf("expected span name %q, got %q", "GET /prs/{id}", spans[0].Name())
+	}
+
+	var route string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "http.route" {
+			route = attr.Value.AsString()
+		}
+	}
+	if route != "/prs/{id}" {
+		t.Errorf("expected http.route %q, got %q", "/prs/{id}", route)
+	}
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+)
+
+// actorID возвращает пользователя, от имени которого выполняется запрос, для записи в историю PR.
+// Для фоновых задач, клиентов без пользователя и при выключенной аутентификации возвращает nil
+func actorID(ctx context.Context) *int {
+	p := auth.FromContext(ctx)
+	if p == nil || p.UserID == 0 {
+		return nil
+	}
+	id := p.UserID
+	return &id
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultAvailabilityCheckInterval - как часто AvailabilityWorker проверяет начавшиеся периоды отсутствия
+const DefaultAvailabilityCheckInterval = time.Minute
+
+// AvailabilityWorker в фоне переназначает открытые ревью пользователей, у которых начался период отсутствия
+type AvailabilityWorker struct {
+	userService *UserService
+	interval    time.Duration
+	running     atomic.Bool
+}
+
+func NewAvailabilityWorker(userService *UserService, interval time.Duration) *AvailabilityWorker {
+	if interval <= 0 {
+		interval = DefaultAvailabilityCheckInterval
+	}
+	return &AvailabilityWorker{
+		userService: userService,
+		interval:    interval,
+	}
+}
+
+// Run выполняет проверку сразу и затем с заданным интервалом, пока не будет отменен ctx.
+// Начатая итерация не прерывается отменой ctx, чтобы при остановке сервера работа не обрывалась на середине
+func (w *AvailabilityWorker) Run(ctx context.Context) {
+	w.running.Store(true)
+	defer w.running.Store(false)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(context.WithoutCancel(ctx))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running сообщает, выполняется ли Run
+func (w *AvailabilityWorker) Running() bool {
+	return w.running.Load()
+}
+
+func (w *AvailabilityWorker) runOnce(ctx context.Context) {
+	reassigned, err := w.userService.ReassignAwayReviewers(ctx, w.userService.options.now())
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to reassign reviews of unavailable users", "error", err)
+	}
+	if reassigned > 0 {
+		slog.InfoContext(ctx, "reassigned reviews of unavailable users", "count", reassigned)
+	}
+}
@@ -1,24 +1,90 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+)
 
 // Predefined errors for service layer following Go 1.13+ error handling best practices
 var (
 	// User errors
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrInvalidUnavailability  = errors.New("invalid unavailability: ends_at must be after starts_at")
+	ErrUnavailabilityNotFound = errors.New("unavailability not found")
+	ErrUserIdentityNotFound   = errors.New("user identity not found")
+	ErrIdentityAlreadyExists  = repository.ErrIdentityAlreadyExists
 
 	// Team errors
-	ErrTeamNotFound      = errors.New("team not found")
-	ErrTeamAlreadyExists = errors.New("team already exists")
+	ErrTeamNotFound        = errors.New("team not found")
+	ErrTeamAlreadyExists   = errors.New("team already exists")
+	ErrNotTeamMember       = errors.New("user is not a member of the team")
+	ErrInvalidTeamSettings = errors.New("invalid team settings: required_reviewers must be at least 1, min_reviewers must be between 0 and required_reviewers, required_approvals must be between 0 and min_reviewers")
+	ErrInvalidReviewerPool = errors.New("invalid reviewer pool: pool team must exist, differ from the team itself and be listed once")
+
+	// Ownership errors
+	ErrInvalidOwnershipRule  = errors.New("invalid ownership rule: pattern must be a non-empty CODEOWNERS path pattern and exactly one of team_name and user_id must be set")
+	ErrOwnershipRuleNotFound = errors.New("ownership rule not found")
 
 	// PR errors
-	ErrPRNotFound            = errors.New("PR not found")
-	ErrPRAlreadyMerged       = errors.New("cannot reassign reviewer: PR is already merged")
-	ErrReviewerNotAssigned   = errors.New("old reviewer is not assigned to this PR")
-	ErrReviewerNotInTeam     = errors.New("reviewer is not in any team")
-	ErrNoAvailableReviewers  = errors.New("no available reviewers in the team")
-	ErrAuthorNotFound        = errors.New("author not found")
-	ErrAuthorNotInTeam       = errors.New("author is not in any team")
-	ErrInsufficientReviewers = errors.New("insufficient active reviewers in team")
-	ErrCannotReviewOwnPR     = errors.New("author cannot review their own PR")
+	ErrPRNotFound              = errors.New("PR not found")
+	ErrPRAlreadyMerged         = errors.New("cannot reassign reviewer: PR is already merged")
+	ErrReviewerNotAssigned     = repository.ErrReviewerNotAssigned
+	ErrReviewerNotInTeam       = errors.New("reviewer is not in any team")
+	ErrNoAvailableReviewers    = errors.New("no available reviewers in the team")
+	ErrAuthorNotFound          = errors.New("author not found")
+	ErrAuthorNotInTeam         = errors.New("author is not in any team")
+	ErrAuthorNotTeamMember     = errors.New("author is not a member of the requested team")
+	ErrInsufficientReviewers   = errors.New("insufficient active reviewers in team")
+	ErrCannotReviewOwnPR       = errors.New("author cannot review their own PR")
+	ErrReviewersAtCapacity     = errors.New("all eligible reviewers have reached their open review limit")
+	ErrReviewerApproved        = repository.ErrReviewerApproved
+	ErrMergeBlocked            = repository.ErrMergeBlocked
+	ErrPRNotOpen               = repository.ErrPRNotOpen
+	ErrInvalidStatusTransition = repository.ErrInvalidStatusTransition
+
+	// Review errors
+	ErrNotPRReviewer      = repository.ErrNotPRReviewer
+	ErrReviewOnMergedPR   = errors.New("cannot submit review: PR is already merged")
+	ErrInvalidReviewState = errors.New("invalid review state: expected APPROVED or CHANGES_REQUESTED")
+
+	// Webhook errors
+	ErrWebhookNotFound     = errors.New("webhook subscription not found")
+	ErrInvalidWebhookEvent = errors.New("invalid webhook event type")
+
+	// Integration errors
+	ErrIntegrationNotConfigured = errors.New("integration is not configured")
+	ErrInvalidSignature         = errors.New("invalid webhook signature")
+	ErrInvalidPayload           = errors.New("invalid webhook payload")
+	ErrIdentityNotFound         = errors.New("no user is linked to this external identity")
+
+	// Reviewer selection errors
+	ErrUnknownSelectionStrategy = errors.New("unknown reviewer selection strategy")
 )
+
+// MergeBlockedError описывает, каких одобрений не хватает для мержа PR.
+// Оборачивает ErrMergeBlocked, поэтому проверяется через errors.Is или errors.As
+type MergeBlockedError struct {
+	PendingReviewers   []int
+	ChangesRequestedBy []int
+	RequiredApprovals  int
+	Approvals          int
+}
+
+// MissingApprovals возвращает, сколько одобрений не хватает до требуемого количества
+func (e *MergeBlockedError) MissingApprovals() int {
+	if e.Approvals >= e.RequiredApprovals {
+		return 0
+	}
+	return e.RequiredApprovals - e.Approvals
+}
+
+func (e *MergeBlockedError) Error() string {
+	return fmt.Sprintf("%s: %d of %d required approvals, %d reviewers requested changes",
+		ErrMergeBlocked, e.Approvals, e.RequiredApprovals, len(e.ChangesRequestedBy))
+}
+
+func (e *MergeBlockedError) Unwrap() error {
+	return ErrMergeBlocked
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// DefaultReadinessCheckTimeout - сколько readiness-проверка ждет ответа БД
+const DefaultReadinessCheckTimeout = 2 * time.Second
+
+// WorkerStatus сообщает, работает ли фоновая задача
+type WorkerStatus interface {
+	Running() bool
+}
+
+// HealthService проверяет, может ли сервис принимать запросы
+type HealthService struct {
+	healthRepo    repository.HealthRepositoryInterface
+	schemaVersion int
+	workers       map[string]WorkerStatus
+	checkTimeout  time.Duration
+	shuttingDown  atomic.Bool
+}
+
+// NewHealthService создает проверку готовности: БД должна отвечать, схема должна быть на версии schemaVersion,
+// а все фоновые задачи из workers (по именам компонентов) - работать
+func NewHealthService(healthRepo repository.HealthRepositoryInterface, schemaVersion int, workers map[string]WorkerStatus) *HealthService {
+	return &HealthService{
+		healthRepo:    healthRepo,
+		schemaVersion: schemaVersion,
+		workers:       workers,
+		checkTimeout:  DefaultReadinessCheckTimeout,
+	}
+}
+
+// Liveness сообщает, что процесс запущен и обрабатывает запросы. Зависимости не проверяются,
+// чтобы недоступность БД не приводила к перезапуску сервиса
+func (s *HealthService) Liveness() models.HealthReport {
+	return models.HealthReport{Status: models.HealthStatusUp}
+}
+
+// Readiness проверяет зависимости сервиса. После начала остановки сервис всегда не готов
+func (s *HealthService) Readiness(ctx context.Context) models.HealthReport {
+	components := make(map[string]models.ComponentHealth, len(s.workers)+3)
+
+	if s.shuttingDown.Load() {
+		components["server"] = componentDown(fmt.Errorf("shutting down"))
+	} else {
+		components["server"] = models.ComponentHealth{Status: models.HealthStatusUp}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.checkTimeout)
+	defer cancel()
+
+	if err := s.healthRepo.Ping(ctx); err != nil {
+		components["database"] = componentDown(err)
+		components["migrations"] = componentDown(fmt.Errorf("database unavailable"))
+	} else {
+		components["database"] = models.ComponentHealth{Status: models.HealthStatusUp}
+		components["migrations"] = s.checkMigrations(ctx)
+	}
+
+	for name, worker := range s.workers {
+		if worker.Running() {
+			components[name] = models.ComponentHealth{Status: models.HealthStatusUp}
+		} else {
+			components[name] = componentDown(fmt.Errorf("not running"))
+		}
+	}
+
+	report := models.HealthReport{Status: models.HealthStatusUp, Components: components}
+	for _, component := range components {
+		if component.Status != models.HealthStatusUp {
+			report.Status = models.HealthStatusDown
+			break
+		}
+	}
+	return report
+}
+
+// SetShuttingDown переводит сервис в состояние "не готов" при остановке, чтобы балансировщик перестал направлять
+// на него новые запросы, пока завершаются текущие
+func (s *HealthService) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) models.ComponentHealth {
+	version, dirty, err := s.healthRepo.SchemaVersion(ctx)
+	if err != nil {
+		return componentDown(fmt.Errorf("failed to get schema version: %w", err))
+	}
+	if dirty {
+		return componentDown(fmt.Errorf("migration %d failed and left the schema dirty", version))
+	}
+	if version != s.schemaVersion {
+		return componentDown(fmt.Errorf("schema version %d, expected %d", version, s.schemaVersion))
+	}
+	return models.ComponentHealth{Status: models.HealthStatusUp}
+}
+
+func componentDown(err error) models.ComponentHealth {
+	return models.ComponentHealth{Status: models.HealthStatusDown, Error: err.Error()}
+}
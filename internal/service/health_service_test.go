@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+type mockHealthRepository struct {
+	pingErr error
+	version int
+	dirty   bool
+}
+
+func (m *mockHealthRepository) Ping(_ context.Context) error { return m.pingErr }
+func (m *mockHealthRepository) SchemaVersion(_ context.Context) (int, bool, error) {
+	return m.version, m.dirty, nil
+}
+
+type fakeWorker bool
+
+func (w fakeWorker) Running() bool { return bool(w) }
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name         string
+		repo         *mockHealthRepository
+		worker       fakeWorker
+		shuttingDown bool
+		wantDown     []string
+	}{
+		{name: "ready", repo: &mockHealthRepository{version: 3}, worker: true},
+		{name: "database unavailable", repo: &mockHealthRepository{pingErr: errors.New("connection refused")}, worker: true, wantDown: []string{"database", "migrations"}},
+		{name: "schema behind", repo: &mockHealthRepository{version: 2}, worker: true, wantDown: []string{"migrations"}},
+		{name: "dirty schema", repo: &mockHealthRepository{version: 3, dirty: true}, worker: true, wantDown: []string{"migrations"}},
+		{name: "worker stopped", repo: &mockHealthRepository{version: 3}, worker: false, wantDown: []string{"webhook_worker"}},
+		{name: "shutting down", repo: &mockHealthRepository{version: 3}, worker: true, shuttingDown: true, wantDown: []string{"server"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewHealthService(tt.repo, 3, map[string]WorkerStatus{"webhook_worker": tt.worker})
+			if tt.shuttingDown {
+				service.SetShuttingDown()
+			}
+
+			report := service.Readiness(context.Background())
+
+			wantStatus := models.HealthStatusUp
+			if len(tt.wantDown) > 0 {
+				wantStatus = models.HealthStatusDown
+			}
+			if report.Status != wantStatus {
+				t.Errorf("expected status %s, got %s", wantStatus, report.Status)
+			}
+
+			down := make(map[string]bool)
+			for _, name := range tt.wantDown {
+				down[name] = true
+			}
+			for name, component := range report.Components {
+				if down[name] != (component.Status == models.HealthStatusDown) {
+					t.Errorf("unexpected status %s of component %s (%s)", component.Status, name, component.Error)
+				}
+			}
+			if len(report.Components) != 4 {
+				t.Errorf("expected 4 components, got %d", len(report.Components))
+			}
+		})
+	}
+}
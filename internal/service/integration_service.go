@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+const (
+	// GitHubSignatureHeader содержит подпись тела запроса GitHub: "sha256=" + hex(HMAC-SHA256(secret, body))
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	// GitHubEventHeader содержит тип события GitHub
+	GitHubEventHeader = "X-GitHub-Event"
+	// GitLabTokenHeader содержит секретный токен, заданный в настройках вебхука GitLab
+	GitLabTokenHeader = "X-Gitlab-Token"
+	// GitLabEventHeader содержит тип события GitLab
+	GitLabEventHeader = "X-Gitlab-Event"
+)
+
+// Результаты обработки вебхука провайдера (поле result в dto.IntegrationResponse)
+const (
+	IntegrationResultCreated   = "created"
+	IntegrationResultMerged    = "merged"
+	IntegrationResultClosed    = "closed"
+	IntegrationResultReopened  = "reopened"
+	IntegrationResultReady     = "ready"
+	IntegrationResultUnchanged = "unchanged"
+	IntegrationResultIgnored   = "ignored"
+)
+
+// externalPRAction - что произошло с pull request'ом у провайдера
+type externalPRAction string
+
+const (
+	externalPROpened   externalPRAction = "opened"
+	externalPRClosed   externalPRAction = "closed"
+	externalPRMerged   externalPRAction = "merged"
+	externalPRReopened externalPRAction = "reopened"
+	externalPRReady    externalPRAction = "ready"
+)
+
+// externalPREvent - событие pull request'а, приведенное к общему для провайдеров виду
+type externalPREvent struct {
+	Ref         models.ExternalRef
+	Title       string
+	AuthorLogin string
+	Action      externalPRAction
+	Draft       bool
+}
+
+// IntegrationService принимает вебхуки GitHub и GitLab и отражает жизненный цикл их pull request'ов в PR сервиса.
+// Повторная доставка одного и того же события не меняет PR: он находится по ссылке на внешний pull request
+type IntegrationService struct {
+	prService    *PRService
+	prRepo       repository.PRRepositoryInterface
+	userRepo     repository.UserRepositoryInterface
+	githubSecret string
+	gitlabSecret string
+}
+
+// NewIntegrationService создает сервис интеграций. Пустой секрет отключает соответствующего провайдера
+func NewIntegrationService(prService *PRService, prRepo repository.PRRepositoryInterface, userRepo repository.UserRepositoryInterface, githubSecret, gitlabSecret string) *IntegrationService {
+	return &IntegrationService{
+		prService:    prService,
+		prRepo:       prRepo,
+		userRepo:     userRepo,
+		githubSecret: githubSecret,
+		gitlabSecret: gitlabSecret,
+	}
+}
+
+type githubPullRequestPayload struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Title  string `json:"title"`
+		Number int    `json:"number"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// HandleGitHub проверяет подпись и применяет событие pull_request из GitHub.
+// Остальные события (в том числе ping) игнорируются
+func (s *IntegrationService) HandleGitHub(ctx context.Context, eventType, signature string, body []byte) (*dto.IntegrationResponse, error) {
+	if s.githubSecret == "" {
+		return nil, ErrIntegrationNotConfigured
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhookPayload(s.githubSecret, body))) {
+		return nil, ErrInvalidSignature
+	}
+	if eventType != "pull_request" {
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	}
+
+	var payload githubPullRequestPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if payload.Repository.FullName == "" || payload.PullRequest.Number <= 0 {
+		return nil, fmt.Errorf("%w: repository and pull request number are required", ErrInvalidPayload)
+	}
+
+	event := externalPREvent{
+		Ref: models.ExternalRef{
+			Provider: models.ProviderGitHub,
+			Repo:     payload.Repository.FullName,
+			Number:   payload.PullRequest.Number,
+		},
+		Title:       payload.PullRequest.Title,
+		AuthorLogin: payload.PullRequest.User.Login,
+		Draft:       payload.PullRequest.Draft,
+	}
+	switch payload.Action {
+	case "opened":
+		event.Action = externalPROpened
+	case "closed":
+		event.Action = externalPRClosed
+		if payload.PullRequest.Merged {
+			event.Action = externalPRMerged
+		}
+	case "reopened":
+		event.Action = externalPRReopened
+	case "ready_for_review":
+		event.Action = externalPRReady
+	default:
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	}
+
+	return s.apply(ctx, event)
+}
+
+type gitlabMergeRequestPayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		Title  string `json:"title"`
+		Action string `json:"action"`
+		IID    int    `json:"iid"`
+		Draft  bool   `json:"draft"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Draft *struct {
+			Previous bool `json:"previous"`
+			Current  bool `json:"current"`
+		} `json:"draft"`
+	} `json:"changes"`
+}
+
+// HandleGitLab проверяет токен и применяет событие Merge Request Hook из GitLab.
+// Автором нового PR считается пользователь, открывший merge request
+func (s *IntegrationService) HandleGitLab(ctx context.Context, eventType, token string, body []byte) (*dto.IntegrationResponse, error) {
+	if s.gitlabSecret == "" {
+		return nil, ErrIntegrationNotConfigured
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.gitlabSecret)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+	if eventType != "Merge Request Hook" {
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	}
+
+	var payload gitlabMergeRequestPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if payload.Project.PathWithNamespace == "" || payload.ObjectAttributes.IID <= 0 {
+		return nil, fmt.Errorf("%w: project and merge request iid are required", ErrInvalidPayload)
+	}
+
+	event := externalPREvent{
+		Ref: models.ExternalRef{
+			Provider: models.ProviderGitLab,
+			Repo:     payload.Project.PathWithNamespace,
+			Number:   payload.ObjectAttributes.IID,
+		},
+		Title:       payload.ObjectAttributes.Title,
+		AuthorLogin: payload.User.Username,
+		Draft:       payload.ObjectAttributes.Draft,
+	}
+	switch payload.ObjectAttributes.Action {
+	case "open":
+		event.Action = externalPROpened
+	case "close":
+		event.Action = externalPRClosed
+	case "merge":
+		event.Action = externalPRMerged
+	case "reopen":
+		event.Action = externalPRReopened
+	case "update":
+		if draft := payload.Changes.Draft; draft != nil && draft.Previous && !draft.Current {
+			event.Action = externalPRReady
+			break
+		}
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	default:
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	}
+
+	return s.apply(ctx, event)
+}
+
+// apply отражает событие на PR. События по pull request'ам, которые не были открыты
+// через интеграцию, и переходы, недопустимые для текущего статуса PR (например, закрытие смерженного
+// или reopen черновика), игнорируются; событие, уже отраженное на PR, ничего не меняет
+func (s *IntegrationService) apply(ctx context.Context, event externalPREvent) (*dto.IntegrationResponse, error) {
+	pr, err := s.prRepo.GetByExternalRef(ctx, event.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR: %w", err)
+	}
+
+	if event.Action == externalPROpened {
+		if pr != nil {
+			return &dto.IntegrationResponse{Result: IntegrationResultUnchanged, PR: pr}, nil
+		}
+		return s.create(ctx, event)
+	}
+
+	if pr == nil {
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored}, nil
+	}
+
+	var target models.PRStatus
+	var result string
+	var transition func(ctx context.Context, id int) (*models.PR, error)
+	switch event.Action {
+	case externalPRMerged:
+		// Pull request уже смержен у провайдера, поэтому требования к одобрениям не блокируют мерж,
+		// а лишь помечают его как принудительный
+		target, result = models.PRStatusMerged, IntegrationResultMerged
+		transition = func(ctx context.Context, id int) (*models.PR, error) { return s.prService.MergePR(ctx, id, true) }
+	case externalPRClosed:
+		target, result, transition = models.PRStatusClosed, IntegrationResultClosed, s.prService.ClosePR
+	case externalPRReopened:
+		target, result, transition = models.PRStatusOpen, IntegrationResultReopened, s.prService.ReopenPR
+	case externalPRReady:
+		target, result, transition = models.PRStatusOpen, IntegrationResultReady, s.prService.MarkReady
+	}
+
+	if pr.Status == target {
+		return &dto.IntegrationResponse{Result: IntegrationResultUnchanged, PR: pr}, nil
+	}
+
+	updated, err := transition(ctx, pr.ID)
+	if errors.Is(err, ErrInvalidStatusTransition) {
+		return &dto.IntegrationResponse{Result: IntegrationResultIgnored, PR: pr}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dto.IntegrationResponse{Result: result, PR: updated}, nil
+}
+
+func (s *IntegrationService) create(ctx context.Context, event externalPREvent) (*dto.IntegrationResponse, error) {
+	author, err := s.userRepo.GetByIdentity(ctx, event.Ref.Provider, event.AuthorLogin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author: %w", err)
+	}
+	if author == nil {
+		return nil, fmt.Errorf("%w: %s user %q", ErrIdentityNotFound, event.Ref.Provider, event.AuthorLogin)
+	}
+
+	pr, err := s.prService.CreateExternalPR(ctx, event.Title, author.ID, event.Ref, event.Draft)
+	if err != nil {
+		// Параллельная доставка того же события могла создать PR раньше (уникальный индекс по ссылке)
+		if existing, getErr := s.prRepo.GetByExternalRef(ctx, event.Ref); getErr == nil && existing != nil {
+			return &dto.IntegrationResponse{Result: IntegrationResultUnchanged, PR: existing}, nil
+		}
+		return nil, err
+	}
+	return &dto.IntegrationResponse{Result: IntegrationResultCreated, PR: pr}, nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+const testGitHubSecret = "github-secret"
+
+func githubPullRequestBody(action string, merged bool) []byte {
+	mergedValue := "false"
+	if merged {
+		mergedValue = "true"
+	}
+	return []byte(`{
+		"action": "` + action + `",
+		"pull_request": {"number": 42, "title": "Add feature", "draft": false, "merged": ` + mergedValue + `, "user": {"login": "alice"}},
+		"repository": {"full_name": "org/service"}
+	}`)
+}
+
+func newIntegrationTestService(prRepo *mockPRRepository, userRepo *mockUserRepository) *IntegrationService {
+	teamRepo := &mockTeamRepository{
+		getUserTeamFunc: func(userID int) (string, error) {
+			return "backend", nil
+		},
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			return &models.TeamSettings{TeamName: teamName, RequiredReviewers: 2, MinReviewers: 2, RequiredApprovals: 1}, nil
+		},
+	}
+	prService := NewPRService(prRepo, userRepo, teamRepo)
+	return NewIntegrationService(prService, prRepo, userRepo, testGitHubSecret, "gitlab-token")
+}
+
+func TestHandleGitHub_NotConfigured(t *testing.T) {
+	service := NewIntegrationService(nil, &mockPRRepository{}, &mockUserRepository{}, "", "")
+	_, err := service.HandleGitHub(context.Background(), "pull_request", "", githubPullRequestBody("opened", false))
+
+	if !errors.Is(err, ErrIntegrationNotConfigured) {
+		t.Errorf("expected ErrIntegrationNotConfigured, got %v", err)
+	}
+}
+
+func TestHandleGitHub_InvalidSignature(t *testing.T) {
+	service := newIntegrationTestService(&mockPRRepository{}, &mockUserRepository{})
+	body := githubPullRequestBody("opened", false)
+	_, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload("wrong-secret", body), body)
+
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestHandleGitHub_OpenedCreatesPR(t *testing.T) {
+	var created *models.PR
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			pr.ID = 1
+			created = pr
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIdentityFunc: func(provider models.IdentityProvider, externalID string) (*models.User, error) {
+			if provider != models.ProviderGitHub || externalID != "alice" {
+				t.Errorf("unexpected identity lookup %s/%s", provider, externalID)
+			}
+			return &models.User{ID: 1, IsActive: true}, nil
+		},
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+
+	service := newIntegrationTestService(mockPR, mockUser)
+	body := githubPullRequestBody("opened", false)
+	result, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != IntegrationResultCreated {
+		t.Errorf("expected result %q, got %q", IntegrationResultCreated, result.Result)
+	}
+	if created == nil || created.AuthorID != 1 || created.Title != "Add feature" {
+		t.Fatalf("unexpected created PR: %+v", created)
+	}
+	want := models.ExternalRef{Provider: models.ProviderGitHub, Repo: "org/service", Number: 42}
+	if created.External == nil || *created.External != want {
+		t.Errorf("expected external ref %+v, got %+v", want, created.External)
+	}
+}
+
+func TestHandleGitHub_DuplicateOpenedIsIdempotent(t *testing.T) {
+	existing := &models.PR{ID: 7, Status: models.PRStatusOpen}
+	mockPR := &mockPRRepository{
+		getByExternalRefFunc: func(ref models.ExternalRef) (*models.PR, error) {
+			return existing, nil
+		},
+		createFunc: func(pr *models.PR) error {
+			t.Error("expected no PR to be created")
+			return nil
+		},
+	}
+
+	service := newIntegrationTestService(mockPR, &mockUserRepository{})
+	body := githubPullRequestBody("opened", false)
+	result, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != IntegrationResultUnchanged || result.PR != existing {
+		t.Errorf("expected existing PR to be returned unchanged, got %+v", result)
+	}
+}
+
+func TestHandleGitHub_UnknownAuthor(t *testing.T) {
+	service := newIntegrationTestService(&mockPRRepository{}, &mockUserRepository{})
+	body := githubPullRequestBody("opened", false)
+	_, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+
+	if !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("expected ErrIdentityNotFound, got %v", err)
+	}
+}
+
+func TestHandleGitHub_ClosedMergedForcesMerge(t *testing.T) {
+	mergedForced := false
+	mockPR := &mockPRRepository{
+		getByExternalRefFunc: func(ref models.ExternalRef) (*models.PR, error) {
+			return &models.PR{ID: 7, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2}}, nil
+		},
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2}}, nil
+		},
+		mergeFunc: func(id int, forced bool) error {
+			mergedForced = forced
+			return nil
+		},
+	}
+
+	service := newIntegrationTestService(mockPR, &mockUserRepository{})
+	body := githubPullRequestBody("closed", true)
+	result, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != IntegrationResultMerged || result.PR.Status != models.PRStatusMerged {
+		t.Errorf("expected PR to be merged, got %+v", result)
+	}
+	if !mergedForced {
+		t.Error("expected merge without approvals to be recorded as forced")
+	}
+}
+
+func TestHandleGitHub_IgnoresUnknownPRAndOtherEvents(t *testing.T) {
+	service := newIntegrationTestService(&mockPRRepository{}, &mockUserRepository{})
+
+	body := githubPullRequestBody("closed", false)
+	result, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+	if err != nil || result.Result != IntegrationResultIgnored {
+		t.Errorf("expected close of unknown PR to be ignored, got %+v, %v", result, err)
+	}
+
+	ping := []byte(`{"zen": "Keep it logically awesome."}`)
+	result, err = service.HandleGitHub(context.Background(), "ping", SignWebhookPayload(testGitHubSecret, ping), ping)
+	if err != nil || result.Result != IntegrationResultIgnored {
+		t.Errorf("expected ping to be ignored, got %+v, %v", result, err)
+	}
+}
+
+func TestHandleGitLab_CloseClosesPR(t *testing.T) {
+	var closedStatus models.PRStatus
+	mockPR := &mockPRRepository{
+		getByExternalRefFunc: func(ref models.ExternalRef) (*models.PR, error) {
+			if ref.Provider != models.ProviderGitLab || ref.Repo != "group/project" || ref.Number != 5 {
+				t.Errorf("unexpected external ref %+v", ref)
+			}
+			return &models.PR{ID: 3, Status: models.PRStatusOpen}, nil
+		},
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Status: models.PRStatusOpen}, nil
+		},
+		updateStatusFunc: func(id int, status models.PRStatus) error {
+			closedStatus = status
+			return nil
+		},
+	}
+
+	service := newIntegrationTestService(mockPR, &mockUserRepository{})
+	body := []byte(`{
+		"object_kind": "merge_request",
+		"user": {"username": "bob"},
+		"project": {"path_with_namespace": "group/project"},
+		"object_attributes": {"iid": 5, "title": "Fix bug", "action": "close"}
+	}`)
+
+	if _, err := service.HandleGitLab(context.Background(), "Merge Request Hook", "wrong-token", body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	result, err := service.HandleGitLab(context.Background(), "Merge Request Hook", "gitlab-token", body)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != IntegrationResultClosed || closedStatus != models.PRStatusClosed {
+		t.Errorf("expected PR to be closed, got %+v (status %s)", result, closedStatus)
+	}
+}
+
+func TestHandleGitHub_IgnoresInapplicableTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.PRStatus
+		action string
+	}{
+		{"closed for merged PR", models.PRStatusMerged, "closed"},
+		{"reopened for draft", models.PRStatusDraft, "reopened"},
+		{"ready_for_review for closed PR", models.PRStatusClosed, "ready_for_review"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPR := &mockPRRepository{
+				getByExternalRefFunc: func(ref models.ExternalRef) (*models.PR, error) {
+					return &models.PR{ID: 7, AuthorID: 1, Status: tt.status}, nil
+				},
+				getByIDFunc: func(id int) (*models.PR, error) {
+					return &models.PR{ID: id, AuthorID: 1, Status: tt.status}, nil
+				},
+				updateStatusFunc: func(id int, status models.PRStatus) error {
+					t.Errorf("expected status to stay %s, got update to %s", tt.status, status)
+					return nil
+				},
+			}
+
+			service := newIntegrationTestService(mockPR, &mockUserRepository{})
+			body := githubPullRequestBody(tt.action, false)
+			result, err := service.HandleGitHub(context.Background(), "pull_request", SignWebhookPayload(testGitHubSecret, body), body)
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result.Result != IntegrationResultIgnored || result.PR == nil || result.PR.Status != tt.status {
+				t.Errorf("expected event to be ignored, got %+v", result)
+			}
+		})
+	}
+}
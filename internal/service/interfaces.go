@@ -2,39 +2,112 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 )
 
 // PRServiceInterface определяет интерфейс для работы с Pull Requests
 type PRServiceInterface interface {
-	CreatePR(title string, authorID int) (*models.PR, error)
-	GetPR(id int) (*models.PR, error)
-	GetAllPRs() ([]models.PR, error)
-	GetPRsByUserID(userID int) ([]models.PR, error)
-	ReassignReviewer(prID int, oldReviewerID int) (*models.PR, error)
-	MergePR(id int) (*models.PR, error)
+	CreatePR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string) (*models.PR, error)
+	CreateDraftPR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string) (*models.PR, error)
+	PreviewPR(ctx context.Context, authorID int, teamName string, changedFiles []string, seed *int64) (*models.ReviewerPreview, error)
+	GetPR(ctx context.Context, id int) (*models.PR, error)
+	GetPREvents(ctx context.Context, prID int) ([]models.PREvent, error)
+	GetAllPRs(ctx context.Context) ([]models.PR, error)
+	GetPRsByUserID(ctx context.Context, userID int) ([]models.PR, error)
+	ReassignReviewer(ctx context.Context, prID int, oldReviewerID int) (*models.PR, error)
+	MergePR(ctx context.Context, id int, force bool) (*models.PR, error)
+	SubmitReview(ctx context.Context, prID int, reviewerID int, state models.ReviewState) (*models.PR, error)
+	ClosePR(ctx context.Context, id int) (*models.PR, error)
+	ReopenPR(ctx context.Context, id int) (*models.PR, error)
+	MarkReady(ctx context.Context, id int) (*models.PR, error)
 }
 
 // UserServiceInterface определяет интерфейс для работы с пользователями
 type UserServiceInterface interface {
-	CreateUser(name string, isActive bool) (*models.User, error)
-	GetUser(id int) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(id int, name *string, isActive *bool) (*models.User, error)
-	BulkDeactivateTeam(teamName string) (*dto.BulkDeactivateTeamResponse, error)
+	CreateUser(ctx context.Context, name string, isActive bool, maxOpenReviews *int) (*models.User, error)
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, id int, name *string, isActive *bool, maxOpenReviews *int) (*models.User, error)
+	BulkDeactivateTeam(ctx context.Context, teamName string) (*dto.BulkDeactivateTeamResponse, error)
+	AddUnavailability(ctx context.Context, userID int, startsAt, endsAt time.Time, reason string) (*models.Unavailability, error)
+	GetUnavailabilities(ctx context.Context, userID int) ([]models.Unavailability, error)
+	DeleteUnavailability(ctx context.Context, userID int, id int) error
+	AddIdentity(ctx context.Context, userID int, provider models.IdentityProvider, externalID string) (*models.UserIdentity, error)
+	GetIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error)
+	DeleteIdentity(ctx context.Context, userID int, id int) error
+	GetUserByIdentity(ctx context.Context, provider models.IdentityProvider, externalID string) (*models.User, error)
 }
 
 // TeamServiceInterface определяет интерфейс для работы с командами
 type TeamServiceInterface interface {
-	CreateTeam(name string) (*models.Team, error)
-	GetTeam(name string) (*models.Team, error)
-	GetAllTeams() ([]models.Team, error)
-	AddMember(teamName string, userID int) (*models.Team, error)
-	RemoveMember(teamName string, userID int) error
+	CreateTeam(ctx context.Context, name string) (*models.Team, error)
+	GetTeam(ctx context.Context, name string) (*models.Team, error)
+	GetAllTeams(ctx context.Context) ([]models.Team, error)
+	AddMember(ctx context.Context, teamName string, userID int) (*models.Team, error)
+	RemoveMember(ctx context.Context, teamName string, userID int) error
+	GetUserTeams(ctx context.Context, userID int) ([]models.TeamMembership, error)
+	SetPrimaryTeam(ctx context.Context, userID int, teamName string) ([]models.TeamMembership, error)
+	SetMemberRole(ctx context.Context, teamName string, userID int, role models.TeamRole) (*models.Team, error)
+	GetMemberRole(ctx context.Context, teamName string, userID int) (models.TeamRole, error)
+	GetSettings(ctx context.Context, teamName string) (*models.TeamSettings, error)
+	UpdateSettings(ctx context.Context, settings *models.TeamSettings) (*models.TeamSettings, error)
+}
+
+// OwnershipServiceInterface определяет интерфейс для работы с правилами владения кодом
+type OwnershipServiceInterface interface {
+	CreateRule(ctx context.Context, pattern, teamName string, userID *int) (*models.OwnershipRule, error)
+	GetRules(ctx context.Context) ([]models.OwnershipRule, error)
+	DeleteRule(ctx context.Context, id int) error
 }
 
 // StatsServiceInterface определяет интерфейс для работы со статистикой
 type StatsServiceInterface interface {
-	GetStats() (*dto.StatsResponse, error)
+	GetStats(ctx context.Context) (*dto.StatsResponse, error)
+}
+
+// WebhookServiceInterface определяет интерфейс для работы с подписками на вебхуки
+type WebhookServiceInterface interface {
+	CreateSubscription(ctx context.Context, url, secret string, events []models.WebhookEventType) (*models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	GetDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error)
+}
+
+// IntegrationServiceInterface определяет интерфейс для приема вебхуков GitHub и GitLab
+type IntegrationServiceInterface interface {
+	HandleGitHub(ctx context.Context, eventType, signature string, body []byte) (*dto.IntegrationResponse, error)
+	HandleGitLab(ctx context.Context, eventType, token string, body []byte) (*dto.IntegrationResponse, error)
+}
+
+// HealthServiceInterface определяет интерфейс для проверок liveness и readiness
+type HealthServiceInterface interface {
+	Liveness() models.HealthReport
+	Readiness(ctx context.Context) models.HealthReport
+}
+
+// AssignmentObserver получает сведения о назначении ревьюверов, например для метрик. Все команды -
+// это команды PR, кроме ReviewersReassigned, где указана команда, из которой выбраны новые ревьюверы
+type AssignmentObserver interface {
+	PRCreated(teamName string)
+	ReviewersAssigned(teamName string, count int)
+	ReviewersReassigned(teamName string, count int)
+	InsufficientReviewers(teamName string)
+}
+
+// noopObserver используется, когда получатель сведений о назначении не задан
+type noopObserver struct{}
+
+func (noopObserver) PRCreated(string)                {}
+func (noopObserver) ReviewersAssigned(string, int)   {}
+func (noopObserver) ReviewersReassigned(string, int) {}
+func (noopObserver) InsufficientReviewers(string)    {}
+
+// EventPublisher получает события, на которые можно подписаться через вебхуки
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType models.WebhookEventType, data interface{}) error
 }
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// Option настраивает сервисы, которые назначают ревьюверов (PRService и UserService)
+type Option func(*options)
+
+type options struct {
+	selector      ReviewerSelector
+	teamSelectors map[string]ReviewerSelector
+	publisher     EventPublisher
+	observer      AssignmentObserver
+	ownership     repository.OwnershipRepositoryInterface
+	seeds         func() int64
+	clock         func() time.Time
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		selector:      NewRandomSelector(),
+		teamSelectors: make(map[string]ReviewerSelector),
+		observer:      noopObserver{},
+		seeds:         rand.Int63,
+		clock:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// selectorFor возвращает стратегию выбора ревьюверов для команды
+func (o options) selectorFor(teamName string) ReviewerSelector {
+	if selector, ok := o.teamSelectors[teamName]; ok {
+		return selector
+	}
+	return o.selector
+}
+
+// nextSeed возвращает зерно для очередного решения о назначении ревьюверов.
+// Зерно записывается в лог (и в объяснение назначения): с ним решение можно воспроизвести
+func (o options) nextSeed() int64 {
+	return o.seeds()
+}
+
+// newRand создает генератор случайных чисел одного решения о назначении ревьюверов
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// now возвращает текущее время по часам сервиса
+func (o options) now() time.Time {
+	return o.clock()
+}
+
+// publish уведомляет подписчиков о событии. Ошибка публикации не отменяет уже выполненную операцию
+func (o options) publish(ctx context.Context, eventType models.WebhookEventType, data interface{}) {
+	if o.publisher == nil {
+		return
+	}
+	if err := o.publisher.Publish(ctx, eventType, data); err != nil {
+		slog.ErrorContext(ctx, "failed to publish event", "event", eventType, "error", err)
+	}
+}
+
+// observeReplacements сообщает о выполненных заменах ревьюверов по командам, из которых выбраны новые ревьюверы.
+// Замены, для которых никого не нашлось, не учитываются
+func (o options) observeReplacements(replacements []repository.ReviewerReplacement) {
+	byTeam := make(map[string]int)
+	for _, replacement := range replacements {
+		if replacement.NewReviewerID != 0 {
+			byTeam[replacement.TeamName]++
+		}
+	}
+	for teamName, count := range byTeam {
+		o.observer.ReviewersReassigned(teamName, count)
+	}
+}
+
+// publishReplacements публикует событие pr.reviewer_reassigned для каждой примененной замены ревьювера
+func (o options) publishReplacements(ctx context.Context, replacements []repository.ReviewerReplacement) {
+	for _, replacement := range replacements {
+		o.publish(ctx, models.WebhookEventReviewerReassigned, models.ReviewerReassignedEvent{
+			Reason:        replacement.Reason,
+			PRID:          replacement.PRID,
+			OldReviewerID: replacement.OldReviewerID,
+			NewReviewerID: replacement.NewReviewerID,
+		})
+	}
+}
+
+// WithEventPublisher задает получателя событий о PR и командах (например, WebhookService)
+func WithEventPublisher(publisher EventPublisher) Option {
+	return func(o *options) {
+		o.publisher = publisher
+	}
+}
+
+// WithAssignmentObserver задает получателя сведений о назначении ревьюверов (например, метрик Prometheus)
+func WithAssignmentObserver(observer AssignmentObserver) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// WithOwnershipRules включает назначение владельцев измененных файлов по правилам владения кодом
+func WithOwnershipRules(ownershipRepo repository.OwnershipRepositoryInterface) Option {
+	return func(o *options) {
+		o.ownership = ownershipRepo
+	}
+}
+
+// WithSeedSource задает источник зерен для решений о назначении ревьюверов (по умолчанию - случайные зерна).
+// Постоянное зерно делает выбор воспроизводимым, например в тестах
+func WithSeedSource(seeds func() int64) Option {
+	return func(o *options) {
+		o.seeds = seeds
+	}
+}
+
+// WithClock задает часы сервиса (по умолчанию - time.Now)
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithReviewerSelector задает стратегию выбора ревьюверов по умолчанию
+func WithReviewerSelector(selector ReviewerSelector) Option {
+	return func(o *options) {
+		o.selector = selector
+	}
+}
+
+// WithTeamReviewerSelector переопределяет стратегию выбора ревьюверов для конкретной команды
+func WithTeamReviewerSelector(teamName string, selector ReviewerSelector) Option {
+	return func(o *options) {
+		o.teamSelectors[teamName] = selector
+	}
+}
+
+// SelectorOptionsFromConfig строит опции стратегий из конфигурации.
+// defaultStrategy - название стратегии по умолчанию (пустая строка - random),
+// teamStrategies - переопределения вида "backend=least-loaded,frontend=round-robin".
+func SelectorOptionsFromConfig(defaultStrategy, teamStrategies string) ([]Option, error) {
+	var opts []Option
+
+	if defaultStrategy != "" {
+		selector, err := NewReviewerSelector(defaultStrategy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithReviewerSelector(selector))
+	}
+
+	for _, entry := range strings.Split(teamStrategies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		teamName, strategy, ok := strings.Cut(entry, "=")
+		teamName = strings.TrimSpace(teamName)
+		if !ok || teamName == "" {
+			return nil, fmt.Errorf("invalid team strategy %q: expected team=strategy", entry)
+		}
+
+		selector, err := NewReviewerSelector(strings.TrimSpace(strategy))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTeamReviewerSelector(teamName, selector))
+	}
+
+	return opts, nil
+}
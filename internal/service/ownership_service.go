@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// OwnershipService управляет правилами владения кодом, по которым на PR назначаются владельцы измененных файлов
+type OwnershipService struct {
+	ownershipRepo repository.OwnershipRepositoryInterface
+	teamRepo      repository.TeamRepositoryInterface
+	userRepo      repository.UserRepositoryInterface
+}
+
+func NewOwnershipService(ownershipRepo repository.OwnershipRepositoryInterface, teamRepo repository.TeamRepositoryInterface, userRepo repository.UserRepositoryInterface) *OwnershipService {
+	return &OwnershipService{
+		ownershipRepo: ownershipRepo,
+		teamRepo:      teamRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// CreateRule добавляет правило владения: файлы по шаблону pattern принадлежат команде teamName или пользователю userID.
+// Должен быть задан ровно один владелец. Новое правило имеет приоритет над ранее созданными
+func (s *OwnershipService) CreateRule(ctx context.Context, pattern, teamName string, userID *int) (*models.OwnershipRule, error) {
+	pattern = strings.TrimSpace(pattern)
+	if _, err := compileOwnershipPattern(pattern); err != nil {
+		return nil, err
+	}
+	if (teamName == "") == (userID == nil) {
+		return nil, ErrInvalidOwnershipRule
+	}
+
+	if teamName != "" {
+		team, err := s.teamRepo.GetByName(ctx, teamName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get team: %w", err)
+		}
+		if team == nil {
+			return nil, ErrTeamNotFound
+		}
+	} else {
+		user, err := s.userRepo.GetByID(ctx, *userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	rule := &models.OwnershipRule{
+		Pattern:  pattern,
+		TeamName: teamName,
+		UserID:   userID,
+	}
+	if err := s.ownershipRepo.Create(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create ownership rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *OwnershipService) GetRules(ctx context.Context) ([]models.OwnershipRule, error) {
+	rules, err := s.ownershipRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *OwnershipService) DeleteRule(ctx context.Context, id int) error {
+	deleted, err := s.ownershipRepo.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ownership rule: %w", err)
+	}
+	if !deleted {
+		return ErrOwnershipRuleNotFound
+	}
+	return nil
+}
+
+// ownedPaths - правило владения и измененные файлы, которые ему принадлежат
+type ownedPaths struct {
+	Rule  models.OwnershipRule
+	Paths []string
+}
+
+// matchOwners определяет владельцев измененных файлов. Как и в CODEOWNERS, файлом владеет последнее
+// подходящее правило из rules. Результат упорядочен по порядку правил
+func matchOwners(rules []models.OwnershipRule, paths []string) []ownedPaths {
+	if len(rules) == 0 || len(paths) == 0 {
+		return nil
+	}
+
+	compiled := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		// Некорректные шаблоны не сохраняются, но правило не должно ломать назначение ревьюверов
+		compiled[i], _ = compileOwnershipPattern(rule.Pattern)
+	}
+
+	byRule := make(map[int][]string)
+	for _, filePath := range paths {
+		normalized := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			if compiled[i] != nil && compiled[i].MatchString(normalized) {
+				byRule[i] = append(byRule[i], filePath)
+				break
+			}
+		}
+	}
+
+	owned := make([]ownedPaths, 0, len(byRule))
+	for i, rule := range rules {
+		if matched, ok := byRule[i]; ok {
+			owned = append(owned, ownedPaths{Rule: rule, Paths: matched})
+		}
+	}
+	return owned
+}
+
+// compileOwnershipPattern переводит шаблон CODEOWNERS в регулярное выражение для пути файла без ведущего "/".
+// Шаблон, начинающийся с "/" или содержащий "/" в середине, привязан к корню репозитория; иначе он совпадает
+// с именем файла или каталога на любой глубине. Шаблон совпадает и со всем содержимым подходящего каталога
+func compileOwnershipPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" || strings.HasPrefix(pattern, "!") || strings.HasPrefix(pattern, "#") || strings.ContainsAny(pattern, " \t") {
+		return nil, ErrInvalidOwnershipRule
+	}
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+	if trimmed == "" {
+		return nil, ErrInvalidOwnershipRule
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case trimmed[i] == '*':
+			expr.WriteString("[^/]*")
+		case trimmed[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(trimmed[i : i+1]))
+		}
+	}
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOwnershipRule, err)
+	}
+	return re, nil
+}
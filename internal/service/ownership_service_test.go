@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+type mockOwnershipRepository struct {
+	rules []models.OwnershipRule
+}
+
+func (m *mockOwnershipRepository) Create(_ context.Context, rule *models.OwnershipRule) error {
+	rule.ID = len(m.rules) + 1
+	m.rules = append(m.rules, *rule)
+	return nil
+}
+
+func (m *mockOwnershipRepository) GetAll(_ context.Context) ([]models.OwnershipRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockOwnershipRepository) Delete(_ context.Context, id int) (bool, error) {
+	for i, rule := range m.rules {
+		if rule.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCompileOwnershipPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.go", path: "main.go", want: true},
+		{pattern: "*.go", path: "internal/service/pr_service.go", want: true},
+		{pattern: "*.go", path: "README.md", want: false},
+		{pattern: "docs/", path: "docs/api/index.md", want: true},
+		{pattern: "docs/", path: "docs", want: false},
+		{pattern: "/internal/service/", path: "internal/service/pr_service.go", want: true},
+		{pattern: "/internal/service/", path: "pkg/internal/service/x.go", want: false},
+		{pattern: "internal/*.go", path: "internal/main.go", want: true},
+		{pattern: "internal/*.go", path: "internal/service/main.go", want: false},
+		{pattern: "internal/**/*.go", path: "internal/service/main.go", want: true},
+		{pattern: "internal/**/*.go", path: "internal/main.go", want: true},
+		{pattern: "migrations", path: "migrations/000001_init.up.sql", want: true},
+		{pattern: "migrations", path: "db/migrations/000001_init.up.sql", want: true},
+		{pattern: "*", path: "any/file.txt", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			re, err := compileOwnershipPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("expected pattern to compile, got %v", err)
+			}
+			if got := re.MatchString(tt.path); got != tt.want {
+				t.Errorf("expected match %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchOwners_LastRuleWins(t *testing.T) {
+	userID := 7
+	rules := []models.OwnershipRule{
+		{ID: 1, Pattern: "*", TeamName: "backend"},
+		{ID: 2, Pattern: "docs/", UserID: &userID},
+	}
+
+	owned := matchOwners(rules, []string{"docs/readme.md", "cmd/server/main.go", "/docs/guide.md"})
+
+	if len(owned) != 2 {
+		t.Fatalf("expected 2 owning rules, got %+v", owned)
+	}
+	if owned[0].Rule.ID != 1 || len(owned[0].Paths) != 1 || owned[0].Paths[0] != "cmd/server/main.go" {
+		t.Errorf("expected backend to own only cmd/server/main.go, got %+v", owned[0])
+	}
+	if owned[1].Rule.ID != 2 || len(owned[1].Paths) != 2 {
+		t.Errorf("expected user 7 to own both docs files, got %+v", owned[1])
+	}
+}
+
+func TestCreateOwnershipRule_Invalid(t *testing.T) {
+	userID := 1
+	tests := []struct {
+		name     string
+		pattern  string
+		teamName string
+		userID   *int
+	}{
+		{name: "no owner", pattern: "*.go"},
+		{name: "both owners", pattern: "*.go", teamName: "backend", userID: &userID},
+		{name: "negation", pattern: "!*.go", teamName: "backend"},
+		{name: "whitespace", pattern: "docs/ api", teamName: "backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewOwnershipService(&mockOwnershipRepository{}, &mockTeamRepository{}, &mockUserRepository{})
+			_, err := service.CreateRule(context.Background(), tt.pattern, tt.teamName, tt.userID)
+
+			if !errors.Is(err, ErrInvalidOwnershipRule) {
+				t.Errorf("expected ErrInvalidOwnershipRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateOwnershipRule_TeamNotFound(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return nil, nil
+		},
+	}
+
+	service := NewOwnershipService(&mockOwnershipRepository{}, mockTeam, &mockUserRepository{})
+	_, err := service.CreateRule(context.Background(), "docs/", "writers", nil)
+
+	if !errors.Is(err, ErrTeamNotFound) {
+		t.Errorf("expected ErrTeamNotFound, got %v", err)
+	}
+}
+
+func TestDeleteOwnershipRule_NotFound(t *testing.T) {
+	service := NewOwnershipService(&mockOwnershipRepository{}, &mockTeamRepository{}, &mockUserRepository{})
+
+	if err := service.DeleteRule(context.Background(), 42); !errors.Is(err, ErrOwnershipRuleNotFound) {
+		t.Errorf("expected ErrOwnershipRuleNotFound, got %v", err)
+	}
+}
+
+func TestCreatePR_AssignsCodeOwnersFirst(t *testing.T) {
+	ownerID := 9
+	ownership := &mockOwnershipRepository{rules: []models.OwnershipRule{
+		{ID: 1, Pattern: "migrations/", TeamName: "dba"},
+		{ID: 2, Pattern: "*.md", UserID: &ownerID},
+	}}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "User", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			if teamName == "dba" {
+				return []models.User{{ID: 5, IsActive: true}}, nil
+			}
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			return &models.TeamSettings{TeamName: teamName, RequiredReviewers: 3, MinReviewers: 1}, nil
+		},
+	}
+
+	service := NewPRService(&mockPRRepository{}, mockUser, mockTeam, WithOwnershipRules(ownership))
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", []string{"migrations/000015.up.sql", "README.md"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pr.Assignments) != 3 {
+		t.Fatalf("expected 3 assignments, got %+v", pr.Assignments)
+	}
+
+	dba, owner, team := pr.Assignments[0], pr.Assignments[1], pr.Assignments[2]
+	if dba.ReviewerID != 5 || dba.Source != models.AssignmentSourceOwnership || dba.RuleID == nil || *dba.RuleID != 1 {
+		t.Errorf("expected reviewer 5 from the dba ownership rule, got %+v", dba)
+	}
+	if owner.ReviewerID != 9 || owner.Source != models.AssignmentSourceOwnership || len(owner.Paths) != 1 || owner.Paths[0] != "README.md" {
+		t.Errorf("expected user 9 as owner of README.md, got %+v", owner)
+	}
+	if team.Source != models.AssignmentSourceTeam || team.TeamName != "team1" {
+		t.Errorf("expected the remaining slot to be filled from team1, got %+v", team)
+	}
+	if pr.Reviews[0].PoolTeam != "dba" || pr.Reviews[1].PoolTeam != "" {
+		t.Errorf("expected dba reviewer to be replaced within dba, got %+v", pr.Reviews)
+	}
+}
@@ -1,9 +1,10 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"time"
+	"log/slog"
 
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
@@ -13,85 +14,188 @@ type PRService struct {
 	prRepo   repository.PRRepositoryInterface
 	userRepo repository.UserRepositoryInterface
 	teamRepo repository.TeamRepositoryInterface
+	options  options
 }
 
-func NewPRService(prRepo repository.PRRepositoryInterface, userRepo repository.UserRepositoryInterface, teamRepo repository.TeamRepositoryInterface) *PRService {
+func NewPRService(prRepo repository.PRRepositoryInterface, userRepo repository.UserRepositoryInterface, teamRepo repository.TeamRepositoryInterface, opts ...Option) *PRService {
 	return &PRService{
 		prRepo:   prRepo,
 		userRepo: userRepo,
 		teamRepo: teamRepo,
+		options:  newOptions(opts),
 	}
 }
 
-func (s *PRService) CreatePR(title string, authorID int) (*models.PR, error) {
-	author, err := s.userRepo.GetByID(authorID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get author: %w", err)
-	}
-	if author == nil {
-		return nil, ErrAuthorNotFound
+// CreatePR создает PR и назначает ревьюверов из команды teamName, в которой должен состоять автор.
+// Если teamName пустая, используется основная команда автора. Владельцы changedFiles назначаются в первую очередь
+func (s *PRService) CreatePR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.CreatePR")
+	defer func() { endSpan(span, err) }()
+
+	return s.createPR(ctx, title, authorID, teamName, changedFiles, nil)
+}
+
+// CreateDraftPR создает черновик PR без ревьюверов. Ревьюверы назначаются, когда черновик переводится в OPEN
+func (s *PRService) CreateDraftPR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.CreateDraftPR")
+	defer func() { endSpan(span, err) }()
+
+	return s.createDraftPR(ctx, title, authorID, teamName, changedFiles, nil)
+}
+
+// CreateExternalPR создает PR (или черновик) для pull request'а из GitHub/GitLab и сохраняет ссылку на него
+func (s *PRService) CreateExternalPR(ctx context.Context, title string, authorID int, ref models.ExternalRef, draft bool) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.CreateExternalPR")
+	defer func() { endSpan(span, err) }()
+
+	if draft {
+		return s.createDraftPR(ctx, title, authorID, "", nil, &ref)
 	}
+	return s.createPR(ctx, title, authorID, "", nil, &ref)
+}
 
-	teamName, err := s.teamRepo.GetUserTeam(authorID)
+func (s *PRService) createPR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string, external *models.ExternalRef) (*models.PR, error) {
+	teamName, err := s.authorTeam(ctx, authorID, teamName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user team: %w", err)
+		return nil, err
 	}
-	if teamName == "" {
-		return nil, ErrAuthorNotInTeam
+
+	pick := newReviewerPick(s.options.nextSeed(), false)
+	if err := s.pickReviewers(ctx, pick, teamName, authorID, changedFiles, nil); err != nil {
+		s.observePickError(teamName, err)
+		return nil, err
+	}
+
+	pr := &models.PR{
+		External:     external,
+		Title:        title,
+		TeamName:     teamName,
+		AuthorID:     authorID,
+		Status:       models.PRStatusOpen,
+		ChangedFiles: changedFiles,
+		Reviewers:    models.ReviewerIDs(pick.Reviews),
+		Reviews:      pick.Reviews,
+	}
+	pr.SetExplanation(pick.explanation())
+
+	if err := s.prRepo.Create(ctx, pr); err != nil {
+		return nil, fmt.Errorf("failed to create PR: %w", err)
 	}
+	slog.InfoContext(ctx, "assigned reviewers", "pr_id", pr.ID, "reviewers", pr.Reviewers, "seed", pick.seed)
+	s.options.observer.PRCreated(teamName)
+	s.options.observer.ReviewersAssigned(teamName, len(pr.Reviewers))
+	s.options.publish(ctx, models.WebhookEventPRCreated, pr)
 
-	candidates, err := s.userRepo.GetActiveUsersByTeam(teamName, authorID)
+	return pr, nil
+}
+
+// PreviewPR подбирает ревьюверов так же, как CreatePR, но ничего не сохраняет: ни PR, ни очередь стратегии
+// round-robin. Возвращает выбранных ревьюверов, кандидатов с нагрузкой и причины исключения остальных участников.
+// С заданным seed выбор повторяет назначение, сделанное с тем же зерном при тех же кандидатах
+func (s *PRService) PreviewPR(ctx context.Context, authorID int, teamName string, changedFiles []string, seed *int64) (_ *models.ReviewerPreview, err error) {
+	ctx, span := startSpan(ctx, "PRService.PreviewPR")
+	defer func() { endSpan(span, err) }()
+
+	teamName, err = s.authorTeam(ctx, authorID, teamName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get team members: %w", err)
+		return nil, err
 	}
 
-	if len(candidates) < 2 {
-		return nil, ErrInsufficientReviewers
+	pick := newReviewerPick(s.options.nextSeed(), true)
+	if seed != nil {
+		pick = newReviewerPick(*seed, true)
 	}
+	if err := s.pickReviewers(ctx, pick, teamName, authorID, changedFiles, nil); err != nil {
+		return nil, err
+	}
+
+	return &models.ReviewerPreview{
+		TeamName:              teamName,
+		Reviewers:             models.ReviewerIDs(pick.Reviews),
+		Reviews:               pick.Reviews,
+		AssignmentExplanation: pick.explanation(),
+	}, nil
+}
 
-	reviewers := s.selectRandomReviewers(candidates, 2)
+func (s *PRService) createDraftPR(ctx context.Context, title string, authorID int, teamName string, changedFiles []string, external *models.ExternalRef) (*models.PR, error) {
+	teamName, err := s.authorTeam(ctx, authorID, teamName)
+	if err != nil {
+		return nil, err
+	}
 
 	pr := &models.PR{
-		Title:     title,
-		AuthorID:  authorID,
-		Status:    models.PRStatusOpen,
-		Reviewers: reviewers,
+		External:     external,
+		Title:        title,
+		TeamName:     teamName,
+		AuthorID:     authorID,
+		Status:       models.PRStatusDraft,
+		ChangedFiles: changedFiles,
+		Reviewers:    []int{},
+		Reviews:      []models.Review{},
 	}
 
-	if err := s.prRepo.Create(pr); err != nil {
+	if err := s.prRepo.Create(ctx, pr); err != nil {
 		return nil, fmt.Errorf("failed to create PR: %w", err)
 	}
+	s.options.observer.PRCreated(teamName)
+	s.options.publish(ctx, models.WebhookEventPRCreated, pr)
 
 	return pr, nil
 }
 
-func (s *PRService) selectRandomReviewers(candidates []models.User, maxCount int) []int {
-	if len(candidates) == 0 {
-		return []int{}
+// authorTeam проверяет, что автор существует, и возвращает команду PR: teamName, если автор в ней состоит,
+// или основную команду автора, если teamName пустая
+func (s *PRService) authorTeam(ctx context.Context, authorID int, teamName string) (string, error) {
+	author, err := s.userRepo.GetByID(ctx, authorID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get author: %w", err)
+	}
+	if author == nil {
+		return "", ErrAuthorNotFound
 	}
 
-	count := maxCount
-	if len(candidates) < maxCount {
-		count = len(candidates)
+	if teamName == "" {
+		teamName, err = s.teamRepo.GetUserTeam(ctx, authorID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get user team: %w", err)
+		}
+		if teamName == "" {
+			return "", ErrAuthorNotInTeam
+		}
+		return teamName, nil
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	shuffled := make([]models.User, len(candidates))
-	copy(shuffled, candidates)
-	r.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
+	memberships, err := s.teamRepo.GetUserTeams(ctx, authorID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user teams: %w", err)
+	}
+	for _, membership := range memberships {
+		if membership.TeamName == teamName {
+			return teamName, nil
+		}
+	}
+	return "", ErrAuthorNotTeamMember
+}
 
-	reviewers := make([]int, 0, count)
-	for i := 0; i < count; i++ {
-		reviewers = append(reviewers, shuffled[i].ID)
+// prTeam возвращает команду, из которой назначаются ревьюверы PR.
+// Для PR, созданных до сохранения команды в PR, это основная команда userID
+func (s *PRService) prTeam(ctx context.Context, pr *models.PR, userID int) (string, error) {
+	if pr.TeamName != "" {
+		return pr.TeamName, nil
 	}
 
-	return reviewers
+	teamName, err := s.teamRepo.GetUserTeam(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user team: %w", err)
+	}
+	return teamName, nil
 }
 
-func (s *PRService) GetPR(id int) (*models.PR, error) {
-	pr, err := s.prRepo.GetByID(id)
+func (s *PRService) GetPR(ctx context.Context, id int) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.GetPR")
+	defer func() { endSpan(span, err) }()
+
+	pr, err := s.prRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PR: %w", err)
 	}
@@ -101,24 +205,51 @@ func (s *PRService) GetPR(id int) (*models.PR, error) {
 	return pr, nil
 }
 
-func (s *PRService) GetPRsByUserID(userID int) ([]models.PR, error) {
-	prs, err := s.prRepo.GetByUserID(userID)
+// GetPREvents возвращает историю изменений PR
+func (s *PRService) GetPREvents(ctx context.Context, prID int) (_ []models.PREvent, err error) {
+	ctx, span := startSpan(ctx, "PRService.GetPREvents")
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetPR(ctx, prID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.prRepo.GetEvents(ctx, prID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR events: %w", err)
+	}
+	return events, nil
+}
+
+func (s *PRService) GetPRsByUserID(ctx context.Context, userID int) (_ []models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.GetPRsByUserID")
+	defer func() { endSpan(span, err) }()
+
+	prs, err := s.prRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PRs: %w", err)
 	}
 	return prs, nil
 }
 
-func (s *PRService) GetAllPRs() ([]models.PR, error) {
-	prs, err := s.prRepo.GetAll()
+func (s *PRService) GetAllPRs(ctx context.Context) (_ []models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.GetAllPRs")
+	defer func() { endSpan(span, err) }()
+
+	prs, err := s.prRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PRs: %w", err)
 	}
 	return prs, nil
 }
 
-func (s *PRService) MergePR(id int) (*models.PR, error) {
-	pr, err := s.prRepo.GetByID(id)
+// MergePR мержит PR, если он набрал требуемое командой количество одобрений и никто из ревьюверов
+// не запросил изменения. С force проверка пропускается, а PR помечается как смерженный принудительно
+func (s *PRService) MergePR(ctx context.Context, id int, force bool) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.MergePR")
+	defer func() { endSpan(span, err) }()
+
+	pr, err := s.prRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PR: %w", err)
 	}
@@ -129,17 +260,95 @@ func (s *PRService) MergePR(id int) (*models.PR, error) {
 	if pr.Status == models.PRStatusMerged {
 		return pr, nil
 	}
+	if !pr.Status.CanTransitionTo(models.PRStatusMerged) {
+		return nil, invalidTransition(pr.Status, models.PRStatusMerged)
+	}
+
+	blocked, requiredApprovals, err := s.checkMergeRequirements(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if blocked != nil && !force {
+		return nil, blocked
+	}
+	forced := blocked != nil
 
-	if err := s.prRepo.UpdateStatus(id, models.PRStatusMerged); err != nil {
+	// Репозиторий повторяет проверку под блокировкой PR: ревью могли измениться после проверки выше
+	err = s.prRepo.Merge(ctx, id, forced, requiredApprovals, s.options.now(), actorID(ctx))
+	if errors.Is(err, ErrMergeBlocked) && force {
+		forced = true
+		err = s.prRepo.Merge(ctx, id, forced, requiredApprovals, s.options.now(), actorID(ctx))
+	}
+	if errors.Is(err, ErrMergeBlocked) {
+		return nil, s.recheckMergeRequirements(ctx, id)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to merge PR: %w", err)
 	}
 
 	pr.Status = models.PRStatusMerged
+	pr.ForceMerged = forced
+	s.options.publish(ctx, models.WebhookEventPRMerged, pr)
 	return pr, nil
 }
 
-func (s *PRService) ReassignReviewer(prID int, oldReviewerID int) (*models.PR, error) {
-	pr, err := s.prRepo.GetByID(prID)
+// checkMergeRequirements возвращает MergeBlockedError, если PR не удовлетворяет требованиям команды автора к мержу,
+// и требуемое командой количество одобрений
+func (s *PRService) checkMergeRequirements(ctx context.Context, pr *models.PR) (*MergeBlockedError, int, error) {
+	teamName, err := s.prTeam(ctx, pr, pr.AuthorID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	settings := models.DefaultTeamSettings(teamName)
+	if teamName != "" {
+		settings, err = loadTeamSettings(ctx, s.teamRepo, teamName)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	result := &MergeBlockedError{
+		PendingReviewers:   []int{},
+		ChangesRequestedBy: []int{},
+		RequiredApprovals:  settings.RequiredApprovals,
+	}
+	for _, reviewerID := range pr.Reviewers {
+		switch pr.ReviewState(reviewerID) {
+		case models.ReviewStateApproved:
+			result.Approvals++
+		case models.ReviewStateChangesRequested:
+			result.ChangesRequestedBy = append(result.ChangesRequestedBy, reviewerID)
+		default:
+			result.PendingReviewers = append(result.PendingReviewers, reviewerID)
+		}
+	}
+
+	if result.MissingApprovals() == 0 && len(result.ChangesRequestedBy) == 0 {
+		return nil, settings.RequiredApprovals, nil
+	}
+	return result, settings.RequiredApprovals, nil
+}
+
+// recheckMergeRequirements перечитывает PR, мерж которого отклонил репозиторий, и возвращает MergeBlockedError
+// с актуальным списком недостающего. Если PR прочитать не удалось, возвращается ErrMergeBlocked без подробностей
+func (s *PRService) recheckMergeRequirements(ctx context.Context, id int) error {
+	pr, err := s.prRepo.GetByID(ctx, id)
+	if err != nil || pr == nil {
+		return ErrMergeBlocked
+	}
+	blocked, _, err := s.checkMergeRequirements(ctx, pr)
+	if err != nil || blocked == nil {
+		return ErrMergeBlocked
+	}
+	return blocked
+}
+
+func (s *PRService) ReassignReviewer(ctx context.Context, prID int, oldReviewerID int) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.ReassignReviewer")
+	defer func() { endSpan(span, err) }()
+
+	pr, err := s.prRepo.GetByID(ctx, prID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PR: %w", err)
 	}
@@ -150,6 +359,9 @@ func (s *PRService) ReassignReviewer(prID int, oldReviewerID int) (*models.PR, e
 	if pr.Status == models.PRStatusMerged {
 		return nil, ErrPRAlreadyMerged
 	}
+	if pr.Status != models.PRStatusOpen {
+		return nil, ErrPRNotOpen
+	}
 
 	found := false
 	for _, reviewerID := range pr.Reviewers {
@@ -161,16 +373,23 @@ func (s *PRService) ReassignReviewer(prID int, oldReviewerID int) (*models.PR, e
 	if !found {
 		return nil, ErrReviewerNotAssigned
 	}
+	if pr.ReviewState(oldReviewerID) == models.ReviewStateApproved {
+		return nil, ErrReviewerApproved
+	}
 
-	teamName, err := s.teamRepo.GetUserTeam(oldReviewerID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get reviewer team: %w", err)
+	// Замена выбирается из того же пула, что и снимаемый ревьювер
+	teamName := pr.PoolTeam(oldReviewerID)
+	if teamName == "" {
+		teamName, err = s.prTeam(ctx, pr, oldReviewerID)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if teamName == "" {
 		return nil, ErrReviewerNotInTeam
 	}
 
-	candidates, err := s.userRepo.GetActiveUsersByTeam(teamName, oldReviewerID)
+	candidates, err := s.userRepo.GetActiveUsersByTeam(ctx, teamName, oldReviewerID, s.options.now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team members: %w", err)
 	}
@@ -195,16 +414,208 @@ func (s *PRService) ReassignReviewer(prID int, oldReviewerID int) (*models.PR, e
 		return nil, ErrNoAvailableReviewers
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	newReviewerID := filteredCandidates[r.Intn(len(filteredCandidates))].ID
+	reviewerCandidates, err := loadReviewerCandidates(ctx, s.prRepo, filteredCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	available := withinCapacity(reviewerCandidates)
+	if len(available) == 0 {
+		return nil, ErrReviewersAtCapacity
+	}
+
+	seed := s.options.nextSeed()
+	// Пользовательская стратегия может никого не выбрать - это не должно приводить к панике
+	picked := s.options.selectorFor(teamName).Select(teamName, available, 1, newRand(seed))
+	if len(picked) == 0 {
+		return nil, ErrInsufficientReviewers
+	}
+	newReviewerID := picked[0]
 
-	if err := s.prRepo.ReassignReviewer(prID, oldReviewerID, newReviewerID); err != nil {
+	if err := s.prRepo.ReassignReviewer(ctx, prID, oldReviewerID, newReviewerID, actorID(ctx)); err != nil {
+		// Репозиторий повторяет проверки под блокировкой PR: за время выбора замены PR могли закрыть,
+		// а ревьювера - снять или получить от него одобрение
+		if errors.Is(err, ErrPRNotOpen) || errors.Is(err, ErrReviewerNotAssigned) || errors.Is(err, ErrReviewerApproved) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to reassign reviewer: %w", err)
 	}
-	updatedPR, err := s.prRepo.GetByID(prID)
+	slog.InfoContext(ctx, "reassigned reviewer", "pr_id", prID, "old_reviewer_id", oldReviewerID, "new_reviewer_id", newReviewerID, "seed", seed)
+	s.options.observer.ReviewersReassigned(teamName, 1)
+	s.options.publish(ctx, models.WebhookEventReviewerReassigned, models.ReviewerReassignedEvent{
+		Reason:        models.ReassignReasonManual,
+		PRID:          prID,
+		OldReviewerID: oldReviewerID,
+		NewReviewerID: newReviewerID,
+	})
+	updatedPR, err := s.prRepo.GetByID(ctx, prID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated PR: %w", err)
 	}
 
 	return updatedPR, nil
 }
+
+// SubmitReview сохраняет вердикт назначенного ревьювера по открытому PR
+func (s *PRService) SubmitReview(ctx context.Context, prID int, reviewerID int, state models.ReviewState) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.SubmitReview")
+	defer func() { endSpan(span, err) }()
+
+	if state != models.ReviewStateApproved && state != models.ReviewStateChangesRequested {
+		return nil, ErrInvalidReviewState
+	}
+
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR: %w", err)
+	}
+	if pr == nil {
+		return nil, ErrPRNotFound
+	}
+
+	if pr.Status == models.PRStatusMerged {
+		return nil, ErrReviewOnMergedPR
+	}
+	if pr.Status != models.PRStatusOpen {
+		return nil, ErrPRNotOpen
+	}
+
+	assigned := false
+	for _, id := range pr.Reviewers {
+		if id == reviewerID {
+			assigned = true
+			break
+		}
+	}
+	if !assigned {
+		return nil, ErrNotPRReviewer
+	}
+
+	if err := s.prRepo.SetReviewState(ctx, prID, reviewerID, state, s.options.now()); err != nil {
+		if errors.Is(err, ErrPRNotOpen) || errors.Is(err, ErrNotPRReviewer) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to submit review: %w", err)
+	}
+
+	updatedPR, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated PR: %w", err)
+	}
+
+	return updatedPR, nil
+}
+
+// ClosePR закрывает PR без мержа. Ревьюверы остаются назначенными, но не учитываются в нагрузке
+func (s *PRService) ClosePR(ctx context.Context, id int) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.ClosePR")
+	defer func() { endSpan(span, err) }()
+
+	pr, err := s.getPRForTransition(ctx, id, models.PRStatusClosed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.prRepo.UpdateStatus(ctx, id, models.PRStatusClosed, s.options.now(), actorID(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to close PR: %w", err)
+	}
+
+	pr.Status = models.PRStatusClosed
+	return pr, nil
+}
+
+// ReopenPR снова открывает закрытый PR. Ревьюверы, ставшие недоступными за время, пока PR был закрыт, заменяются
+func (s *PRService) ReopenPR(ctx context.Context, id int) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.ReopenPR")
+	defer func() { endSpan(span, err) }()
+
+	return s.openPR(ctx, id, models.PRStatusClosed)
+}
+
+// MarkReady переводит черновик в OPEN и назначает ревьюверов
+func (s *PRService) MarkReady(ctx context.Context, id int) (_ *models.PR, err error) {
+	ctx, span := startSpan(ctx, "PRService.MarkReady")
+	defer func() { endSpan(span, err) }()
+
+	return s.openPR(ctx, id, models.PRStatusDraft)
+}
+
+// openPR переводит PR из статуса from в OPEN, проверяя текущих ревьюверов и добирая недостающих
+func (s *PRService) openPR(ctx context.Context, id int, from models.PRStatus) (*models.PR, error) {
+	pr, err := s.getPRForTransition(ctx, id, models.PRStatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status != from {
+		return nil, invalidTransition(pr.Status, models.PRStatusOpen)
+	}
+
+	teamName := pr.TeamName
+	if teamName == "" {
+		teamName, err = s.authorTeam(ctx, pr.AuthorID, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pick := newReviewerPick(s.options.nextSeed(), false)
+	if err := s.pickReviewers(ctx, pick, teamName, pr.AuthorID, pr.ChangedFiles, pr.Reviews); err != nil {
+		s.observePickError(teamName, err)
+		return nil, err
+	}
+
+	if err := s.prRepo.UpdateStatusWithReviewers(ctx, id, models.PRStatusOpen, pick.Reviews, pick.explanation(), actorID(ctx)); err != nil {
+		return nil, fmt.Errorf("failed to open PR: %w", err)
+	}
+	slog.InfoContext(ctx, "assigned reviewers", "pr_id", id, "reviewers", models.ReviewerIDs(pick.Reviews), "seed", pick.seed)
+	s.options.observer.ReviewersAssigned(teamName, newReviewers(pr.Reviews, pick.Reviews))
+
+	updatedPR, err := s.prRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated PR: %w", err)
+	}
+
+	return updatedPR, nil
+}
+
+// newReviewers возвращает число ревьюверов из picked, которых нет среди current
+func newReviewers(current, picked []models.Review) int {
+	kept := make(map[int]struct{}, len(current))
+	for _, review := range current {
+		kept[review.ReviewerID] = struct{}{}
+	}
+	count := 0
+	for _, review := range picked {
+		if _, ok := kept[review.ReviewerID]; !ok {
+			count++
+		}
+	}
+	return count
+}
+
+// observePickError учитывает PR, которые не удалось открыть из-за нехватки ревьюверов
+func (s *PRService) observePickError(teamName string, err error) {
+	if errors.Is(err, ErrInsufficientReviewers) {
+		s.options.observer.InsufficientReviewers(teamName)
+	}
+}
+
+// getPRForTransition возвращает PR, если его можно перевести в статус to
+func (s *PRService) getPRForTransition(ctx context.Context, id int, to models.PRStatus) (*models.PR, error) {
+	pr, err := s.prRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR: %w", err)
+	}
+	if pr == nil {
+		return nil, ErrPRNotFound
+	}
+
+	if !pr.Status.CanTransitionTo(to) {
+		return nil, invalidTransition(pr.Status, to)
+	}
+	return pr, nil
+}
+
+func invalidTransition(from, to models.PRStatus) error {
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+}
@@ -1,137 +1,316 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/internal/tracing/tracingtest"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type mockPRRepository struct {
 	createFunc                  func(*models.PR) error
 	getByIDFunc                 func(int) (*models.PR, error)
+	getByIDsFunc                func([]int) ([]models.PR, error)
+	getByExternalRefFunc        func(models.ExternalRef) (*models.PR, error)
 	getByUserIDFunc             func(int) ([]models.PR, error)
 	getAllFunc                  func() ([]models.PR, error)
 	updateStatusFunc            func(int, models.PRStatus) error
+	mergeFunc                   func(int, bool) error
+	updateStatusReviewersFunc   func(int, models.PRStatus, []models.Review) error
+	getEventsFunc               func(int) ([]models.PREvent, error)
 	reassignReviewerFunc        func(int, int, int) error
+	setReviewStateFunc          func(int, int, models.ReviewState, time.Time) error
 	getOpenPRsWithReviewersFunc func([]int) (map[int][]int, error)
-	bulkReassignReviewersFunc   func(map[int][]int, string, []int) (int, error)
+	getOpenReviewCountsFunc     func([]int) (map[int]int, error)
+	bulkReassignReviewersFunc   func([]repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error)
 }
 
-func (m *mockPRRepository) Create(pr *models.PR) error {
+func (m *mockPRRepository) Create(_ context.Context, pr *models.PR) error {
 	if m.createFunc != nil {
 		return m.createFunc(pr)
 	}
 	return nil
 }
 
-func (m *mockPRRepository) GetByID(id int) (*models.PR, error) {
+func (m *mockPRRepository) GetByID(_ context.Context, id int) (*models.PR, error) {
 	if m.getByIDFunc != nil {
 		return m.getByIDFunc(id)
 	}
 	return nil, nil
 }
 
-func (m *mockPRRepository) GetByUserID(userID int) ([]models.PR, error) {
+func (m *mockPRRepository) GetByIDs(_ context.Context, ids []int) ([]models.PR, error) {
+	if m.getByIDsFunc != nil {
+		return m.getByIDsFunc(ids)
+	}
+	return nil, nil
+}
+
+func (m *mockPRRepository) GetByUserID(_ context.Context, userID int) ([]models.PR, error) {
 	if m.getByUserIDFunc != nil {
 		return m.getByUserIDFunc(userID)
 	}
 	return nil, nil
 }
 
-func (m *mockPRRepository) GetAll() ([]models.PR, error) {
+func (m *mockPRRepository) GetAll(_ context.Context) ([]models.PR, error) {
 	if m.getAllFunc != nil {
 		return m.getAllFunc()
 	}
 	return nil, nil
 }
 
-func (m *mockPRRepository) UpdateStatus(id int, status models.PRStatus) error {
+func (m *mockPRRepository) UpdateStatus(_ context.Context, id int, status models.PRStatus, _ time.Time, _ *int) error {
 	if m.updateStatusFunc != nil {
 		return m.updateStatusFunc(id, status)
 	}
 	return nil
 }
 
-func (m *mockPRRepository) ReassignReviewer(prID, oldReviewerID, newReviewerID int) error {
+func (m *mockPRRepository) UpdateStatusWithReviewers(_ context.Context, id int, status models.PRStatus, reviewers []models.Review, explanation models.AssignmentExplanation, _ *int) error {
+	if m.updateStatusReviewersFunc != nil {
+		return m.updateStatusReviewersFunc(id, status, reviewers)
+	}
+	return nil
+}
+
+func (m *mockPRRepository) GetByExternalRef(_ context.Context, ref models.ExternalRef) (*models.PR, error) {
+	if m.getByExternalRefFunc != nil {
+		return m.getByExternalRefFunc(ref)
+	}
+	return nil, nil
+}
+
+func (m *mockPRRepository) GetEvents(_ context.Context, prID int) ([]models.PREvent, error) {
+	if m.getEventsFunc != nil {
+		return m.getEventsFunc(prID)
+	}
+	return []models.PREvent{}, nil
+}
+
+func (m *mockPRRepository) Merge(_ context.Context, id int, forced bool, _ int, _ time.Time, _ *int) error {
+	if m.mergeFunc != nil {
+		return m.mergeFunc(id, forced)
+	}
+	return nil
+}
+
+func (m *mockPRRepository) ReassignReviewer(_ context.Context, prID, oldReviewerID, newReviewerID int, _ *int) error {
 	if m.reassignReviewerFunc != nil {
 		return m.reassignReviewerFunc(prID, oldReviewerID, newReviewerID)
 	}
 	return nil
 }
 
-func (m *mockPRRepository) GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, error) {
+func (m *mockPRRepository) SetReviewState(_ context.Context, prID, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+	if m.setReviewStateFunc != nil {
+		return m.setReviewStateFunc(prID, reviewerID, state, reviewedAt)
+	}
+	return nil
+}
+
+func (m *mockPRRepository) GetOpenPRsWithReviewers(_ context.Context, userIDs []int) (map[int][]int, error) {
 	if m.getOpenPRsWithReviewersFunc != nil {
 		return m.getOpenPRsWithReviewersFunc(userIDs)
 	}
 	return nil, nil
 }
 
-func (m *mockPRRepository) BulkReassignReviewers(prReviewerMap map[int][]int, teamName string, excludeUserIDs []int) (int, error) {
+func (m *mockPRRepository) GetOpenReviewCounts(_ context.Context, userIDs []int) (map[int]int, error) {
+	if m.getOpenReviewCountsFunc != nil {
+		return m.getOpenReviewCountsFunc(userIDs)
+	}
+	return map[int]int{}, nil
+}
+
+func (m *mockPRRepository) BulkReassignReviewers(_ context.Context, replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
 	if m.bulkReassignReviewersFunc != nil {
-		return m.bulkReassignReviewersFunc(prReviewerMap, teamName, excludeUserIDs)
+		return m.bulkReassignReviewersFunc(replacements)
 	}
-	return 0, nil
+	return []repository.ReviewerReplacement{}, nil
 }
 
-func (m *mockPRRepository) GetStats() (map[string]int, error) {
+func (m *mockPRRepository) GetStats(_ context.Context) (map[string]int, error) {
 	return map[string]int{}, nil
 }
 
 type mockUserRepository struct {
-	getByIDFunc              func(int) (*models.User, error)
-	bulkDeactivateByTeamFunc func(string) (int, error)
-	getActiveUsersByTeamFunc func(string, int) ([]models.User, error)
+	getByIDFunc                      func(int) (*models.User, error)
+	bulkDeactivateByTeamFunc         func(string) (int, error)
+	getActiveUsersByTeamFunc         func(string, int) ([]models.User, error)
+	createUnavailabilityFunc         func(*models.Unavailability) error
+	deleteUnavailabilityFunc         func(int, int) (bool, error)
+	getStartedUnavailabilitiesFunc   func(time.Time) ([]models.Unavailability, error)
+	markUnavailabilityReassignedFunc func(int, time.Time) error
+	getByIdentityFunc                func(models.IdentityProvider, string) (*models.User, error)
+	createIdentityFunc               func(*models.UserIdentity) error
+	deleteIdentityFunc               func(int, int) (bool, error)
 }
 
-func (m *mockUserRepository) GetByID(id int) (*models.User, error) {
+func (m *mockUserRepository) GetByID(_ context.Context, id int) (*models.User, error) {
 	if m.getByIDFunc != nil {
 		return m.getByIDFunc(id)
 	}
 	return nil, nil
 }
 
-func (m *mockUserRepository) Create(user *models.User) error { return nil }
-func (m *mockUserRepository) GetAll() ([]models.User, error) { return nil, nil }
-func (m *mockUserRepository) Update(user *models.User) error { return nil }
+func (m *mockUserRepository) Create(_ context.Context, user *models.User) error { return nil }
+func (m *mockUserRepository) GetAll(_ context.Context) ([]models.User, error)   { return nil, nil }
+func (m *mockUserRepository) Update(_ context.Context, user *models.User) error { return nil }
 
-func (m *mockUserRepository) BulkDeactivateByTeam(teamName string) (int, error) {
+func (m *mockUserRepository) BulkDeactivateByTeam(_ context.Context, teamName string) (int, error) {
 	if m.bulkDeactivateByTeamFunc != nil {
 		return m.bulkDeactivateByTeamFunc(teamName)
 	}
 	return 0, nil
 }
 
-func (m *mockUserRepository) GetActiveUsersByTeam(teamName string, excludeUserID int) ([]models.User, error) {
+func (m *mockUserRepository) GetActiveUsersByTeam(_ context.Context, teamName string, excludeUserID int, _ time.Time) ([]models.User, error) {
 	if m.getActiveUsersByTeamFunc != nil {
 		return m.getActiveUsersByTeamFunc(teamName, excludeUserID)
 	}
 	return []models.User{}, nil
 }
 
+func (m *mockUserRepository) CreateUnavailability(_ context.Context, u *models.Unavailability) error {
+	if m.createUnavailabilityFunc != nil {
+		return m.createUnavailabilityFunc(u)
+	}
+	return nil
+}
+
+func (m *mockUserRepository) GetUnavailabilities(_ context.Context, userID int, _ time.Time) ([]models.Unavailability, error) {
+	return []models.Unavailability{}, nil
+}
+
+func (m *mockUserRepository) DeleteUnavailability(_ context.Context, userID int, id int) (bool, error) {
+	if m.deleteUnavailabilityFunc != nil {
+		return m.deleteUnavailabilityFunc(userID, id)
+	}
+	return true, nil
+}
+
+func (m *mockUserRepository) GetStartedUnavailabilities(_ context.Context, at time.Time) ([]models.Unavailability, error) {
+	if m.getStartedUnavailabilitiesFunc != nil {
+		return m.getStartedUnavailabilitiesFunc(at)
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepository) MarkUnavailabilityReassigned(_ context.Context, id int, at time.Time) error {
+	if m.markUnavailabilityReassignedFunc != nil {
+		return m.markUnavailabilityReassignedFunc(id, at)
+	}
+	return nil
+}
+
+func (m *mockUserRepository) GetByIdentity(_ context.Context, provider models.IdentityProvider, externalID string) (*models.User, error) {
+	if m.getByIdentityFunc != nil {
+		return m.getByIdentityFunc(provider, externalID)
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepository) CreateIdentity(_ context.Context, identity *models.UserIdentity) error {
+	if m.createIdentityFunc != nil {
+		return m.createIdentityFunc(identity)
+	}
+	return nil
+}
+
+func (m *mockUserRepository) GetIdentities(_ context.Context, userID int) ([]models.UserIdentity, error) {
+	return []models.UserIdentity{}, nil
+}
+
+func (m *mockUserRepository) DeleteIdentity(_ context.Context, userID int, id int) (bool, error) {
+	if m.deleteIdentityFunc != nil {
+		return m.deleteIdentityFunc(userID, id)
+	}
+	return true, nil
+}
+
 type mockTeamRepository struct {
-	getByNameFunc   func(string) (*models.Team, error)
-	getUserTeamFunc func(int) (string, error)
+	getByNameFunc      func(string) (*models.Team, error)
+	getUserTeamFunc    func(int) (string, error)
+	getUserTeamsFunc   func(int) ([]models.TeamMembership, error)
+	setPrimaryTeamFunc func(int, string) (bool, error)
+	getMemberRoleFunc  func(string, int) (models.TeamRole, error)
+	setMemberRoleFunc  func(string, int, models.TeamRole) (bool, error)
+	getSettingsFunc    func(string) (*models.TeamSettings, error)
+	upsertSettingsFunc func(*models.TeamSettings) error
 }
 
-func (m *mockTeamRepository) GetByName(name string) (*models.Team, error) {
+func (m *mockTeamRepository) GetByName(_ context.Context, name string) (*models.Team, error) {
 	if m.getByNameFunc != nil {
 		return m.getByNameFunc(name)
 	}
 	return nil, nil
 }
 
-func (m *mockTeamRepository) Create(team *models.Team) error                 { return nil }
-func (m *mockTeamRepository) GetAll() ([]models.Team, error)                 { return nil, nil }
-func (m *mockTeamRepository) AddMember(teamName string, userID int) error    { return nil }
-func (m *mockTeamRepository) RemoveMember(teamName string, userID int) error { return nil }
-func (m *mockTeamRepository) GetUserTeam(userID int) (string, error) {
+func (m *mockTeamRepository) Create(_ context.Context, team *models.Team) error { return nil }
+func (m *mockTeamRepository) GetAll(_ context.Context) ([]models.Team, error)   { return nil, nil }
+func (m *mockTeamRepository) AddMember(_ context.Context, teamName string, userID int) error {
+	return nil
+}
+func (m *mockTeamRepository) RemoveMember(_ context.Context, teamName string, userID int) error {
+	return nil
+}
+func (m *mockTeamRepository) GetUserTeam(_ context.Context, userID int) (string, error) {
 	if m.getUserTeamFunc != nil {
 		return m.getUserTeamFunc(userID)
 	}
 	return "team1", nil
 }
 
+func (m *mockTeamRepository) GetUserTeams(_ context.Context, userID int) ([]models.TeamMembership, error) {
+	if m.getUserTeamsFunc != nil {
+		return m.getUserTeamsFunc(userID)
+	}
+	return []models.TeamMembership{{TeamName: "team1", IsPrimary: true}}, nil
+}
+
+func (m *mockTeamRepository) SetPrimaryTeam(_ context.Context, userID int, teamName string) (bool, error) {
+	if m.setPrimaryTeamFunc != nil {
+		return m.setPrimaryTeamFunc(userID, teamName)
+	}
+	return true, nil
+}
+
+func (m *mockTeamRepository) GetMemberRole(_ context.Context, teamName string, userID int) (models.TeamRole, error) {
+	if m.getMemberRoleFunc != nil {
+		return m.getMemberRoleFunc(teamName, userID)
+	}
+	return models.TeamRoleMember, nil
+}
+
+func (m *mockTeamRepository) SetMemberRole(_ context.Context, teamName string, userID int, role models.TeamRole) (bool, error) {
+	if m.setMemberRoleFunc != nil {
+		return m.setMemberRoleFunc(teamName, userID, role)
+	}
+	return true, nil
+}
+
+func (m *mockTeamRepository) GetSettings(_ context.Context, teamName string) (*models.TeamSettings, error) {
+	if m.getSettingsFunc != nil {
+		return m.getSettingsFunc(teamName)
+	}
+	return nil, nil
+}
+
+func (m *mockTeamRepository) UpsertSettings(_ context.Context, settings *models.TeamSettings) error {
+	if m.upsertSettingsFunc != nil {
+		return m.upsertSettingsFunc(settings)
+	}
+	return nil
+}
+
 func TestCreatePR_Success(t *testing.T) {
 	mockPR := &mockPRRepository{
 		createFunc: func(pr *models.PR) error {
@@ -164,7 +343,7 @@ func TestCreatePR_Success(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.CreatePR("Test PR", 1)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -190,7 +369,7 @@ func TestCreatePR_AuthorNotFound(t *testing.T) {
 	mockTeam := &mockTeamRepository{}
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	_, err := service.CreatePR("Test PR", 1)
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	if !errors.Is(err, ErrAuthorNotFound) {
 		t.Errorf("expected ErrAuthorNotFound, got %v", err)
@@ -211,13 +390,133 @@ func TestCreatePR_AuthorNotInTeam(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	_, err := service.CreatePR("Test PR", 1)
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	if !errors.Is(err, ErrAuthorNotInTeam) {
 		t.Errorf("expected ErrAuthorNotInTeam, got %v", err)
 	}
 }
 
+func TestCreatePR_ExplicitTeam(t *testing.T) {
+	var candidatesTeam string
+	mockPR := &mockPRRepository{}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			candidatesTeam = teamName
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getUserTeamsFunc: func(userID int) ([]models.TeamMembership, error) {
+			return []models.TeamMembership{{TeamName: "backend", IsPrimary: true}, {TeamName: "platform"}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "platform", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pr.TeamName != "platform" {
+		t.Errorf("expected PR team platform, got %q", pr.TeamName)
+	}
+	if candidatesTeam != "platform" {
+		t.Errorf("expected reviewers to be picked from platform, got %q", candidatesTeam)
+	}
+}
+
+func TestCreatePR_AuthorNotTeamMember(t *testing.T) {
+	mockPR := &mockPRRepository{}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "platform", nil)
+
+	if !errors.Is(err, ErrAuthorNotTeamMember) {
+		t.Errorf("expected ErrAuthorNotTeamMember, got %v", err)
+	}
+}
+
+func TestCreatePR_ReviewerPools(t *testing.T) {
+	mockPR := &mockPRRepository{}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			if teamName == "security" {
+				// Пользователь 2 состоит в обеих командах и уже будет назначен из команды PR
+				return []models.User{{ID: 2, IsActive: true}, {ID: 9, IsActive: true}}, nil
+			}
+			return []models.User{{ID: 2, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			return &models.TeamSettings{
+				TeamName:          teamName,
+				RequiredReviewers: 1,
+				MinReviewers:      1,
+				ReviewerPools:     []models.ReviewerPool{{TeamName: "security", Reviewers: 1}},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pr.Reviews) != 2 {
+		t.Fatalf("expected 2 reviewers, got %+v", pr.Reviews)
+	}
+	if pr.Reviews[0].ReviewerID != 2 || pr.Reviews[0].PoolTeam != "" {
+		t.Errorf("expected reviewer 2 from the PR team, got %+v", pr.Reviews[0])
+	}
+	if pr.Reviews[1].ReviewerID != 9 || pr.Reviews[1].PoolTeam != "security" {
+		t.Errorf("expected reviewer 9 from the security pool, got %+v", pr.Reviews[1])
+	}
+}
+
+func TestCreatePR_ReviewerPoolNotFilled(t *testing.T) {
+	mockPR := &mockPRRepository{}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			if teamName == "security" {
+				return []models.User{}, nil
+			}
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			settings := models.DefaultTeamSettings(teamName)
+			settings.ReviewerPools = []models.ReviewerPool{{TeamName: "security", Reviewers: 1}}
+			return settings, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if !errors.Is(err, ErrInsufficientReviewers) {
+		t.Errorf("expected ErrInsufficientReviewers, got %v", err)
+	}
+}
+
 func TestCreatePR_NotEnoughReviewers(t *testing.T) {
 	mockPR := &mockPRRepository{}
 	mockUser := &mockUserRepository{
@@ -243,7 +542,7 @@ func TestCreatePR_NotEnoughReviewers(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	_, err := service.CreatePR("Test PR", 1)
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	if !errors.Is(err, ErrInsufficientReviewers) {
 		t.Errorf("expected ErrInsufficientReviewers, got %v", err)
@@ -259,7 +558,7 @@ func TestGetPR_Success(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	pr, err := service.GetPR(1)
+	pr, err := service.GetPR(context.Background(), 1)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -277,7 +576,7 @@ func TestGetPR_NotFound(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	_, err := service.GetPR(1)
+	_, err := service.GetPR(context.Background(), 1)
 
 	if !errors.Is(err, ErrPRNotFound) {
 		t.Errorf("expected ErrPRNotFound, got %v", err)
@@ -289,13 +588,13 @@ func TestMergePR_Success(t *testing.T) {
 		getByIDFunc: func(id int) (*models.PR, error) {
 			return &models.PR{ID: id, Title: "Test", Status: models.PRStatusOpen}, nil
 		},
-		updateStatusFunc: func(id int, status models.PRStatus) error {
+		mergeFunc: func(id int, forced bool) error {
 			return nil
 		},
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	pr, err := service.MergePR(1)
+	pr, err := service.MergePR(context.Background(), 1, false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -313,7 +612,7 @@ func TestMergePR_AlreadyMerged(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	pr, err := service.MergePR(1)
+	pr, err := service.MergePR(context.Background(), 1, false)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -331,131 +630,1158 @@ func TestMergePR_NotFound(t *testing.T) {
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	_, err := service.MergePR(1)
+	_, err := service.MergePR(context.Background(), 1, false)
 
 	if !errors.Is(err, ErrPRNotFound) {
 		t.Errorf("expected ErrPRNotFound, got %v", err)
 	}
 }
 
-func TestReassignReviewer_Success(t *testing.T) {
+func TestMergePR_ClosedConcurrently(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Title: "Test", Status: models.PRStatusOpen}, nil
+		},
+		mergeFunc: func(id int, forced bool) error {
+			// PR закрыли между чтением и блокировкой строки
+			return fmt.Errorf("%w: CLOSED -> MERGED", repository.ErrInvalidStatusTransition)
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.MergePR(context.Background(), 1, false)
+
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("expected ErrInvalidStatusTransition, got %v", err)
+	}
+}
+
+func TestMergePR_RequiresApprovals(t *testing.T) {
 	mockPR := &mockPRRepository{
 		getByIDFunc: func(id int) (*models.PR, error) {
 			return &models.PR{
 				ID:        id,
-				Title:     "Test",
 				AuthorID:  1,
 				Status:    models.PRStatusOpen,
-				Reviewers: []int{2, 3},
+				Reviewers: []int{2, 3, 4},
+				Reviews: []models.Review{
+					{ReviewerID: 2, State: models.ReviewStateApproved},
+					{ReviewerID: 3, State: models.ReviewStateChangesRequested},
+					{ReviewerID: 4, State: models.ReviewStatePending},
+				},
 			}, nil
 		},
-		reassignReviewerFunc: func(prID, oldID, newID int) error {
+		mergeFunc: func(id int, forced bool) error {
+			t.Error("PR must not be merged")
 			return nil
 		},
 	}
-	mockUser := &mockUserRepository{
-		getByIDFunc: func(id int) (*models.User, error) {
-			return &models.User{ID: id, Name: "User", IsActive: true}, nil
-		},
-		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
-			return []models.User{
-				{ID: 4, Name: "NewReviewer", IsActive: true},
-				{ID: 5, Name: "AnotherReviewer", IsActive: true},
-			}, nil
-		},
-	}
 	mockTeam := &mockTeamRepository{
-		getByNameFunc: func(name string) (*models.Team, error) {
-			return &models.Team{
-				Name: name,
-				Members: []models.User{
-					{ID: 1, Name: "Author", IsActive: true},
-					{ID: 2, Name: "OldReviewer", IsActive: true},
-					{ID: 3, Name: "Reviewer", IsActive: true},
-					{ID: 4, Name: "NewReviewer", IsActive: true},
-				},
-			}, nil
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			return &models.TeamSettings{TeamName: teamName, RequiredReviewers: 3, MinReviewers: 3, RequiredApprovals: 2}, nil
 		},
 	}
 
-	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.ReassignReviewer(1, 2)
+	service := NewPRService(mockPR, &mockUserRepository{}, mockTeam)
+	_, err := service.MergePR(context.Background(), 1, false)
 
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
+	var blocked *MergeBlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrMergeBlocked) {
+		t.Fatalf("expected MergeBlockedError, got %v", err)
 	}
-	if pr == nil {
-		t.Fatal("expected PR to be returned")
+	if blocked.Approvals != 1 || blocked.MissingApprovals() != 1 {
+		t.Errorf("expected 1 approval and 1 missing, got %d and %d", blocked.Approvals, blocked.MissingApprovals())
+	}
+	if len(blocked.ChangesRequestedBy) != 1 || blocked.ChangesRequestedBy[0] != 3 {
+		t.Errorf("expected changes requested by 3, got %v", blocked.ChangesRequestedBy)
+	}
+	if len(blocked.PendingReviewers) != 1 || blocked.PendingReviewers[0] != 4 {
+		t.Errorf("expected reviewer 4 to be pending, got %v", blocked.PendingReviewers)
 	}
 }
 
-func TestReassignReviewer_PRNotFound(t *testing.T) {
+func TestMergePR_ChangesRequestedBlocksWithoutRequiredApprovals(t *testing.T) {
 	mockPR := &mockPRRepository{
 		getByIDFunc: func(id int) (*models.PR, error) {
-			return nil, nil
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2},
+				Reviews:   []models.Review{{ReviewerID: 2, State: models.ReviewStateChangesRequested}},
+			}, nil
 		},
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	_, err := service.ReassignReviewer(1, 2)
+	_, err := service.MergePR(context.Background(), 1, false)
 
-	if !errors.Is(err, ErrPRNotFound) {
-		t.Errorf("expected ErrPRNotFound, got %v", err)
+	if !errors.Is(err, ErrMergeBlocked) {
+		t.Errorf("expected ErrMergeBlocked, got %v", err)
 	}
 }
 
-func TestReassignReviewer_PRAlreadyMerged(t *testing.T) {
+func TestMergePR_ForceBypassesCheck(t *testing.T) {
+	var mergedForced bool
 	mockPR := &mockPRRepository{
 		getByIDFunc: func(id int) (*models.PR, error) {
-			return &models.PR{ID: id, Status: models.PRStatusMerged}, nil
+			return &models.PR{ID: id, Status: models.PRStatusOpen, Reviewers: []int{2}}, nil
+		},
+		mergeFunc: func(id int, forced bool) error {
+			mergedForced = forced
+			return nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+			return &models.TeamSettings{TeamName: teamName, RequiredReviewers: 2, MinReviewers: 1, RequiredApprovals: 1}, nil
 		},
 	}
 
-	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	_, err := service.ReassignReviewer(1, 2)
+	service := NewPRService(mockPR, &mockUserRepository{}, mockTeam)
+	pr, err := service.MergePR(context.Background(), 1, true)
 
-	if !errors.Is(err, ErrPRAlreadyMerged) {
-		t.Errorf("expected ErrPRAlreadyMerged, got %v", err)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mergedForced || !pr.ForceMerged {
+		t.Error("expected forced merge to be recorded")
 	}
 }
 
-func TestReassignReviewer_ReviewerNotAssigned(t *testing.T) {
+func TestMergePR_ForceNotRecordedWhenRequirementsMet(t *testing.T) {
+	var mergedForced bool
 	mockPR := &mockPRRepository{
 		getByIDFunc: func(id int) (*models.PR, error) {
 			return &models.PR{
 				ID:        id,
 				Status:    models.PRStatusOpen,
-				Reviewers: []int{3, 4},
+				Reviewers: []int{2},
+				Reviews:   []models.Review{{ReviewerID: 2, State: models.ReviewStateApproved}},
 			}, nil
 		},
+		mergeFunc: func(id int, forced bool) error {
+			mergedForced = forced
+			return nil
+		},
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	_, err := service.ReassignReviewer(1, 2)
-
-	if !errors.Is(err, ErrReviewerNotAssigned) {
-		t.Errorf("expected ErrReviewerNotAssigned, got %v", err)
+	if _, err := service.MergePR(context.Background(), 1, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if mergedForced {
+		t.Error("expected merge not to be marked as forced")
 	}
 }
 
-func TestGetPRsByUserID_Success(t *testing.T) {
-	expectedPRs := []models.PR{
-		{ID: 1, Title: "PR1"},
-		{ID: 2, Title: "PR2"},
-	}
+func TestMergePR_ReviewChangedConcurrently(t *testing.T) {
+	reads := 0
 	mockPR := &mockPRRepository{
-		getByUserIDFunc: func(id int) ([]models.PR, error) {
-			return expectedPRs, nil
+		getByIDFunc: func(id int) (*models.PR, error) {
+			reads++
+			state := models.ReviewStateApproved
+			if reads > 1 {
+				state = models.ReviewStateChangesRequested
+			}
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2},
+				Reviews:   []models.Review{{ReviewerID: 2, State: state}},
+			}, nil
+		},
+		mergeFunc: func(id int, forced bool) error {
+			return repository.ErrMergeBlocked
 		},
 	}
 
 	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
-	prs, err := service.GetPRsByUserID(1)
+	_, err := service.MergePR(context.Background(), 1, false)
 
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
+	var blocked *MergeBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected MergeBlockedError, got %v", err)
 	}
-	if len(prs) != 2 {
-		t.Errorf("expected 2 PRs, got %d", len(prs))
+	if len(blocked.ChangesRequestedBy) != 1 || blocked.ChangesRequestedBy[0] != 2 {
+		t.Errorf("expected changes requested by 2, got %v", blocked.ChangesRequestedBy)
+	}
+}
+
+func TestMergePR_ForceAfterReviewChangedConcurrently(t *testing.T) {
+	var calls []bool
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2},
+				Reviews:   []models.Review{{ReviewerID: 2, State: models.ReviewStateApproved}},
+			}, nil
+		},
+		mergeFunc: func(id int, forced bool) error {
+			calls = append(calls, forced)
+			if !forced {
+				return repository.ErrMergeBlocked
+			}
+			return nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	pr, err := service.MergePR(context.Background(), 1, true)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("expected a checked merge followed by a forced one, got %v", calls)
+	}
+	if !pr.ForceMerged {
+		t.Error("expected forced merge to be recorded")
+	}
+}
+
+func TestReassignReviewer_Success(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				Title:     "Test",
+				AuthorID:  1,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2, 3},
+			}, nil
+		},
+		reassignReviewerFunc: func(prID, oldID, newID int) error {
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "User", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{
+				{ID: 4, Name: "NewReviewer", IsActive: true},
+				{ID: 5, Name: "AnotherReviewer", IsActive: true},
+			}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{
+				Name: name,
+				Members: []models.User{
+					{ID: 1, Name: "Author", IsActive: true},
+					{ID: 2, Name: "OldReviewer", IsActive: true},
+					{ID: 3, Name: "Reviewer", IsActive: true},
+					{ID: 4, Name: "NewReviewer", IsActive: true},
+				},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	pr, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pr == nil {
+		t.Fatal("expected PR to be returned")
+	}
+}
+
+func TestReassignReviewer_StaysInPool(t *testing.T) {
+	var candidatesTeam string
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				AuthorID:  1,
+				TeamName:  "backend",
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2, 3},
+				Reviews:   []models.Review{{ReviewerID: 2}, {ReviewerID: 3, PoolTeam: "security"}},
+			}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			candidatesTeam = teamName
+			return []models.User{{ID: 7, IsActive: true}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	if _, err := service.ReassignReviewer(context.Background(), 1, 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if candidatesTeam != "security" {
+		t.Errorf("expected replacement from the security pool, got %q", candidatesTeam)
+	}
+}
+
+func TestReassignReviewer_PRNotFound(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return nil, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrPRNotFound) {
+		t.Errorf("expected ErrPRNotFound, got %v", err)
+	}
+}
+
+func TestReassignReviewer_PRAlreadyMerged(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Status: models.PRStatusMerged}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrPRAlreadyMerged) {
+		t.Errorf("expected ErrPRAlreadyMerged, got %v", err)
+	}
+}
+
+func TestReassignReviewer_ReviewerNotAssigned(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{3, 4},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrReviewerNotAssigned) {
+		t.Errorf("expected ErrReviewerNotAssigned, got %v", err)
+	}
+}
+
+func TestReassignReviewer_ReviewerAlreadyApproved(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2, 3},
+				Reviews: []models.Review{
+					{ReviewerID: 2, State: models.ReviewStateApproved},
+					{ReviewerID: 3, State: models.ReviewStatePending},
+				},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrReviewerApproved) {
+		t.Errorf("expected ErrReviewerApproved, got %v", err)
+	}
+}
+
+func TestSubmitReview_Success(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var saved models.ReviewState
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{
+				ID:        id,
+				Status:    models.PRStatusOpen,
+				Reviewers: []int{2, 3},
+				Reviews:   []models.Review{{ReviewerID: 2, State: saved}, {ReviewerID: 3, State: models.ReviewStatePending}},
+			}, nil
+		},
+		setReviewStateFunc: func(prID, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+			if reviewerID != 2 {
+				t.Errorf("expected review from reviewer 2, got %d", reviewerID)
+			}
+			if !reviewedAt.Equal(now) {
+				t.Errorf("expected review time from the service clock, got %v", reviewedAt)
+			}
+			saved = state
+			return nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{}, WithClock(func() time.Time { return now }))
+	pr, err := service.SubmitReview(context.Background(), 1, 2, models.ReviewStateChangesRequested)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pr.ReviewState(2) != models.ReviewStateChangesRequested {
+		t.Errorf("expected CHANGES_REQUESTED, got %s", pr.ReviewState(2))
+	}
+}
+
+func TestSubmitReview_Errors(t *testing.T) {
+	openPR := &models.PR{ID: 1, Status: models.PRStatusOpen, Reviewers: []int{2}}
+	mergedPR := &models.PR{ID: 1, Status: models.PRStatusMerged, Reviewers: []int{2}}
+
+	tests := []struct {
+		name       string
+		pr         *models.PR
+		reviewerID int
+		state      models.ReviewState
+		wantErr    error
+	}{
+		{name: "PR not found", pr: nil, reviewerID: 2, state: models.ReviewStateApproved, wantErr: ErrPRNotFound},
+		{name: "PR merged", pr: mergedPR, reviewerID: 2, state: models.ReviewStateApproved, wantErr: ErrReviewOnMergedPR},
+		{name: "not a reviewer", pr: openPR, reviewerID: 5, state: models.ReviewStateApproved, wantErr: ErrNotPRReviewer},
+		{name: "pending is not a verdict", pr: openPR, reviewerID: 2, state: models.ReviewStatePending, wantErr: ErrInvalidReviewState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPR := &mockPRRepository{
+				getByIDFunc: func(id int) (*models.PR, error) {
+					return tt.pr, nil
+				},
+				setReviewStateFunc: func(prID, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+					t.Error("review must not be saved")
+					return nil
+				},
+			}
+
+			service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+			_, err := service.SubmitReview(context.Background(), 1, tt.reviewerID, tt.state)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSubmitReview_ClosedConcurrently(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Status: models.PRStatusOpen, Reviewers: []int{2}}, nil
+		},
+		setReviewStateFunc: func(prID, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+			// PR смержили между чтением и блокировкой строки
+			return repository.ErrPRNotOpen
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.SubmitReview(context.Background(), 1, 2, models.ReviewStateApproved)
+
+	if !errors.Is(err, ErrPRNotOpen) {
+		t.Errorf("expected ErrPRNotOpen, got %v", err)
+	}
+}
+
+func TestGetPRsByUserID_Success(t *testing.T) {
+	expectedPRs := []models.PR{
+		{ID: 1, Title: "PR1"},
+		{ID: 2, Title: "PR2"},
+	}
+	mockPR := &mockPRRepository{
+		getByUserIDFunc: func(id int) ([]models.PR, error) {
+			return expectedPRs, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	prs, err := service.GetPRsByUserID(context.Background(), 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(prs) != 2 {
+		t.Errorf("expected 2 PRs, got %d", len(prs))
+	}
+}
+
+func TestCreatePR_ExposesCandidateLoads(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{3: 2}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 3}, {ID: 2}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []models.CandidateLoad{{UserID: 2, OpenReviews: 0}, {UserID: 3, OpenReviews: 2}}
+	if len(pr.CandidateLoads) != len(expected) {
+		t.Fatalf("expected %d candidate loads, got %d", len(expected), len(pr.CandidateLoads))
+	}
+	for i, load := range expected {
+		if pr.CandidateLoads[i] != load {
+			t.Errorf("expected candidate load %v, got %v", load, pr.CandidateLoads[i])
+		}
+	}
+}
+
+func TestPreviewPR_ExplainsExclusions(t *testing.T) {
+	created := false
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			created = true
+			return nil
+		},
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{5: 1}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}, {ID: 5, IsActive: true, MaxOpenReviews: intPtr(1)}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{
+				{ID: 1, IsActive: true},
+				{ID: 2, IsActive: true},
+				{ID: 3, IsActive: true},
+				{ID: 4, IsActive: false},
+				{ID: 5, IsActive: true, MaxOpenReviews: intPtr(1)},
+				{ID: 6, IsActive: true},
+			}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	preview, err := service.PreviewPR(context.Background(), 1, "", nil, nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created {
+		t.Error("expected preview not to create a PR")
+	}
+	if preview.TeamName != "team1" || len(preview.Reviewers) != 2 || preview.Reviewers[0] == 5 || preview.Reviewers[1] == 5 {
+		t.Errorf("expected reviewers 2 and 3 from team1, got %+v", preview)
+	}
+
+	expected := []models.ReviewerExclusion{
+		{TeamName: "team1", Reason: models.ExclusionReasonAuthor, UserID: 1},
+		{TeamName: "team1", Reason: models.ExclusionReasonInactive, UserID: 4},
+		{TeamName: "team1", Reason: models.ExclusionReasonAtCapacity, UserID: 5},
+		{TeamName: "team1", Reason: models.ExclusionReasonOOO, UserID: 6},
+	}
+	if len(preview.Exclusions) != len(expected) {
+		t.Fatalf("expected %d exclusions, got %+v", len(expected), preview.Exclusions)
+	}
+	for i, exclusion := range expected {
+		if preview.Exclusions[i] != exclusion {
+			t.Errorf("expected exclusion %+v, got %+v", exclusion, preview.Exclusions[i])
+		}
+	}
+}
+
+func TestPreviewPR_DoesNotAdvanceRoundRobin(t *testing.T) {
+	var stored *models.PR
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			stored = pr
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2}, {ID: 3}, {ID: 4}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{}, WithReviewerSelector(NewRoundRobinSelector()))
+	first, err := service.PreviewPR(context.Background(), 1, "", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, _ := service.PreviewPR(context.Background(), 1, "", nil, nil)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i := range first.Reviewers {
+		if first.Reviewers[i] != second.Reviewers[i] || first.Reviewers[i] != pr.Reviewers[i] {
+			t.Errorf("expected previews and PR to get the same reviewers, got %v, %v and %v", first.Reviewers, second.Reviewers, pr.Reviewers)
+		}
+	}
+	if len(stored.Assignments) != 2 || stored.Assignments[0].Source != models.AssignmentSourceTeam {
+		t.Errorf("expected the explanation to be stored with the PR, got %+v", stored.Assignments)
+	}
+}
+
+func TestCreatePR_SeedMakesAssignmentReproducible(t *testing.T) {
+	members := []models.User{{ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}}
+	newService := func(order []models.User) *PRService {
+		mockUser := &mockUserRepository{
+			getByIDFunc: func(id int) (*models.User, error) {
+				return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+			},
+			getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+				return order, nil
+			},
+		}
+		return NewPRService(&mockPRRepository{}, mockUser, &mockTeamRepository{}, WithSeedSource(func() int64 { return 42 }))
+	}
+
+	reversed := make([]models.User, len(members))
+	for i, member := range members {
+		reversed[len(members)-1-i] = member
+	}
+
+	first, err := newService(members).CreatePR(context.Background(), "Test PR", 1, "", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, _ := newService(reversed).CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if first.AssignmentSeed != 42 {
+		t.Errorf("expected seed 42 to be stored with the PR, got %d", first.AssignmentSeed)
+	}
+	for i := range first.Reviewers {
+		if first.Reviewers[i] != second.Reviewers[i] {
+			t.Fatalf("expected the same reviewers for the same seed, got %v and %v", first.Reviewers, second.Reviewers)
+		}
+	}
+
+	// Предпросмотр с тем же зерном воспроизводит назначение, даже если сервис выдает другие зерна
+	replay := newService(members)
+	replay.options.seeds = func() int64 { return 7 }
+	seed := first.AssignmentSeed
+	preview, err := replay.PreviewPR(context.Background(), 1, "", nil, &seed)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i := range first.Reviewers {
+		if first.Reviewers[i] != preview.Reviewers[i] {
+			t.Fatalf("expected preview to replay reviewers %v, got %v", first.Reviewers, preview.Reviewers)
+		}
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestCreatePR_ReviewersAtCapacity(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{2: 3, 3: 1}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{
+				{ID: 2, IsActive: true, MaxOpenReviews: intPtr(3)},
+				{ID: 3, IsActive: true, MaxOpenReviews: intPtr(1)},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if !errors.Is(err, ErrReviewersAtCapacity) {
+		t.Errorf("expected ErrReviewersAtCapacity, got %v", err)
+	}
+}
+
+func TestReassignReviewer_SkipsReviewersAtCapacity(t *testing.T) {
+	var newReviewer int
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2, 3}}, nil
+		},
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{4: 2, 5: 7}, nil
+		},
+		reassignReviewerFunc: func(prID, oldID, newID int) error {
+			newReviewer = newID
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{
+				{ID: 4, IsActive: true, MaxOpenReviews: intPtr(2)},
+				{ID: 5, IsActive: true},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	if _, err := service.ReassignReviewer(context.Background(), 1, 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if newReviewer != 5 {
+		t.Errorf("expected reviewer without limit to be picked, got %d", newReviewer)
+	}
+}
+
+func TestReassignReviewer_AllAtCapacity(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2, 3}}, nil
+		},
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{4: 2}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 4, IsActive: true, MaxOpenReviews: intPtr(2)}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrReviewersAtCapacity) {
+		t.Errorf("expected ErrReviewersAtCapacity, got %v", err)
+	}
+}
+
+// emptySelector - пользовательская стратегия, которая никого не выбирает
+type emptySelector struct{}
+
+func (emptySelector) Select(string, []ReviewerCandidate, int, *rand.Rand) []int { return nil }
+
+func TestReassignReviewer_SelectorPicksNobody(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2, 3}}, nil
+		},
+		reassignReviewerFunc: func(prID, oldID, newID int) error {
+			t.Fatal("reviewer must not be reassigned")
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 4, IsActive: true}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{}, WithReviewerSelector(emptySelector{}))
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrInsufficientReviewers) {
+		t.Errorf("expected ErrInsufficientReviewers, got %v", err)
+	}
+}
+
+func TestCreatePR_SelectorPicksFewerThanMinimum(t *testing.T) {
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			t.Fatal("PR must not be created")
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getUserTeamFunc: func(userID int) (string, error) {
+			return "backend", nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, mockTeam, WithReviewerSelector(emptySelector{}))
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if !errors.Is(err, ErrInsufficientReviewers) {
+		t.Errorf("expected ErrInsufficientReviewers, got %v", err)
+	}
+}
+
+func TestCreatePR_UsesTeamSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		settings      *models.TeamSettings
+		candidates    []models.User
+		wantReviewers int
+		wantErr       error
+	}{
+		{
+			name:          "single reviewer team",
+			settings:      &models.TeamSettings{RequiredReviewers: 1, MinReviewers: 1},
+			candidates:    []models.User{{ID: 2}, {ID: 3}},
+			wantReviewers: 1,
+		},
+		{
+			name:          "critical team gets three reviewers",
+			settings:      &models.TeamSettings{RequiredReviewers: 3, MinReviewers: 3},
+			candidates:    []models.User{{ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+			wantReviewers: 3,
+		},
+		{
+			name:          "partial assignment above minimum",
+			settings:      &models.TeamSettings{RequiredReviewers: 3, MinReviewers: 1},
+			candidates:    []models.User{{ID: 2}, {ID: 3}},
+			wantReviewers: 2,
+		},
+		{
+			name:       "below minimum",
+			settings:   &models.TeamSettings{RequiredReviewers: 3, MinReviewers: 3},
+			candidates: []models.User{{ID: 2}, {ID: 3}},
+			wantErr:    ErrInsufficientReviewers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUser := &mockUserRepository{
+				getByIDFunc: func(id int) (*models.User, error) {
+					return &models.User{ID: id, IsActive: true}, nil
+				},
+				getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+					return tt.candidates, nil
+				},
+			}
+			mockTeam := &mockTeamRepository{
+				getSettingsFunc: func(teamName string) (*models.TeamSettings, error) {
+					return tt.settings, nil
+				},
+			}
+
+			service := NewPRService(&mockPRRepository{}, mockUser, mockTeam)
+			pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(pr.Reviewers) != tt.wantReviewers {
+				t.Errorf("expected %d reviewers, got %d", tt.wantReviewers, len(pr.Reviewers))
+			}
+		})
+	}
+}
+
+func TestCreateDraftPR_NoReviewers(t *testing.T) {
+	var created *models.PR
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			created = pr
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			t.Error("draft PR must not look for reviewers")
+			return nil, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	pr, err := service.CreateDraftPR(context.Background(), "Draft", 1, "", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created == nil || pr.Status != models.PRStatusDraft || len(pr.Reviewers) != 0 {
+		t.Errorf("expected draft without reviewers, got %+v", pr)
+	}
+}
+
+func TestMarkReady_AssignsReviewers(t *testing.T) {
+	var assigned []int
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			status := models.PRStatusDraft
+			if assigned != nil {
+				status = models.PRStatusOpen
+			}
+			return &models.PR{ID: id, AuthorID: 1, Status: status, Reviewers: assigned}, nil
+		},
+		updateStatusReviewersFunc: func(id int, status models.PRStatus, reviews []models.Review) error {
+			if status != models.PRStatusOpen {
+				t.Errorf("expected status OPEN, got %s", status)
+			}
+			assigned = models.ReviewerIDs(reviews)
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2}, {ID: 3}, {ID: 4}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	pr, err := service.MarkReady(context.Background(), 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pr.Status != models.PRStatusOpen || len(pr.Reviewers) != 2 {
+		t.Errorf("expected open PR with 2 reviewers, got %+v", pr)
+	}
+}
+
+func TestReopenPR_ReplacesUnavailableReviewers(t *testing.T) {
+	var assigned []int
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			if assigned != nil {
+				return &models.PR{ID: id, AuthorID: 1, Status: models.PRStatusOpen, Reviewers: assigned}, nil
+			}
+			return &models.PR{
+				ID:        id,
+				AuthorID:  1,
+				Status:    models.PRStatusClosed,
+				Reviewers: []int{2, 5},
+				Reviews:   []models.Review{{ReviewerID: 2, State: models.ReviewStatePending}, {ReviewerID: 5, State: models.ReviewStatePending}},
+			}, nil
+		},
+		updateStatusReviewersFunc: func(id int, status models.PRStatus, reviews []models.Review) error {
+			assigned = models.ReviewerIDs(reviews)
+			return nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			// Ревьювер 5 был деактивирован, пока PR был закрыт
+			return []models.User{{ID: 2}, {ID: 3}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{})
+	pr, err := service.ReopenPR(context.Background(), 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(pr.Reviewers) != 2 || pr.Reviewers[0] != 2 || pr.Reviewers[1] != 3 {
+		t.Errorf("expected reviewer 2 to be kept and 5 replaced by 3, got %v", pr.Reviewers)
+	}
+}
+
+func TestPRLifecycle_InvalidTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.PRStatus
+		action func(*PRService) (*models.PR, error)
+	}{
+		{name: "close merged", status: models.PRStatusMerged, action: func(s *PRService) (*models.PR, error) { return s.ClosePR(context.Background(), 1) }},
+		{name: "close closed", status: models.PRStatusClosed, action: func(s *PRService) (*models.PR, error) { return s.ClosePR(context.Background(), 1) }},
+		{name: "reopen open", status: models.PRStatusOpen, action: func(s *PRService) (*models.PR, error) { return s.ReopenPR(context.Background(), 1) }},
+		{name: "reopen draft", status: models.PRStatusDraft, action: func(s *PRService) (*models.PR, error) { return s.ReopenPR(context.Background(), 1) }},
+		{name: "ready closed", status: models.PRStatusClosed, action: func(s *PRService) (*models.PR, error) { return s.MarkReady(context.Background(), 1) }},
+		{name: "merge draft", status: models.PRStatusDraft, action: func(s *PRService) (*models.PR, error) { return s.MergePR(context.Background(), 1, true) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPR := &mockPRRepository{
+				getByIDFunc: func(id int) (*models.PR, error) {
+					return &models.PR{ID: id, AuthorID: 1, Status: tt.status}, nil
+				},
+			}
+
+			service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+			_, err := tt.action(service)
+
+			if !errors.Is(err, ErrInvalidStatusTransition) {
+				t.Errorf("expected ErrInvalidStatusTransition, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReassignReviewer_ClosedPR(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Status: models.PRStatusClosed, Reviewers: []int{2}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.ReassignReviewer(context.Background(), 1, 2)
+
+	if !errors.Is(err, ErrPRNotOpen) {
+		t.Errorf("expected ErrPRNotOpen, got %v", err)
+	}
+}
+
+func TestGetPREvents_Success(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return &models.PR{ID: id, Status: models.PRStatusOpen}, nil
+		},
+		getEventsFunc: func(prID int) ([]models.PREvent, error) {
+			return []models.PREvent{
+				{ID: 1, PRID: prID, Type: models.PREventCreated},
+				{ID: 2, PRID: prID, Type: models.PREventReviewerAssigned},
+			}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	events, err := service.GetPREvents(context.Background(), 1)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(events) != 2 || events[0].Type != models.PREventCreated {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestGetPREvents_PRNotFound(t *testing.T) {
+	mockPR := &mockPRRepository{
+		getByIDFunc: func(id int) (*models.PR, error) {
+			return nil, nil
+		},
+	}
+
+	service := NewPRService(mockPR, &mockUserRepository{}, &mockTeamRepository{})
+	_, err := service.GetPREvents(context.Background(), 1)
+
+	if !errors.Is(err, ErrPRNotFound) {
+		t.Errorf("expected ErrPRNotFound, got %v", err)
+	}
+}
+
+// recordingObserver запоминает сведения о назначении ревьюверов
+type recordingObserver struct {
+	created      map[string]int
+	assigned     map[string]int
+	reassigned   map[string]int
+	insufficient map[string]int
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{
+		created:      make(map[string]int),
+		assigned:     make(map[string]int),
+		reassigned:   make(map[string]int),
+		insufficient: make(map[string]int),
+	}
+}
+
+func (o *recordingObserver) PRCreated(teamName string) { o.created[teamName]++ }
+func (o *recordingObserver) ReviewersAssigned(teamName string, count int) {
+	o.assigned[teamName] += count
+}
+func (o *recordingObserver) ReviewersReassigned(teamName string, count int) {
+	o.reassigned[teamName] += count
+}
+func (o *recordingObserver) InsufficientReviewers(teamName string) { o.insufficient[teamName]++ }
+
+func TestCreatePR_ObservesAssignment(t *testing.T) {
+	candidates := []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return candidates, nil
+		},
+	}
+	observer := newRecordingObserver()
+	service := NewPRService(&mockPRRepository{}, mockUser, &mockTeamRepository{}, WithAssignmentObserver(observer))
+
+	if _, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	candidates = candidates[:1]
+	if _, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil); !errors.Is(err, ErrInsufficientReviewers) {
+		t.Fatalf("expected ErrInsufficientReviewers, got %v", err)
+	}
+
+	if observer.created["team1"] != 1 {
+		t.Errorf("expected 1 created PR, got %d", observer.created["team1"])
+	}
+	if observer.assigned["team1"] != 2 {
+		t.Errorf("expected 2 assigned reviewers, got %d", observer.assigned["team1"])
+	}
+	if observer.insufficient["team1"] != 1 {
+		t.Errorf("expected 1 insufficient reviewers error, got %d", observer.insufficient["team1"])
+	}
+}
+
+func TestCreatePR_RecordsSpans(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2, IsActive: true}}, nil
+		},
+	}
+	service := NewPRService(&mockPRRepository{}, mockUser, &mockTeamRepository{})
+
+	_, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+	if !errors.Is(err, ErrInsufficientReviewers) {
+		t.Fatalf("expected ErrInsufficientReviewers, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %v", tracingtest.SpanNames(recorder))
+	}
+	if spans[0].Name() != "PRService.CreatePR" {
+		t.Errorf("expected span PRService.CreatePR, got %s", spans[0].Name())
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[0].Status().Code)
 	}
 }
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
 	"github.com/Rodjolo/pr-reviewer-service/mocks"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 	"github.com/stretchr/testify/assert"
@@ -20,18 +23,21 @@ func TestCreatePR_WithMockery_Success(t *testing.T) {
 		{ID: 3, Name: "Reviewer2", IsActive: true},
 	}
 
-	mockUser.On("GetByID", 1).Return(author, nil)
-	mockTeam.On("GetUserTeam", 1).Return("team1", nil)
-	mockUser.On("GetActiveUsersByTeam", "team1", 1).Return(reviewers, nil)
-	mockPR.On("Create", mock.MatchedBy(func(pr *models.PR) bool {
-		return pr.Title == "Test PR" && pr.AuthorID == 1 && len(pr.Reviewers) == 2
+	mockUser.On("GetByID", mock.Anything, 1).Return(author, nil)
+	mockTeam.On("GetUserTeam", mock.Anything, 1).Return("team1", nil)
+	mockTeam.On("GetSettings", mock.Anything, "team1").Return(nil, nil)
+	mockUser.On("GetActiveUsersByTeam", mock.Anything, "team1", 1, mock.Anything).Return(reviewers, nil)
+	mockTeam.On("GetByName", mock.Anything, "team1").Return(&models.Team{Name: "team1", Members: append([]models.User{*author}, reviewers...)}, nil)
+	mockPR.On("GetOpenReviewCounts", mock.Anything, []int{2, 3}).Return(map[int]int{2: 1}, nil)
+	mockPR.On("Create", mock.Anything, mock.MatchedBy(func(pr *models.PR) bool {
+		return pr.Title == "Test PR" && pr.AuthorID == 1 && len(pr.Reviewers) == 2 && len(pr.Exclusions) == 1
 	})).Run(func(args mock.Arguments) {
-		pr := args.Get(0).(*models.PR)
+		pr := args.Get(1).(*models.PR)
 		pr.ID = 1
 	}).Return(nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.CreatePR("Test PR", 1)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, pr)
@@ -49,10 +55,10 @@ func TestCreatePR_WithMockery_AuthorNotFound(t *testing.T) {
 	mockUser := mocks.NewMockUserRepositoryInterface(t)
 	mockTeam := mocks.NewMockTeamRepositoryInterface(t)
 
-	mockUser.On("GetByID", 999).Return(nil, nil)
+	mockUser.On("GetByID", mock.Anything, 999).Return(nil, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.CreatePR("Test PR", 999)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 999, "", nil)
 
 	assert.Error(t, err)
 	assert.Nil(t, pr)
@@ -71,12 +77,13 @@ func TestCreatePR_WithMockery_InsufficientReviewers(t *testing.T) {
 		{ID: 2, Name: "OnlyReviewer", IsActive: true},
 	}
 
-	mockUser.On("GetByID", 1).Return(author, nil)
-	mockTeam.On("GetUserTeam", 1).Return("team1", nil)
-	mockUser.On("GetActiveUsersByTeam", "team1", 1).Return(onlyOneReviewer, nil)
+	mockUser.On("GetByID", mock.Anything, 1).Return(author, nil)
+	mockTeam.On("GetUserTeam", mock.Anything, 1).Return("team1", nil)
+	mockTeam.On("GetSettings", mock.Anything, "team1").Return(nil, nil)
+	mockUser.On("GetActiveUsersByTeam", mock.Anything, "team1", 1, mock.Anything).Return(onlyOneReviewer, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.CreatePR("Test PR", 1)
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
 
 	assert.Error(t, err)
 	assert.Nil(t, pr)
@@ -98,10 +105,10 @@ func TestGetPR_WithMockery_Success(t *testing.T) {
 		Status:   models.PRStatusOpen,
 	}
 
-	mockPR.On("GetByID", 1).Return(expectedPR, nil)
+	mockPR.On("GetByID", mock.Anything, 1).Return(expectedPR, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.GetPR(1)
+	pr, err := service.GetPR(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedPR, pr)
@@ -114,10 +121,10 @@ func TestGetPR_WithMockery_NotFound(t *testing.T) {
 	mockUser := mocks.NewMockUserRepositoryInterface(t)
 	mockTeam := mocks.NewMockTeamRepositoryInterface(t)
 
-	mockPR.On("GetByID", 999).Return(nil, nil)
+	mockPR.On("GetByID", mock.Anything, 999).Return(nil, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.GetPR(999)
+	pr, err := service.GetPR(context.Background(), 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, pr)
@@ -138,19 +145,45 @@ func TestMergePR_WithMockery_Success(t *testing.T) {
 		Status:   models.PRStatusOpen,
 	}
 
-	mockPR.On("GetByID", 1).Return(existingPR, nil)
-	mockPR.On("UpdateStatus", 1, models.PRStatusMerged).Return(nil)
+	mockPR.On("GetByID", mock.Anything, 1).Return(existingPR, nil)
+	mockTeam.On("GetUserTeam", mock.Anything, 1).Return("team1", nil)
+	mockTeam.On("GetSettings", mock.Anything, "team1").Return(nil, nil)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	mockPR.On("Merge", mock.Anything, 1, false, 0, now, (*int)(nil)).Return(nil)
 
-	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.MergePR(1)
+	service := NewPRService(mockPR, mockUser, mockTeam, WithClock(func() time.Time { return now }))
+	pr, err := service.MergePR(context.Background(), 1, false)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, pr)
 	assert.Equal(t, models.PRStatusMerged, pr.Status)
 
 	mockPR.AssertExpectations(t)
-	mockPR.AssertCalled(t, "GetByID", 1)
-	mockPR.AssertCalled(t, "UpdateStatus", 1, models.PRStatusMerged)
+	mockPR.AssertCalled(t, "GetByID", mock.Anything, 1)
+	mockPR.AssertCalled(t, "Merge", mock.Anything, 1, false, 0, now, (*int)(nil))
+}
+
+func TestMergePR_WithMockery_RecordsActor(t *testing.T) {
+	mockPR := mocks.NewMockPRRepositoryInterface(t)
+	mockUser := mocks.NewMockUserRepositoryInterface(t)
+	mockTeam := mocks.NewMockTeamRepositoryInterface(t)
+
+	existingPR := &models.PR{ID: 1, Title: "Test PR", AuthorID: 1, Status: models.PRStatusOpen, Reviewers: []int{2}}
+
+	mockPR.On("GetByID", mock.Anything, 1).Return(existingPR, nil)
+	mockTeam.On("GetUserTeam", mock.Anything, 1).Return("team1", nil)
+	mockTeam.On("GetSettings", mock.Anything, "team1").Return(&models.TeamSettings{TeamName: "team1", RequiredApprovals: 1}, nil)
+	mockPR.On("Merge", mock.Anything, 1, true, 1, mock.Anything, mock.MatchedBy(func(actorID *int) bool {
+		return actorID != nil && *actorID == 7
+	})).Return(nil)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root", Role: auth.RoleAdmin, UserID: 7})
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	pr, err := service.MergePR(ctx, 1, true)
+
+	assert.NoError(t, err)
+	assert.True(t, pr.ForceMerged)
+	mockPR.AssertExpectations(t)
 }
 
 func TestMergePR_WithMockery_AlreadyMerged(t *testing.T) {
@@ -165,17 +198,34 @@ func TestMergePR_WithMockery_AlreadyMerged(t *testing.T) {
 		Status:   models.PRStatusMerged,
 	}
 
-	mockPR.On("GetByID", 1).Return(mergedPR, nil)
+	mockPR.On("GetByID", mock.Anything, 1).Return(mergedPR, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.MergePR(1)
+	pr, err := service.MergePR(context.Background(), 1, false)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, pr)
 	assert.Equal(t, models.PRStatusMerged, pr.Status)
 
 	mockPR.AssertExpectations(t)
-	mockPR.AssertNotCalled(t, "UpdateStatus")
+	mockPR.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestClosePR_WithMockery_UsesServiceClock(t *testing.T) {
+	mockPR := mocks.NewMockPRRepositoryInterface(t)
+	mockUser := mocks.NewMockUserRepositoryInterface(t)
+	mockTeam := mocks.NewMockTeamRepositoryInterface(t)
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	mockPR.On("GetByID", mock.Anything, 1).Return(&models.PR{ID: 1, AuthorID: 1, Status: models.PRStatusOpen}, nil)
+	mockPR.On("UpdateStatus", mock.Anything, 1, models.PRStatusClosed, now, (*int)(nil)).Return(nil)
+
+	service := NewPRService(mockPR, mockUser, mockTeam, WithClock(func() time.Time { return now }))
+	pr, err := service.ClosePR(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PRStatusClosed, pr.Status)
+	mockPR.AssertExpectations(t)
 }
 
 func TestReassignReviewer_WithMockery_Success(t *testing.T) {
@@ -198,16 +248,17 @@ func TestReassignReviewer_WithMockery_Success(t *testing.T) {
 		{ID: 5, Name: "AnotherReviewer", IsActive: true},
 	}
 
-	mockPR.On("GetByID", 1).Return(existingPR, nil).Maybe()
-	mockUser.On("GetByID", 1).Return(author, nil).Maybe()
-	mockUser.On("GetByID", 2).Return(oldReviewer, nil).Maybe()
-	mockTeam.On("GetUserTeam", 1).Return("team1", nil).Maybe()
-	mockTeam.On("GetUserTeam", 2).Return("team1", nil).Maybe()
-	mockUser.On("GetActiveUsersByTeam", "team1", 2).Return(newReviewers, nil).Maybe()
-	mockPR.On("ReassignReviewer", 1, 2, mock.AnythingOfType("int")).Return(nil).Maybe()
+	mockPR.On("GetByID", mock.Anything, 1).Return(existingPR, nil).Maybe()
+	mockUser.On("GetByID", mock.Anything, 1).Return(author, nil).Maybe()
+	mockUser.On("GetByID", mock.Anything, 2).Return(oldReviewer, nil).Maybe()
+	mockTeam.On("GetUserTeam", mock.Anything, 1).Return("team1", nil).Maybe()
+	mockTeam.On("GetUserTeam", mock.Anything, 2).Return("team1", nil).Maybe()
+	mockUser.On("GetActiveUsersByTeam", mock.Anything, "team1", 2, mock.Anything).Return(newReviewers, nil).Maybe()
+	mockPR.On("GetOpenReviewCounts", mock.Anything, []int{4, 5}).Return(map[int]int{}, nil).Maybe()
+	mockPR.On("ReassignReviewer", mock.Anything, 1, 2, mock.AnythingOfType("int"), (*int)(nil)).Return(nil).Maybe()
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	pr, err := service.ReassignReviewer(1, 2)
+	pr, err := service.ReassignReviewer(context.Background(), 1, 2)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, pr)
@@ -227,10 +278,10 @@ func TestGetPRsByUserID_WithMockery_Success(t *testing.T) {
 		{ID: 2, Title: "PR2", AuthorID: 1},
 	}
 
-	mockPR.On("GetByUserID", 1).Return(expectedPRs, nil)
+	mockPR.On("GetByUserID", mock.Anything, 1).Return(expectedPRs, nil)
 
 	service := NewPRService(mockPR, mockUser, mockTeam)
-	prs, err := service.GetPRsByUserID(1)
+	prs, err := service.GetPRsByUserID(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.Len(t, prs, 2)
@@ -238,3 +289,26 @@ func TestGetPRsByUserID_WithMockery_Success(t *testing.T) {
 
 	mockPR.AssertExpectations(t)
 }
+
+func TestCreatePR_WithMockery_ContextCanceled(t *testing.T) {
+	mockPR := mocks.NewMockPRRepositoryInterface(t)
+	mockUser := mocks.NewMockUserRepositoryInterface(t)
+	mockTeam := mocks.NewMockTeamRepositoryInterface(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Репозиторий должен получить контекст запроса (или производный от него) и прервать запрос к БД
+	mockUser.On("GetByID", mock.MatchedBy(func(c context.Context) bool {
+		return c.Err() == context.Canceled
+	}), 1).Return(func(ctx context.Context, _ int) (*models.User, error) {
+		return nil, ctx.Err()
+	})
+
+	service := NewPRService(mockPR, mockUser, mockTeam)
+	pr, err := service.CreatePR(ctx, "Test PR", 1, "", nil)
+
+	assert.Nil(t, pr)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockPR.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
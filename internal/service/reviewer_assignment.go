@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// reviewerPick - ревьюверы, подобранные на PR, с объяснением, почему назначен каждый из них
+// и почему остальные участники команд не стали кандидатами
+type reviewerPick struct {
+	Reviews     []models.Review
+	Loads       []models.CandidateLoad
+	Exclusions  []models.ReviewerExclusion
+	Assignments []models.ReviewerAssignment
+	// seed - зерно rng, из которого стратегии выбора берут всю случайность этого назначения
+	seed int64
+	rng  *rand.Rand
+	// preview - назначение только предпросматривается, и стратегии выбора не должны менять свое состояние
+	preview bool
+}
+
+func newReviewerPick(seed int64, preview bool) *reviewerPick {
+	return &reviewerPick{
+		Reviews:     []models.Review{},
+		Loads:       []models.CandidateLoad{},
+		Exclusions:  []models.ReviewerExclusion{},
+		Assignments: []models.ReviewerAssignment{},
+		seed:        seed,
+		rng:         newRand(seed),
+		preview:     preview,
+	}
+}
+
+func (p *reviewerPick) add(review models.Review, assignment models.ReviewerAssignment) {
+	assignment.ReviewerID = review.ReviewerID
+	p.Reviews = append(p.Reviews, review)
+	p.Assignments = append(p.Assignments, assignment)
+}
+
+// exclude добавляет исключенных участников, пропуская уже записанных для той же команды
+func (p *reviewerPick) exclude(exclusions ...models.ReviewerExclusion) {
+	for _, exclusion := range exclusions {
+		duplicate := false
+		for _, existing := range p.Exclusions {
+			if existing.UserID == exclusion.UserID && existing.TeamName == exclusion.TeamName {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			p.Exclusions = append(p.Exclusions, exclusion)
+		}
+	}
+}
+
+// assigned возвращает множество уже подобранных ревьюверов
+func (p *reviewerPick) assigned() map[int]struct{} {
+	assigned := make(map[int]struct{}, len(p.Reviews))
+	for _, review := range p.Reviews {
+		assigned[review.ReviewerID] = struct{}{}
+	}
+	return assigned
+}
+
+// choose выбирает до count ревьюверов стратегией selector. При предпросмотре у стратегий с состоянием
+// вызывается Preview, чтобы предпросмотр не влиял на следующие назначения
+func (p *reviewerPick) choose(selector ReviewerSelector, teamName string, candidates []ReviewerCandidate, count int) []int {
+	if previewer, ok := selector.(ReviewerPreviewer); ok && p.preview {
+		return previewer.Preview(teamName, candidates, count, p.rng)
+	}
+	return selector.Select(teamName, candidates, count, p.rng)
+}
+
+func (p *reviewerPick) explanation() models.AssignmentExplanation {
+	return models.AssignmentExplanation{
+		Seed:           p.seed,
+		CandidateLoads: p.Loads,
+		Exclusions:     p.Exclusions,
+		Assignments:    p.Assignments,
+	}
+}
+
+// pickReviewers подбирает в pick ревьюверов PR автора authorID по настройкам команды teamName: сначала владельцев
+// измененных файлов, затем оставшиеся из RequiredReviewers места - из самой команды, и столько, сколько требует
+// каждый пул ревьюверов, - из команд пулов. Ревьюверы из current, которые по-прежнему доступны, сохраняются
+// на своих местах, остальные места добираются стратегией выбора. При предпросмотре ничего не меняется - ни в базе,
+// ни в состоянии стратегий выбора
+func (s *PRService) pickReviewers(ctx context.Context, pick *reviewerPick, teamName string, authorID int, changedFiles []string, current []models.Review) error {
+	settings, err := loadTeamSettings(ctx, s.teamRepo, teamName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.pickOwners(ctx, pick, teamName, authorID, changedFiles, current); err != nil {
+		return err
+	}
+
+	// Владельцы кода занимают места ревьюверов команды
+	owners := len(pick.Reviews)
+	want := max(settings.RequiredReviewers-owners, 0)
+	minReviewers := max(settings.MinReviewers-owners, 0)
+
+	reviews, err := s.pickFromTeam(ctx, pick, teamName, "", authorID, want, minReviewers, current)
+	if err != nil {
+		return err
+	}
+	for _, review := range reviews {
+		pick.add(review, models.ReviewerAssignment{Source: models.AssignmentSourceTeam, TeamName: teamName})
+	}
+
+	for _, pool := range settings.ReviewerPools {
+		// Ревьювер, уже выбранный владельцем кода, из команды PR или другого пула, не может занять место в этом пуле
+		poolReviews, err := s.pickFromTeam(ctx, pick, pool.TeamName, pool.TeamName, authorID, pool.Reviewers, pool.Reviewers, current)
+		if err != nil {
+			return fmt.Errorf("reviewer pool %s: %w", pool.TeamName, err)
+		}
+		for _, review := range poolReviews {
+			pick.add(review, models.ReviewerAssignment{Source: models.AssignmentSourcePool, TeamName: pool.TeamName})
+		}
+	}
+
+	return nil
+}
+
+// pickOwners назначает по одному ревьюверу на каждое правило владения, которому принадлежат измененные файлы.
+// Правило пропускается, если его владелец - автор, уже назначен или недоступен: владельцы не блокируют создание PR
+func (s *PRService) pickOwners(ctx context.Context, pick *reviewerPick, teamName string, authorID int, changedFiles []string, current []models.Review) error {
+	if s.options.ownership == nil || len(changedFiles) == 0 {
+		return nil
+	}
+
+	rules, err := s.options.ownership.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get ownership rules: %w", err)
+	}
+
+	for _, owned := range matchOwners(rules, changedFiles) {
+		ruleID := owned.Rule.ID
+		assignment := models.ReviewerAssignment{
+			RuleID:   &ruleID,
+			Source:   models.AssignmentSourceOwnership,
+			TeamName: owned.Rule.TeamName,
+			Pattern:  owned.Rule.Pattern,
+			Paths:    owned.Paths,
+		}
+
+		var candidates []ReviewerCandidate
+		if owned.Rule.UserID != nil {
+			candidates, err = s.ownerCandidates(ctx, pick, *owned.Rule.UserID, authorID)
+		} else {
+			candidates, err = s.teamCandidates(ctx, pick, owned.Rule.TeamName, authorID)
+		}
+		if err != nil {
+			return err
+		}
+		pick.Loads = mergeCandidateLoads(pick.Loads, candidateLoads(candidates))
+
+		// Правило уже выполнено, если кто-то из его владельцев назначен по другому правилу
+		assigned := pick.assigned()
+		satisfied := false
+		for _, candidate := range candidates {
+			if _, ok := assigned[candidate.User.ID]; ok {
+				satisfied = true
+				break
+			}
+		}
+		available := withinCapacity(candidates)
+		if satisfied || len(available) == 0 {
+			continue
+		}
+
+		// Ревьюверы не из команды PR заменяются участниками той же команды владельцев
+		poolTeam := owned.Rule.TeamName
+		if poolTeam == teamName {
+			poolTeam = ""
+		}
+
+		if review, ok := keptReview(current, available); ok {
+			review.PoolTeam = poolTeam
+			pick.add(review, assignment)
+			continue
+		}
+
+		selectorTeam := owned.Rule.TeamName
+		if selectorTeam == "" {
+			selectorTeam = teamName
+		}
+		picked := pick.choose(s.options.selectorFor(selectorTeam), selectorTeam, available, 1)
+		if len(picked) == 0 {
+			continue
+		}
+		pick.add(models.Review{ReviewerID: picked[0], State: models.ReviewStatePending, PoolTeam: poolTeam}, assignment)
+	}
+
+	return nil
+}
+
+// ownerCandidates возвращает владельца-пользователя как единственного кандидата, если он активен,
+// не отсутствует и не является автором PR; иначе записывает причину в pick
+func (s *PRService) ownerCandidates(ctx context.Context, pick *reviewerPick, userID, authorID int) ([]ReviewerCandidate, error) {
+	if userID == authorID {
+		pick.exclude(models.ReviewerExclusion{UserID: userID, Reason: models.ExclusionReasonAuthor})
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code owner: %w", err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+	if !user.IsActive {
+		pick.exclude(models.ReviewerExclusion{UserID: userID, Reason: models.ExclusionReasonInactive})
+		return nil, nil
+	}
+
+	now := s.options.now()
+	unavailabilities, err := s.userRepo.GetUnavailabilities(ctx, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code owner unavailabilities: %w", err)
+	}
+	for _, unavailability := range unavailabilities {
+		if !unavailability.StartsAt.After(now) && unavailability.EndsAt.After(now) {
+			pick.exclude(models.ReviewerExclusion{UserID: userID, Reason: models.ExclusionReasonOOO})
+			return nil, nil
+		}
+	}
+
+	candidates, err := loadReviewerCandidates(ctx, s.prRepo, []models.User{*user})
+	if err != nil {
+		return nil, err
+	}
+	if !candidates[0].HasCapacity() {
+		pick.exclude(models.ReviewerExclusion{UserID: userID, Reason: models.ExclusionReasonAtCapacity})
+	}
+	return candidates, nil
+}
+
+// teamCandidates возвращает активных участников команды, кроме автора, вместе с их нагрузкой.
+// Причины, по которым остальные участники не стали кандидатами, записываются в pick
+func (s *PRService) teamCandidates(ctx context.Context, pick *reviewerPick, teamName string, authorID int) ([]ReviewerCandidate, error) {
+	members, err := s.userRepo.GetActiveUsersByTeam(ctx, teamName, authorID, s.options.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+	candidates, err := loadReviewerCandidates(ctx, s.prRepo, members)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions, err := s.teamExclusions(ctx, teamName, authorID, members, candidates)
+	if err != nil {
+		return nil, err
+	}
+	pick.exclude(exclusions...)
+	return candidates, nil
+}
+
+// teamExclusions объясняет, почему участники команды teamName не стали кандидатами в ревьюверы.
+// active - результат GetActiveUsersByTeam: участники, которых в нем нет, либо автор, либо неактивны, либо отсутствуют.
+// candidates - кандидаты с нагрузкой, среди которых ищутся достигшие лимита открытых ревью
+func (s *PRService) teamExclusions(ctx context.Context, teamName string, authorID int, active []models.User, candidates []ReviewerCandidate) ([]models.ReviewerExclusion, error) {
+	team, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	if team == nil {
+		return nil, nil
+	}
+
+	activeIDs := make(map[int]struct{}, len(active))
+	for _, user := range active {
+		activeIDs[user.ID] = struct{}{}
+	}
+	hasCapacity := make(map[int]bool, len(candidates))
+	for _, candidate := range candidates {
+		hasCapacity[candidate.User.ID] = candidate.HasCapacity()
+	}
+
+	var exclusions []models.ReviewerExclusion
+	for _, member := range team.Members {
+		var reason models.ExclusionReason
+		_, isActive := activeIDs[member.ID]
+		capacity, isCandidate := hasCapacity[member.ID]
+		switch {
+		case member.ID == authorID:
+			reason = models.ExclusionReasonAuthor
+		case !member.IsActive:
+			reason = models.ExclusionReasonInactive
+		case !isActive:
+			reason = models.ExclusionReasonOOO
+		case isCandidate && !capacity:
+			reason = models.ExclusionReasonAtCapacity
+		default:
+			continue
+		}
+		exclusions = append(exclusions, models.ReviewerExclusion{TeamName: teamName, Reason: reason, UserID: member.ID})
+	}
+	return exclusions, nil
+}
+
+// keptReview возвращает ревью из current, ревьювер которого есть среди available
+func keptReview(current []models.Review, available []ReviewerCandidate) (models.Review, bool) {
+	for _, review := range current {
+		for _, candidate := range available {
+			if candidate.User.ID == review.ReviewerID {
+				return review, true
+			}
+		}
+	}
+	return models.Review{}, false
+}
+
+// pickFromTeam подбирает до want ревьюверов из команды teamName, но не меньше minReviewers, пропуская уже
+// подобранных в pick. poolTeam записывается в ревью выбранных ревьюверов; ревьюверы из current с тем же пулом
+// сохраняются. Нагрузка кандидатов и причины исключения участников команды записываются в pick
+func (s *PRService) pickFromTeam(ctx context.Context, pick *reviewerPick, teamName, poolTeam string, authorID, want, minReviewers int, current []models.Review) ([]models.Review, error) {
+	members, err := s.userRepo.GetActiveUsersByTeam(ctx, teamName, authorID, s.options.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+
+	exclude := pick.assigned()
+	candidates := make([]models.User, 0, len(members))
+	for _, member := range members {
+		if _, skip := exclude[member.ID]; !skip {
+			candidates = append(candidates, member)
+		}
+	}
+
+	if len(candidates) < minReviewers {
+		return nil, ErrInsufficientReviewers
+	}
+
+	reviewerCandidates, err := loadReviewerCandidates(ctx, s.prRepo, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	available := withinCapacity(reviewerCandidates)
+	if len(available) < minReviewers {
+		return nil, ErrReviewersAtCapacity
+	}
+
+	exclusions, err := s.teamExclusions(ctx, teamName, authorID, members, reviewerCandidates)
+	if err != nil {
+		return nil, err
+	}
+	pick.exclude(exclusions...)
+	pick.Loads = mergeCandidateLoads(pick.Loads, candidateLoads(reviewerCandidates))
+
+	keep := make(map[int]models.Review, len(current))
+	for _, review := range current {
+		if review.PoolTeam == poolTeam {
+			keep[review.ReviewerID] = review
+		}
+	}
+
+	reviews := make([]models.Review, 0, want)
+	rest := make([]ReviewerCandidate, 0, len(available))
+	for _, candidate := range available {
+		if review, ok := keep[candidate.User.ID]; ok && len(reviews) < want {
+			reviews = append(reviews, review)
+		} else {
+			rest = append(rest, candidate)
+		}
+	}
+
+	// Назначаем до want ревьюверов. Стратегия выбора может вернуть меньше запрошенного,
+	// поэтому минимум проверяется по фактически выбранным ревьюверам
+	for _, reviewerID := range pick.choose(s.options.selectorFor(teamName), teamName, rest, want-len(reviews)) {
+		reviews = append(reviews, models.Review{ReviewerID: reviewerID, State: models.ReviewStatePending, PoolTeam: poolTeam})
+	}
+	if len(reviews) < minReviewers {
+		return nil, ErrInsufficientReviewers
+	}
+
+	return reviews, nil
+}
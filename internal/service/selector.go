@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+// Названия встроенных стратегий выбора ревьюверов
+const (
+	SelectionStrategyRandom      = "random"
+	SelectionStrategyRoundRobin  = "round-robin"
+	SelectionStrategyLeastLoaded = "least-loaded"
+	SelectionStrategyWeighted    = "weighted"
+)
+
+// ReviewerCandidate описывает кандидата в ревьюверы вместе с количеством открытых PR, которые он уже ревьюит
+type ReviewerCandidate struct {
+	User        models.User
+	OpenReviews int
+}
+
+// HasCapacity сообщает, может ли кандидат взять еще одно ревью с учетом его лимита
+func (c ReviewerCandidate) HasCapacity() bool {
+	return c.User.MaxOpenReviews == nil || c.OpenReviews < *c.User.MaxOpenReviews
+}
+
+// ReviewerSelector определяет стратегию выбора ревьюверов из списка кандидатов.
+// Select возвращает ID не более count выбранных кандидатов. Вся случайность берется из rng:
+// с тем же зерном и теми же кандидатами выбор повторяется.
+type ReviewerSelector interface {
+	Select(teamName string, candidates []ReviewerCandidate, count int, rng *rand.Rand) []int
+}
+
+// ReviewerPreviewer реализуют стратегии, у которых Select меняет внутреннее состояние (например, round-robin).
+// Preview выбирает так же, как Select, но состояние не меняет; используется при предпросмотре назначения
+type ReviewerPreviewer interface {
+	Preview(teamName string, candidates []ReviewerCandidate, count int, rng *rand.Rand) []int
+}
+
+// NewReviewerSelector создает встроенную стратегию по её названию
+func NewReviewerSelector(name string) (ReviewerSelector, error) {
+	switch name {
+	case SelectionStrategyRandom:
+		return NewRandomSelector(), nil
+	case SelectionStrategyRoundRobin:
+		return NewRoundRobinSelector(), nil
+	case SelectionStrategyLeastLoaded:
+		return NewLeastLoadedSelector(), nil
+	case SelectionStrategyWeighted:
+		return NewWeightedSelector(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSelectionStrategy, name)
+	}
+}
+
+// RandomSelector выбирает ревьюверов равновероятно
+type RandomSelector struct{}
+
+func NewRandomSelector() *RandomSelector {
+	return &RandomSelector{}
+}
+
+func (s *RandomSelector) Select(_ string, candidates []ReviewerCandidate, count int, rng *rand.Rand) []int {
+	shuffled := shuffleCandidates(candidates, rng)
+	return candidateIDs(shuffled, count)
+}
+
+// RoundRobinSelector выбирает ревьюверов по кругу (по возрастанию ID) отдельно для каждой команды
+type RoundRobinSelector struct {
+	mu         sync.Mutex
+	lastByTeam map[string]int
+}
+
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{lastByTeam: make(map[string]int)}
+}
+
+func (s *RoundRobinSelector) Select(teamName string, candidates []ReviewerCandidate, count int, _ *rand.Rand) []int {
+	return s.pick(teamName, candidates, count, true)
+}
+
+// Preview возвращает ревьюверов, которых выбрал бы Select, не сдвигая очередь команды
+func (s *RoundRobinSelector) Preview(teamName string, candidates []ReviewerCandidate, count int, _ *rand.Rand) []int {
+	return s.pick(teamName, candidates, count, false)
+}
+
+func (s *RoundRobinSelector) pick(teamName string, candidates []ReviewerCandidate, count int, advance bool) []int {
+	if len(candidates) == 0 || count <= 0 {
+		return []int{}
+	}
+
+	sorted := make([]ReviewerCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].User.ID < sorted[j].User.ID
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Начинаем с первого кандидата, чей ID больше последнего выбранного в этой команде
+	last := s.lastByTeam[teamName]
+	start := sort.Search(len(sorted), func(i int) bool {
+		return sorted[i].User.ID > last
+	})
+
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	reviewers := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		reviewers = append(reviewers, sorted[(start+i)%len(sorted)].User.ID)
+	}
+	if advance {
+		s.lastByTeam[teamName] = reviewers[len(reviewers)-1]
+	}
+
+	return reviewers
+}
+
+// LeastLoadedSelector выбирает ревьюверов с наименьшим количеством открытых ревью,
+// при равной нагрузке выбор случайный
+type LeastLoadedSelector struct{}
+
+func NewLeastLoadedSelector() *LeastLoadedSelector {
+	return &LeastLoadedSelector{}
+}
+
+func (s *LeastLoadedSelector) Select(_ string, candidates []ReviewerCandidate, count int, rng *rand.Rand) []int {
+	shuffled := shuffleCandidates(candidates, rng)
+	sort.SliceStable(shuffled, func(i, j int) bool {
+		return shuffled[i].OpenReviews < shuffled[j].OpenReviews
+	})
+	return candidateIDs(shuffled, count)
+}
+
+// WeightedSelector выбирает ревьюверов случайно с весом, обратно пропорциональным их нагрузке:
+// менее загруженные выбираются чаще, но и загруженные не исключаются полностью
+type WeightedSelector struct{}
+
+func NewWeightedSelector() *WeightedSelector {
+	return &WeightedSelector{}
+}
+
+func (s *WeightedSelector) Select(_ string, candidates []ReviewerCandidate, count int, rng *rand.Rand) []int {
+	remaining := make([]ReviewerCandidate, len(candidates))
+	copy(remaining, candidates)
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].User.ID < remaining[j].User.ID
+	})
+
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	if count < 0 {
+		count = 0
+	}
+	reviewers := make([]int, 0, count)
+	for len(reviewers) < count {
+		total := 0.0
+		for _, candidate := range remaining {
+			total += candidateWeight(candidate)
+		}
+
+		point := rng.Float64() * total
+		picked := len(remaining) - 1
+		for i, candidate := range remaining {
+			point -= candidateWeight(candidate)
+			if point < 0 {
+				picked = i
+				break
+			}
+		}
+
+		reviewers = append(reviewers, remaining[picked].User.ID)
+		remaining = append(remaining[:picked], remaining[picked+1:]...)
+	}
+
+	return reviewers
+}
+
+// loadReviewerCandidates дополняет пользователей их текущей нагрузкой по открытым PR
+func loadReviewerCandidates(ctx context.Context, prRepo repository.PRRepositoryInterface, users []models.User) ([]ReviewerCandidate, error) {
+	userIDs := make([]int, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	loads, err := prRepo.GetOpenReviewCounts(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviewer load: %w", err)
+	}
+
+	candidates := make([]ReviewerCandidate, len(users))
+	for i, user := range users {
+		candidates[i] = ReviewerCandidate{User: user, OpenReviews: loads[user.ID]}
+	}
+	return candidates, nil
+}
+
+// withinCapacity оставляет только кандидатов, не достигших своего лимита открытых ревью
+func withinCapacity(candidates []ReviewerCandidate) []ReviewerCandidate {
+	available := make([]ReviewerCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.HasCapacity() {
+			available = append(available, candidate)
+		}
+	}
+	return available
+}
+
+// candidateLoads возвращает нагрузку кандидатов в виде, пригодном для ответа API, упорядоченную по ID
+func candidateLoads(candidates []ReviewerCandidate) []models.CandidateLoad {
+	loads := make([]models.CandidateLoad, len(candidates))
+	for i, candidate := range candidates {
+		loads[i] = models.CandidateLoad{UserID: candidate.User.ID, OpenReviews: candidate.OpenReviews}
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		return loads[i].UserID < loads[j].UserID
+	})
+	return loads
+}
+
+// mergeCandidateLoads добавляет к loads нагрузку кандидатов из extra, которых в loads еще нет
+func mergeCandidateLoads(loads, extra []models.CandidateLoad) []models.CandidateLoad {
+	seen := make(map[int]struct{}, len(loads))
+	for _, load := range loads {
+		seen[load.UserID] = struct{}{}
+	}
+	for _, load := range extra {
+		if _, ok := seen[load.UserID]; !ok {
+			loads = append(loads, load)
+		}
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		return loads[i].UserID < loads[j].UserID
+	})
+	return loads
+}
+
+// incrementCandidateLoad учитывает только что назначенное ревью в нагрузке кандидата
+func incrementCandidateLoad(candidates []ReviewerCandidate, userID int) {
+	for i := range candidates {
+		if candidates[i].User.ID == userID {
+			candidates[i].OpenReviews++
+			return
+		}
+	}
+}
+
+func candidateWeight(candidate ReviewerCandidate) float64 {
+	return 1 / float64(1+candidate.OpenReviews)
+}
+
+// shuffleCandidates перемешивает копию кандидатов. Кандидаты сначала упорядочиваются по ID,
+// чтобы результат зависел только от rng, а не от порядка, в котором их вернула база
+func shuffleCandidates(candidates []ReviewerCandidate, rng *rand.Rand) []ReviewerCandidate {
+	shuffled := make([]ReviewerCandidate, len(candidates))
+	copy(shuffled, candidates)
+	sort.Slice(shuffled, func(i, j int) bool {
+		return shuffled[i].User.ID < shuffled[j].User.ID
+	})
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+func candidateIDs(candidates []ReviewerCandidate, count int) []int {
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	ids := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		ids = append(ids, candidates[i].User.ID)
+	}
+	return ids
+}
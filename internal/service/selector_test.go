@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+func testCandidates(loads map[int]int) []ReviewerCandidate {
+	candidates := make([]ReviewerCandidate, 0, len(loads))
+	for id := 1; len(candidates) < len(loads); id++ {
+		if load, ok := loads[id]; ok {
+			candidates = append(candidates, ReviewerCandidate{User: models.User{ID: id, IsActive: true}, OpenReviews: load})
+		}
+	}
+	return candidates
+}
+
+func TestNewReviewerSelector_UnknownStrategy(t *testing.T) {
+	_, err := NewReviewerSelector("fastest")
+
+	if !errors.Is(err, ErrUnknownSelectionStrategy) {
+		t.Errorf("expected ErrUnknownSelectionStrategy, got %v", err)
+	}
+}
+
+func TestRandomSelector_ReturnsDistinctReviewers(t *testing.T) {
+	selector := NewRandomSelector()
+	candidates := testCandidates(map[int]int{1: 0, 2: 0, 3: 0})
+
+	reviewers := selector.Select("team1", candidates, 2, newRand(1))
+
+	if len(reviewers) != 2 {
+		t.Fatalf("expected 2 reviewers, got %d", len(reviewers))
+	}
+	if reviewers[0] == reviewers[1] {
+		t.Errorf("expected distinct reviewers, got %v", reviewers)
+	}
+}
+
+func TestRandomSelector_FewerCandidatesThanRequested(t *testing.T) {
+	selector := NewRandomSelector()
+
+	reviewers := selector.Select("team1", testCandidates(map[int]int{1: 0}), 2, newRand(1))
+
+	if len(reviewers) != 1 {
+		t.Errorf("expected 1 reviewer, got %d", len(reviewers))
+	}
+}
+
+func TestRoundRobinSelector_RotatesPerTeam(t *testing.T) {
+	selector := NewRoundRobinSelector()
+	candidates := testCandidates(map[int]int{1: 0, 2: 0, 3: 0})
+
+	first := selector.Select("team1", candidates, 2, newRand(1))
+	second := selector.Select("team1", candidates, 2, newRand(1))
+	otherTeam := selector.Select("team2", candidates, 1, newRand(1))
+
+	if first[0] != 1 || first[1] != 2 {
+		t.Errorf("expected [1 2], got %v", first)
+	}
+	if second[0] != 3 || second[1] != 1 {
+		t.Errorf("expected [3 1], got %v", second)
+	}
+	if otherTeam[0] != 1 {
+		t.Errorf("expected team2 to start from 1, got %v", otherTeam)
+	}
+}
+
+func TestSelectors_SameSeedSameChoice(t *testing.T) {
+	candidates := testCandidates(map[int]int{1: 0, 2: 1, 3: 0, 4: 2, 5: 0})
+	reversed := make([]ReviewerCandidate, len(candidates))
+	for i, candidate := range candidates {
+		reversed[len(candidates)-1-i] = candidate
+	}
+
+	for _, name := range []string{SelectionStrategyRandom, SelectionStrategyLeastLoaded, SelectionStrategyWeighted} {
+		t.Run(name, func(t *testing.T) {
+			selector, err := NewReviewerSelector(name)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			first := selector.Select("team1", candidates, 3, newRand(42))
+			second := selector.Select("team1", reversed, 3, newRand(42))
+
+			for i := range first {
+				if first[i] != second[i] {
+					t.Fatalf("expected the same choice for the same seed regardless of candidate order, got %v and %v", first, second)
+				}
+			}
+		})
+	}
+}
+
+func TestLeastLoadedSelector_PrefersLowestLoad(t *testing.T) {
+	selector := NewLeastLoadedSelector()
+	candidates := testCandidates(map[int]int{1: 5, 2: 0, 3: 2, 4: 1})
+
+	reviewers := selector.Select("team1", candidates, 2, newRand(1))
+
+	if len(reviewers) != 2 || reviewers[0] != 2 || reviewers[1] != 4 {
+		t.Errorf("expected [2 4], got %v", reviewers)
+	}
+}
+
+func TestWeightedSelector_ReturnsDistinctReviewers(t *testing.T) {
+	selector := NewWeightedSelector()
+	candidates := testCandidates(map[int]int{1: 10, 2: 0, 3: 3})
+
+	reviewers := selector.Select("team1", candidates, 3, newRand(1))
+
+	if len(reviewers) != 3 {
+		t.Fatalf("expected 3 reviewers, got %d", len(reviewers))
+	}
+	seen := make(map[int]bool)
+	for _, id := range reviewers {
+		if seen[id] {
+			t.Errorf("reviewer %d selected twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSelectorOptionsFromConfig(t *testing.T) {
+	opts, err := SelectorOptionsFromConfig("least-loaded", "backend=round-robin, frontend=weighted")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	o := newOptions(opts)
+	if _, ok := o.selectorFor("devops").(*LeastLoadedSelector); !ok {
+		t.Errorf("expected least-loaded selector by default, got %T", o.selectorFor("devops"))
+	}
+	if _, ok := o.selectorFor("backend").(*RoundRobinSelector); !ok {
+		t.Errorf("expected round-robin selector for backend, got %T", o.selectorFor("backend"))
+	}
+	if _, ok := o.selectorFor("frontend").(*WeightedSelector); !ok {
+		t.Errorf("expected weighted selector for frontend, got %T", o.selectorFor("frontend"))
+	}
+}
+
+func TestSelectorOptionsFromConfig_Invalid(t *testing.T) {
+	if _, err := SelectorOptionsFromConfig("", "backend"); err == nil {
+		t.Error("expected error for entry without strategy")
+	}
+	if _, err := SelectorOptionsFromConfig("", "backend=unknown"); !errors.Is(err, ErrUnknownSelectionStrategy) {
+		t.Errorf("expected ErrUnknownSelectionStrategy, got %v", err)
+	}
+}
+
+func TestCreatePR_UsesTeamSelector(t *testing.T) {
+	var created *models.PR
+	mockPR := &mockPRRepository{
+		createFunc: func(pr *models.PR) error {
+			created = pr
+			return nil
+		},
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{2: 4, 3: 0, 4: 1}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Author", IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2}, {ID: 3}, {ID: 4}}, nil
+		},
+	}
+
+	service := NewPRService(mockPR, mockUser, &mockTeamRepository{},
+		WithTeamReviewerSelector("team1", NewLeastLoadedSelector()))
+	if _, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(created.Reviewers) != 2 || created.Reviewers[0] != 3 || created.Reviewers[1] != 4 {
+		t.Errorf("expected least loaded reviewers [3 4], got %v", created.Reviewers)
+	}
+}
+
+func TestBulkDeactivateTeam_ReplacesReviewersWithSelector(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 1}, {ID: 2}}}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 1}, {ID: 5}, {ID: 6}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {1, 2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 5, Reviewers: []int{1, 2}}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = replacements
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, mockTeam)
+	if _, err := service.BulkDeactivateTeam(context.Background(), "team1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 replacements, got %d", len(applied))
+	}
+	// Автор (5) и деактивируемые участники не могут стать ревьюверами,
+	// поэтому первый ревьювер заменяется на 6, а второму замены не остается
+	if applied[0].NewReviewerID != 6 {
+		t.Errorf("expected first reviewer to be replaced by 6, got %d", applied[0].NewReviewerID)
+	}
+	if applied[1].NewReviewerID != 0 {
+		t.Errorf("expected second reviewer to be removed without replacement, got %d", applied[1].NewReviewerID)
+	}
+	for _, replacement := range applied {
+		if replacement.Reason != models.ReassignReasonDeactivation {
+			t.Errorf("expected deactivation reason, got %q", replacement.Reason)
+		}
+	}
+}
+
+func TestLeastLoadedSelector_BreaksTiesRandomly(t *testing.T) {
+	selector := NewLeastLoadedSelector()
+	candidates := testCandidates(map[int]int{1: 0, 2: 0, 3: 5})
+
+	rng := newRand(1)
+	picked := make(map[int]int)
+	for i := 0; i < 200; i++ {
+		picked[selector.Select("team1", candidates, 1, rng)[0]]++
+	}
+
+	if picked[3] != 0 {
+		t.Errorf("expected overloaded reviewer never to be picked, got %d times", picked[3])
+	}
+	if picked[1] == 0 || picked[2] == 0 {
+		t.Errorf("expected ties to be broken randomly, got %v", picked)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
@@ -14,8 +15,8 @@ func NewStatsService(prRepo repository.PRRepositoryInterface) *StatsService {
 	return &StatsService{prRepo: prRepo}
 }
 
-func (s *StatsService) GetStats() (*dto.StatsResponse, error) {
-	statsMap, err := s.prRepo.GetStats()
+func (s *StatsService) GetStats(ctx context.Context) (*dto.StatsResponse, error) {
+	statsMap, err := s.prRepo.GetStats(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
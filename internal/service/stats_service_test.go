@@ -1,26 +1,58 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 )
 
 type mockStatsPRRepository struct{}
 
-func (m *mockStatsPRRepository) Create(pr *models.PR) error                        { return nil }
-func (m *mockStatsPRRepository) GetByID(id int) (*models.PR, error)                { return nil, nil }
-func (m *mockStatsPRRepository) GetByUserID(userID int) ([]models.PR, error)       { return nil, nil }
-func (m *mockStatsPRRepository) GetAll() ([]models.PR, error)                      { return nil, nil }
-func (m *mockStatsPRRepository) UpdateStatus(id int, status models.PRStatus) error { return nil }
-func (m *mockStatsPRRepository) ReassignReviewer(prID, oldID, newID int) error     { return nil }
-func (m *mockStatsPRRepository) GetOpenPRsWithReviewers(userIDs []int) (map[int][]int, error) {
+func (m *mockStatsPRRepository) Create(_ context.Context, pr *models.PR) error { return nil }
+func (m *mockStatsPRRepository) GetByID(_ context.Context, id int) (*models.PR, error) {
 	return nil, nil
 }
-func (m *mockStatsPRRepository) BulkReassignReviewers(prMap map[int][]int, team string, excludeIDs []int) (int, error) {
-	return 0, nil
+func (m *mockStatsPRRepository) GetByIDs(_ context.Context, ids []int) ([]models.PR, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) GetByUserID(_ context.Context, userID int) ([]models.PR, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) GetAll(_ context.Context) ([]models.PR, error) { return nil, nil }
+func (m *mockStatsPRRepository) UpdateStatus(_ context.Context, id int, status models.PRStatus, _ time.Time, _ *int) error {
+	return nil
+}
+func (m *mockStatsPRRepository) UpdateStatusWithReviewers(_ context.Context, id int, status models.PRStatus, reviewers []models.Review, explanation models.AssignmentExplanation, _ *int) error {
+	return nil
+}
+func (m *mockStatsPRRepository) GetByExternalRef(_ context.Context, ref models.ExternalRef) (*models.PR, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) GetEvents(_ context.Context, prID int) ([]models.PREvent, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) Merge(_ context.Context, id int, forced bool, _ int, _ time.Time, _ *int) error {
+	return nil
+}
+func (m *mockStatsPRRepository) ReassignReviewer(_ context.Context, prID, oldID, newID int, _ *int) error {
+	return nil
+}
+func (m *mockStatsPRRepository) SetReviewState(_ context.Context, prID, reviewerID int, state models.ReviewState, reviewedAt time.Time) error {
+	return nil
+}
+func (m *mockStatsPRRepository) GetOpenPRsWithReviewers(_ context.Context, userIDs []int) (map[int][]int, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) GetOpenReviewCounts(_ context.Context, userIDs []int) (map[int]int, error) {
+	return nil, nil
+}
+func (m *mockStatsPRRepository) BulkReassignReviewers(_ context.Context, replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+	return []repository.ReviewerReplacement{}, nil
 }
-func (m *mockStatsPRRepository) GetStats() (map[string]int, error) {
+func (m *mockStatsPRRepository) GetStats(_ context.Context) (map[string]int, error) {
 	return map[string]int{
 		"total_users":  10,
 		"active_users": 8,
@@ -35,7 +67,7 @@ func TestGetStats_Success(t *testing.T) {
 	mockStats := &mockStatsPRRepository{}
 
 	service := NewStatsService(mockStats)
-	stats, err := service.GetStats()
+	stats, err := service.GetStats(context.Background())
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
@@ -19,8 +20,11 @@ func NewTeamService(teamRepo repository.TeamRepositoryInterface, userRepo reposi
 	}
 }
 
-func (s *TeamService) CreateTeam(name string) (*models.Team, error) {
-	existing, err := s.teamRepo.GetByName(name)
+func (s *TeamService) CreateTeam(ctx context.Context, name string) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.CreateTeam")
+	defer func() { endSpan(span, err) }()
+
+	existing, err := s.teamRepo.GetByName(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check team existence: %w", err)
 	}
@@ -30,15 +34,18 @@ func (s *TeamService) CreateTeam(name string) (*models.Team, error) {
 
 	team := &models.Team{Name: name}
 
-	if err := s.teamRepo.Create(team); err != nil {
+	if err := s.teamRepo.Create(ctx, team); err != nil {
 		return nil, fmt.Errorf("failed to create team: %w", err)
 	}
 
 	return team, nil
 }
 
-func (s *TeamService) GetTeam(name string) (*models.Team, error) {
-	team, err := s.teamRepo.GetByName(name)
+func (s *TeamService) GetTeam(ctx context.Context, name string) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetTeam")
+	defer func() { endSpan(span, err) }()
+
+	team, err := s.teamRepo.GetByName(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team: %w", err)
 	}
@@ -48,16 +55,22 @@ func (s *TeamService) GetTeam(name string) (*models.Team, error) {
 	return team, nil
 }
 
-func (s *TeamService) GetAllTeams() ([]models.Team, error) {
-	teams, err := s.teamRepo.GetAll()
+func (s *TeamService) GetAllTeams(ctx context.Context) (_ []models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetAllTeams")
+	defer func() { endSpan(span, err) }()
+
+	teams, err := s.teamRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get teams: %w", err)
 	}
 	return teams, nil
 }
 
-func (s *TeamService) AddMember(teamName string, userID int) (*models.Team, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *TeamService) AddMember(ctx context.Context, teamName string, userID int) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.AddMember")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -65,7 +78,7 @@ func (s *TeamService) AddMember(teamName string, userID int) (*models.Team, erro
 		return nil, ErrUserNotFound
 	}
 
-	team, err := s.teamRepo.GetByName(teamName)
+	team, err := s.teamRepo.GetByName(ctx, teamName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team: %w", err)
 	}
@@ -73,11 +86,11 @@ func (s *TeamService) AddMember(teamName string, userID int) (*models.Team, erro
 		return nil, ErrTeamNotFound
 	}
 
-	if err := s.teamRepo.AddMember(teamName, userID); err != nil {
+	if err := s.teamRepo.AddMember(ctx, teamName, userID); err != nil {
 		return nil, fmt.Errorf("failed to add member: %w", err)
 	}
 
-	updatedTeam, err := s.teamRepo.GetByName(teamName)
+	updatedTeam, err := s.teamRepo.GetByName(ctx, teamName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated team: %w", err)
 	}
@@ -85,17 +98,187 @@ func (s *TeamService) AddMember(teamName string, userID int) (*models.Team, erro
 	return updatedTeam, nil
 }
 
-func (s *TeamService) RemoveMember(teamName string, userID int) error {
-	team, err := s.teamRepo.GetByName(teamName)
+func (s *TeamService) RemoveMember(ctx context.Context, teamName string, userID int) (err error) {
+	ctx, span := startSpan(ctx, "TeamService.RemoveMember")
+	defer func() { endSpan(span, err) }()
+
+	team, err := s.teamRepo.GetByName(ctx, teamName)
 	if err != nil {
 		return fmt.Errorf("failed to get team: %w", err)
 	}
 	if team == nil {
 		return ErrTeamNotFound
 	}
-	if err := s.teamRepo.RemoveMember(teamName, userID); err != nil {
+	if err := s.teamRepo.RemoveMember(ctx, teamName, userID); err != nil {
 		return fmt.Errorf("failed to remove member: %w", err)
 	}
 
 	return nil
 }
+
+// GetUserTeams возвращает команды пользователя, основная - первой
+func (s *TeamService) GetUserTeams(ctx context.Context, userID int) (_ []models.TeamMembership, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetUserTeams")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	memberships, err := s.teamRepo.GetUserTeams(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user teams: %w", err)
+	}
+	return memberships, nil
+}
+
+// SetPrimaryTeam делает команду основной для пользователя. Пользователь должен в ней состоять
+func (s *TeamService) SetPrimaryTeam(ctx context.Context, userID int, teamName string) (_ []models.TeamMembership, err error) {
+	ctx, span := startSpan(ctx, "TeamService.SetPrimaryTeam")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	updated, err := s.teamRepo.SetPrimaryTeam(ctx, userID, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set primary team: %w", err)
+	}
+	if !updated {
+		return nil, ErrNotTeamMember
+	}
+
+	return s.GetUserTeams(ctx, userID)
+}
+
+// SetMemberRole меняет роль участника в команде и возвращает команду с обновленным списком лидов
+func (s *TeamService) SetMemberRole(ctx context.Context, teamName string, userID int, role models.TeamRole) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.SetMemberRole")
+	defer func() { endSpan(span, err) }()
+
+	team, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	updated, err := s.teamRepo.SetMemberRole(ctx, teamName, userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set member role: %w", err)
+	}
+	if !updated {
+		return nil, ErrNotTeamMember
+	}
+
+	updatedTeam, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated team: %w", err)
+	}
+	return updatedTeam, nil
+}
+
+// GetMemberRole возвращает роль пользователя в команде или пустую строку, если он в ней не состоит
+func (s *TeamService) GetMemberRole(ctx context.Context, teamName string, userID int) (_ models.TeamRole, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetMemberRole")
+	defer func() { endSpan(span, err) }()
+
+	role, err := s.teamRepo.GetMemberRole(ctx, teamName, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get member role: %w", err)
+	}
+	return role, nil
+}
+
+func (s *TeamService) GetSettings(ctx context.Context, teamName string) (_ *models.TeamSettings, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetSettings")
+	defer func() { endSpan(span, err) }()
+
+	team, err := s.teamRepo.GetByName(ctx, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	return loadTeamSettings(ctx, s.teamRepo, teamName)
+}
+
+// UpdateSettings заменяет настройки команды settings.TeamName вместе с ее пулами ревьюверов
+func (s *TeamService) UpdateSettings(ctx context.Context, settings *models.TeamSettings) (_ *models.TeamSettings, err error) {
+	ctx, span := startSpan(ctx, "TeamService.UpdateSettings")
+	defer func() { endSpan(span, err) }()
+
+	if settings.RequiredReviewers < 1 ||
+		settings.MinReviewers < 0 || settings.MinReviewers > settings.RequiredReviewers ||
+		settings.RequiredApprovals < 0 || settings.RequiredApprovals > settings.MinReviewers {
+		return nil, ErrInvalidTeamSettings
+	}
+
+	team, err := s.teamRepo.GetByName(ctx, settings.TeamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+
+	if settings.ReviewerPools == nil {
+		settings.ReviewerPools = []models.ReviewerPool{}
+	}
+	if err := s.validateReviewerPools(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	if err := s.teamRepo.UpsertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to update team settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// validateReviewerPools проверяет, что каждый пул ссылается на существующую команду, отличную от самой команды, и указан один раз
+func (s *TeamService) validateReviewerPools(ctx context.Context, settings *models.TeamSettings) error {
+	seen := make(map[string]struct{}, len(settings.ReviewerPools))
+	for _, pool := range settings.ReviewerPools {
+		if pool.Reviewers < 1 || pool.TeamName == settings.TeamName {
+			return ErrInvalidReviewerPool
+		}
+		if _, duplicate := seen[pool.TeamName]; duplicate {
+			return ErrInvalidReviewerPool
+		}
+		seen[pool.TeamName] = struct{}{}
+
+		poolTeam, err := s.teamRepo.GetByName(ctx, pool.TeamName)
+		if err != nil {
+			return fmt.Errorf("failed to get pool team: %w", err)
+		}
+		if poolTeam == nil {
+			return fmt.Errorf("%w: team %s not found", ErrInvalidReviewerPool, pool.TeamName)
+		}
+	}
+	return nil
+}
+
+// loadTeamSettings возвращает настройки команды, подставляя значения по умолчанию, если они не заданы
+func loadTeamSettings(ctx context.Context, teamRepo repository.TeamRepositoryInterface, teamName string) (*models.TeamSettings, error) {
+	settings, err := teamRepo.GetSettings(ctx, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team settings: %w", err)
+	}
+	if settings == nil {
+		return models.DefaultTeamSettings(teamName), nil
+	}
+	return settings, nil
+}
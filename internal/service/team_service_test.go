@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -15,7 +16,7 @@ func TestCreateTeam_Success(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	team, err := service.CreateTeam("team1")
+	team, err := service.CreateTeam(context.Background(), "team1")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -36,7 +37,7 @@ func TestCreateTeam_AlreadyExists(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	_, err := service.CreateTeam("team1")
+	_, err := service.CreateTeam(context.Background(), "team1")
 
 	if !errors.Is(err, ErrTeamAlreadyExists) {
 		t.Errorf("expected ErrTeamAlreadyExists, got %v", err)
@@ -57,7 +58,7 @@ func TestGetTeam_Success(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	team, err := service.GetTeam("team1")
+	team, err := service.GetTeam(context.Background(), "team1")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -75,7 +76,7 @@ func TestGetTeam_NotFound(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	_, err := service.GetTeam("nonexistent")
+	_, err := service.GetTeam(context.Background(), "nonexistent")
 
 	if !errors.Is(err, ErrTeamNotFound) {
 		t.Errorf("expected ErrTeamNotFound, got %v", err)
@@ -98,7 +99,7 @@ func TestAddMember_Success(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, mockUser)
-	team, err := service.AddMember("team1", 1)
+	team, err := service.AddMember(context.Background(), "team1", 1)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -116,7 +117,7 @@ func TestAddMember_UserNotFound(t *testing.T) {
 	}
 
 	service := NewTeamService(&mockTeamRepository{}, mockUser)
-	_, err := service.AddMember("team1", 1)
+	_, err := service.AddMember(context.Background(), "team1", 1)
 
 	if !errors.Is(err, ErrUserNotFound) {
 		t.Errorf("expected ErrUserNotFound, got %v", err)
@@ -136,7 +137,7 @@ func TestAddMember_TeamNotFound(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, mockUser)
-	_, err := service.AddMember("nonexistent", 1)
+	_, err := service.AddMember(context.Background(), "nonexistent", 1)
 
 	if !errors.Is(err, ErrTeamNotFound) {
 		t.Errorf("expected ErrTeamNotFound, got %v", err)
@@ -156,7 +157,7 @@ func TestRemoveMember_Success(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	err := service.RemoveMember("team1", 1)
+	err := service.RemoveMember(context.Background(), "team1", 1)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -171,9 +172,221 @@ func TestRemoveMember_TeamNotFound(t *testing.T) {
 	}
 
 	service := NewTeamService(mockTeam, &mockUserRepository{})
-	err := service.RemoveMember("nonexistent", 1)
+	err := service.RemoveMember(context.Background(), "nonexistent", 1)
 
 	if !errors.Is(err, ErrTeamNotFound) {
 		t.Errorf("expected ErrTeamNotFound, got %v", err)
 	}
 }
+
+func TestSetPrimaryTeam_Success(t *testing.T) {
+	var primary string
+	mockTeam := &mockTeamRepository{
+		setPrimaryTeamFunc: func(userID int, teamName string) (bool, error) {
+			primary = teamName
+			return true, nil
+		},
+		getUserTeamsFunc: func(userID int) ([]models.TeamMembership, error) {
+			return []models.TeamMembership{{TeamName: primary, IsPrimary: true}, {TeamName: "backend"}}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+	}
+
+	service := NewTeamService(mockTeam, mockUser)
+	memberships, err := service.SetPrimaryTeam(context.Background(), 1, "platform")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(memberships) != 2 || memberships[0].TeamName != "platform" || !memberships[0].IsPrimary {
+		t.Errorf("expected platform to be primary, got %+v", memberships)
+	}
+}
+
+func TestSetPrimaryTeam_NotMember(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		setPrimaryTeamFunc: func(userID int, teamName string) (bool, error) {
+			return false, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+	}
+
+	service := NewTeamService(mockTeam, mockUser)
+	_, err := service.SetPrimaryTeam(context.Background(), 1, "platform")
+
+	if !errors.Is(err, ErrNotTeamMember) {
+		t.Errorf("expected ErrNotTeamMember, got %v", err)
+	}
+}
+
+func TestSetMemberRole_Success(t *testing.T) {
+	var gotRole models.TeamRole
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			team := &models.Team{Name: name, Members: []models.User{{ID: 1}}, Leads: []int{}}
+			if gotRole == models.TeamRoleLead {
+				team.Leads = []int{1}
+			}
+			return team, nil
+		},
+		setMemberRoleFunc: func(teamName string, userID int, role models.TeamRole) (bool, error) {
+			gotRole = role
+			return true, nil
+		},
+	}
+
+	service := NewTeamService(mockTeam, &mockUserRepository{})
+	team, err := service.SetMemberRole(context.Background(), "backend", 1, models.TeamRoleLead)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(team.Leads) != 1 || team.Leads[0] != 1 {
+		t.Errorf("expected user 1 to be a lead, got %+v", team.Leads)
+	}
+}
+
+func TestSetMemberRole_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		team     *models.Team
+		isMember bool
+		wantErr  error
+	}{
+		{name: "team not found", team: nil, wantErr: ErrTeamNotFound},
+		{name: "not a member", team: &models.Team{Name: "backend"}, isMember: false, wantErr: ErrNotTeamMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTeam := &mockTeamRepository{
+				getByNameFunc: func(name string) (*models.Team, error) {
+					return tt.team, nil
+				},
+				setMemberRoleFunc: func(teamName string, userID int, role models.TeamRole) (bool, error) {
+					return tt.isMember, nil
+				},
+			}
+
+			service := NewTeamService(mockTeam, &mockUserRepository{})
+			_, err := service.SetMemberRole(context.Background(), "backend", 1, models.TeamRoleLead)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetSettings_Defaults(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name}, nil
+		},
+	}
+
+	service := NewTeamService(mockTeam, &mockUserRepository{})
+	settings, err := service.GetSettings(context.Background(), "team1")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if settings.RequiredReviewers != models.DefaultRequiredReviewers || settings.MinReviewers != models.DefaultMinReviewers {
+		t.Errorf("expected default settings, got %+v", settings)
+	}
+}
+
+func TestUpdateSettings_Success(t *testing.T) {
+	var saved *models.TeamSettings
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name}, nil
+		},
+		upsertSettingsFunc: func(settings *models.TeamSettings) error {
+			saved = settings
+			return nil
+		},
+	}
+
+	service := NewTeamService(mockTeam, &mockUserRepository{})
+	settings, err := service.UpdateSettings(context.Background(), &models.TeamSettings{TeamName: "team1", RequiredReviewers: 3, MinReviewers: 2, RequiredApprovals: 2})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved == nil || saved.RequiredReviewers != 3 || saved.MinReviewers != 2 || saved.RequiredApprovals != 2 {
+		t.Errorf("expected settings to be saved, got %+v", saved)
+	}
+	if settings.TeamName != "team1" {
+		t.Errorf("expected team name 'team1', got %s", settings.TeamName)
+	}
+}
+
+func TestUpdateSettings_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.TeamSettings
+	}{
+		{name: "min above required", settings: models.TeamSettings{RequiredReviewers: 1, MinReviewers: 2}},
+		{name: "approvals above required", settings: models.TeamSettings{RequiredReviewers: 2, MinReviewers: 1, RequiredApprovals: 3}},
+		{name: "approvals above min", settings: models.TeamSettings{RequiredReviewers: 3, MinReviewers: 1, RequiredApprovals: 2}},
+		{name: "negative approvals", settings: models.TeamSettings{RequiredReviewers: 2, MinReviewers: 1, RequiredApprovals: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := tt.settings
+			settings.TeamName = "team1"
+
+			service := NewTeamService(&mockTeamRepository{}, &mockUserRepository{})
+			_, err := service.UpdateSettings(context.Background(), &settings)
+
+			if !errors.Is(err, ErrInvalidTeamSettings) {
+				t.Errorf("expected ErrInvalidTeamSettings, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateSettings_InvalidReviewerPool(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			if name == "missing" {
+				return nil, nil
+			}
+			return &models.Team{Name: name}, nil
+		},
+	}
+
+	tests := []struct {
+		name  string
+		pools []models.ReviewerPool
+	}{
+		{name: "own team", pools: []models.ReviewerPool{{TeamName: "backend", Reviewers: 1}}},
+		{name: "duplicate", pools: []models.ReviewerPool{{TeamName: "security", Reviewers: 1}, {TeamName: "security", Reviewers: 2}}},
+		{name: "unknown team", pools: []models.ReviewerPool{{TeamName: "missing", Reviewers: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTeamService(mockTeam, &mockUserRepository{})
+			_, err := service.UpdateSettings(context.Background(), &models.TeamSettings{
+				TeamName:          "backend",
+				RequiredReviewers: 1,
+				MinReviewers:      1,
+				ReviewerPools:     tt.pools,
+			})
+
+			if !errors.Is(err, ErrInvalidReviewerPool) {
+				t.Errorf("expected ErrInvalidReviewerPool, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Rodjolo/pr-reviewer-service/internal/service"
+
+// startSpan начинает спан вызова метода сервиса. Трейсер берется из глобального провайдера при каждом вызове,
+// чтобы спаны попадали в провайдер, установленный после создания сервиса (например, в тестах)
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan завершает спан и отмечает в нем ошибку, которой завершился вызов
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
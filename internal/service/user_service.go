@@ -1,7 +1,13 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/dto"
@@ -12,31 +18,40 @@ type UserService struct {
 	userRepo repository.UserRepositoryInterface
 	prRepo   repository.PRRepositoryInterface
 	teamRepo repository.TeamRepositoryInterface
+	options  options
 }
 
-func NewUserService(userRepo repository.UserRepositoryInterface, prRepo repository.PRRepositoryInterface, teamRepo repository.TeamRepositoryInterface) *UserService {
+func NewUserService(userRepo repository.UserRepositoryInterface, prRepo repository.PRRepositoryInterface, teamRepo repository.TeamRepositoryInterface, opts ...Option) *UserService {
 	return &UserService{
 		userRepo: userRepo,
 		prRepo:   prRepo,
 		teamRepo: teamRepo,
+		options:  newOptions(opts),
 	}
 }
 
-func (s *UserService) CreateUser(name string, isActive bool) (*models.User, error) {
+func (s *UserService) CreateUser(ctx context.Context, name string, isActive bool, maxOpenReviews *int) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.CreateUser")
+	defer func() { endSpan(span, err) }()
+
 	user := &models.User{
-		Name:     name,
-		IsActive: isActive,
+		Name:           name,
+		IsActive:       isActive,
+		MaxOpenReviews: maxOpenReviews,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return user, nil
 }
 
-func (s *UserService) GetUser(id int) (*models.User, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *UserService) GetUser(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetUser")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -46,16 +61,24 @@ func (s *UserService) GetUser(id int) (*models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetAllUsers() ([]models.User, error) {
-	users, err := s.userRepo.GetAll()
+func (s *UserService) GetAllUsers(ctx context.Context) (_ []models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetAllUsers")
+	defer func() { endSpan(span, err) }()
+
+	users, err := s.userRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
 	return users, nil
 }
 
-func (s *UserService) UpdateUser(id int, name *string, isActive *bool) (*models.User, error) {
-	user, err := s.userRepo.GetByID(id)
+// UpdateUser обновляет переданные поля пользователя.
+// Отрицательное значение maxOpenReviews снимает лимит открытых ревью
+func (s *UserService) UpdateUser(ctx context.Context, id int, name *string, isActive *bool, maxOpenReviews *int) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.UpdateUser")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -69,8 +92,16 @@ func (s *UserService) UpdateUser(id int, name *string, isActive *bool) (*models.
 	if isActive != nil {
 		user.IsActive = *isActive
 	}
+	if maxOpenReviews != nil {
+		if *maxOpenReviews < 0 {
+			user.MaxOpenReviews = nil
+		} else {
+			limit := *maxOpenReviews
+			user.MaxOpenReviews = &limit
+		}
+	}
 
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -78,8 +109,11 @@ func (s *UserService) UpdateUser(id int, name *string, isActive *bool) (*models.
 }
 
 // BulkDeactivateTeam deactivates all team members and reassigns their reviewers
-func (s *UserService) BulkDeactivateTeam(teamName string) (*dto.BulkDeactivateTeamResponse, error) {
-	team, err := s.teamRepo.GetByName(teamName)
+func (s *UserService) BulkDeactivateTeam(ctx context.Context, teamName string) (_ *dto.BulkDeactivateTeamResponse, err error) {
+	ctx, span := startSpan(ctx, "UserService.BulkDeactivateTeam")
+	defer func() { endSpan(span, err) }()
+
+	team, err := s.teamRepo.GetByName(ctx, teamName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team: %w", err)
 	}
@@ -98,25 +132,360 @@ func (s *UserService) BulkDeactivateTeam(teamName string) (*dto.BulkDeactivateTe
 		}, nil
 	}
 
-	prReviewerMap, err := s.prRepo.GetOpenPRsWithReviewers(userIDs)
+	prReviewerMap, err := s.prRepo.GetOpenPRsWithReviewers(ctx, userIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get open PRs: %w", err)
 	}
 
-	deactivatedCount, err := s.userRepo.BulkDeactivateByTeam(teamName)
+	deactivatedCount, err := s.userRepo.BulkDeactivateByTeam(ctx, teamName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to deactivate users: %w", err)
 	}
 	reassignedCount := 0
 	if len(prReviewerMap) > 0 {
-		reassignedCount, err = s.prRepo.BulkReassignReviewers(prReviewerMap, teamName, userIDs)
+		replacements, err := s.planReplacements(ctx, teamName, prReviewerMap, userIDs, models.ReassignReasonDeactivation)
+		if err != nil {
+			return nil, err
+		}
+
+		applied, err := s.prRepo.BulkReassignReviewers(ctx, replacements)
 		if err != nil {
 			return nil, fmt.Errorf("failed to reassign reviewers: %w", err)
 		}
+		reassignedCount = len(applied)
+		s.options.observeReplacements(applied)
+		s.options.publishReplacements(ctx, applied)
 	}
 
+	s.options.publish(ctx, models.WebhookEventTeamDeactivated, models.TeamDeactivatedEvent{
+		TeamName:         teamName,
+		DeactivatedUsers: deactivatedCount,
+		ReassignedPRs:    reassignedCount,
+	})
+
 	return &dto.BulkDeactivateTeamResponse{
 		DeactivatedUsers: deactivatedCount,
 		ReassignedPRs:    reassignedCount,
 	}, nil
 }
+
+// planReplacements подбирает замену каждому снимаемому ревьюверу среди активных участников команды PR
+// (или пула, из которого он был назначен), не входящих в excludeUserIDs и не достигших лимита открытых ревью.
+// Для PR без команды используется fallbackTeam, а если нет и ее, ревьюверы такого PR не трогаются.
+// Ревьюверы, уже одобрившие PR, не заменяются. Если подходящих кандидатов нет, ревьювер просто снимается с PR.
+// reason записывается в историю PR
+func (s *UserService) planReplacements(ctx context.Context, fallbackTeam string, prReviewerMap map[int][]int, excludeUserIDs []int, reason models.ReassignReason) ([]repository.ReviewerReplacement, error) {
+	excluded := make(map[int]struct{}, len(excludeUserIDs))
+	for _, userID := range excludeUserIDs {
+		excluded[userID] = struct{}{}
+	}
+
+	prIDs := make([]int, 0, len(prReviewerMap))
+	for prID := range prReviewerMap {
+		prIDs = append(prIDs, prID)
+	}
+	sort.Ints(prIDs)
+
+	prs, err := s.prRepo.GetByIDs(ctx, prIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PRs: %w", err)
+	}
+	prsMap := make(map[int]models.PR, len(prs))
+	for _, pr := range prs {
+		prsMap[pr.ID] = pr
+	}
+
+	// Кандидаты загружаются один раз на команду; нагрузка учитывается во всех командах кандидата
+	candidatesByTeam := make(map[string][]ReviewerCandidate)
+	teamCandidates := func(teamName string) ([]ReviewerCandidate, error) {
+		if candidates, ok := candidatesByTeam[teamName]; ok {
+			return candidates, nil
+		}
+
+		activeUsers, err := s.userRepo.GetActiveUsersByTeam(ctx, teamName, 0, s.options.now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get team members: %w", err)
+		}
+
+		users := make([]models.User, 0, len(activeUsers))
+		for _, user := range activeUsers {
+			if _, skip := excluded[user.ID]; !skip {
+				users = append(users, user)
+			}
+		}
+
+		candidates, err := loadReviewerCandidates(ctx, s.prRepo, users)
+		if err != nil {
+			return nil, err
+		}
+		candidatesByTeam[teamName] = candidates
+		return candidates, nil
+	}
+
+	// Одно зерно на весь план замен: с ним план можно воспроизвести
+	seed := s.options.nextSeed()
+	rng := newRand(seed)
+
+	replacements := make([]repository.ReviewerReplacement, 0)
+	for _, prID := range prIDs {
+		pr := prsMap[prID]
+
+		prTeam := pr.TeamName
+		if prTeam == "" {
+			prTeam = fallbackTeam
+		}
+
+		// Новый ревьювер не должен быть автором или уже назначенным ревьювером
+		busy := map[int]struct{}{pr.AuthorID: {}}
+		for _, reviewerID := range pr.Reviewers {
+			busy[reviewerID] = struct{}{}
+		}
+
+		for _, oldReviewerID := range prReviewerMap[prID] {
+			// Одобрение уже выдано - как и при ручном переназначении, ревьювер со своим вердиктом остается на PR
+			if pr.ReviewState(oldReviewerID) == models.ReviewStateApproved {
+				continue
+			}
+
+			// Ревьювер из пула другой команды заменяется участником того же пула
+			teamName := pr.PoolTeam(oldReviewerID)
+			if teamName == "" {
+				teamName = prTeam
+			}
+			if teamName == "" {
+				// Без команды подобрать замену не из кого, ревью остается за пользователем
+				continue
+			}
+
+			candidates, err := teamCandidates(teamName)
+			if err != nil {
+				return nil, err
+			}
+
+			available := make([]ReviewerCandidate, 0, len(candidates))
+			for _, candidate := range withinCapacity(candidates) {
+				if _, skip := busy[candidate.User.ID]; !skip {
+					available = append(available, candidate)
+				}
+			}
+
+			replacement := repository.ReviewerReplacement{PRID: prID, OldReviewerID: oldReviewerID, Reason: reason, TeamName: teamName, ActorID: actorID(ctx)}
+			if picked := s.options.selectorFor(teamName).Select(teamName, available, 1, rng); len(picked) > 0 {
+				replacement.NewReviewerID = picked[0]
+				busy[picked[0]] = struct{}{}
+				for _, cached := range candidatesByTeam {
+					incrementCandidateLoad(cached, picked[0])
+				}
+			}
+			replacements = append(replacements, replacement)
+		}
+	}
+
+	if len(replacements) > 0 {
+		slog.InfoContext(ctx, "planned reviewer replacements", "count", len(replacements), "reason", reason, "seed", seed)
+	}
+	return replacements, nil
+}
+
+// AddUnavailability добавляет пользователю период отсутствия, в течение которого он не назначается ревьювером
+func (s *UserService) AddUnavailability(ctx context.Context, userID int, startsAt, endsAt time.Time, reason string) (_ *models.Unavailability, err error) {
+	ctx, span := startSpan(ctx, "UserService.AddUnavailability")
+	defer func() { endSpan(span, err) }()
+
+	if !endsAt.After(startsAt) {
+		return nil, ErrInvalidUnavailability
+	}
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	unavailability := &models.Unavailability{
+		UserID:   userID,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Reason:   reason,
+	}
+	if err := s.userRepo.CreateUnavailability(ctx, unavailability); err != nil {
+		return nil, fmt.Errorf("failed to create unavailability: %w", err)
+	}
+
+	return unavailability, nil
+}
+
+// GetUnavailabilities возвращает текущие и будущие периоды отсутствия пользователя
+func (s *UserService) GetUnavailabilities(ctx context.Context, userID int) (_ []models.Unavailability, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetUnavailabilities")
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	unavailabilities, err := s.userRepo.GetUnavailabilities(ctx, userID, s.options.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unavailabilities: %w", err)
+	}
+	return unavailabilities, nil
+}
+
+func (s *UserService) DeleteUnavailability(ctx context.Context, userID int, id int) (err error) {
+	ctx, span := startSpan(ctx, "UserService.DeleteUnavailability")
+	defer func() { endSpan(span, err) }()
+
+	deleted, err := s.userRepo.DeleteUnavailability(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete unavailability: %w", err)
+	}
+	if !deleted {
+		return ErrUnavailabilityNotFound
+	}
+	return nil
+}
+
+// AddIdentity привязывает к пользователю учетную запись во внешней системе (логин VCS, email, чат).
+// Одна учетная запись может принадлежать только одному пользователю
+func (s *UserService) AddIdentity(ctx context.Context, userID int, provider models.IdentityProvider, externalID string) (_ *models.UserIdentity, err error) {
+	ctx, span := startSpan(ctx, "UserService.AddIdentity")
+	defer func() { endSpan(span, err) }()
+
+	provider = normalizeProvider(provider)
+	externalID = strings.TrimSpace(externalID)
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Быстрая проверка без обращения к уникальному индексу; гонку двух привязок закрывает CreateIdentity
+	existing, err := s.userRepo.GetByIdentity(ctx, provider, externalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check identity existence: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrIdentityAlreadyExists
+	}
+
+	identity := &models.UserIdentity{
+		UserID:     userID,
+		Provider:   provider,
+		ExternalID: externalID,
+	}
+	if err := s.userRepo.CreateIdentity(ctx, identity); err != nil {
+		if errors.Is(err, ErrIdentityAlreadyExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (s *UserService) GetIdentities(ctx context.Context, userID int) (_ []models.UserIdentity, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetIdentities")
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	identities, err := s.userRepo.GetIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (s *UserService) DeleteIdentity(ctx context.Context, userID int, id int) (err error) {
+	ctx, span := startSpan(ctx, "UserService.DeleteIdentity")
+	defer func() { endSpan(span, err) }()
+
+	deleted, err := s.userRepo.DeleteIdentity(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	if !deleted {
+		return ErrUserIdentityNotFound
+	}
+	return nil
+}
+
+// GetUserByIdentity находит пользователя по учетной записи во внешней системе
+func (s *UserService) GetUserByIdentity(ctx context.Context, provider models.IdentityProvider, externalID string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetUserByIdentity")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.userRepo.GetByIdentity(ctx, normalizeProvider(provider), strings.TrimSpace(externalID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+	if user == nil {
+		return nil, ErrIdentityNotFound
+	}
+	return user, nil
+}
+
+func normalizeProvider(provider models.IdentityProvider) models.IdentityProvider {
+	return models.IdentityProvider(strings.ToLower(strings.TrimSpace(string(provider))))
+}
+
+// ReassignAwayReviewers переназначает открытые ревью пользователей, у которых к моменту now начался
+// период отсутствия. Каждый период обрабатывается один раз; ошибка по одному пользователю
+// не мешает обработать остальных. Возвращает количество переназначенных ревью
+func (s *UserService) ReassignAwayReviewers(ctx context.Context, now time.Time) (_ int, err error) {
+	ctx, span := startSpan(ctx, "UserService.ReassignAwayReviewers")
+	defer func() { endSpan(span, err) }()
+
+	unavailabilities, err := s.userRepo.GetStartedUnavailabilities(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get started unavailabilities: %w", err)
+	}
+
+	reassignedCount := 0
+	var errs []error
+	for _, unavailability := range unavailabilities {
+		count, err := s.reassignAwayReviewer(ctx, unavailability.UserID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", unavailability.UserID, err))
+			continue
+		}
+		reassignedCount += count
+
+		if err := s.userRepo.MarkUnavailabilityReassigned(ctx, unavailability.ID, now); err != nil {
+			errs = append(errs, fmt.Errorf("failed to mark unavailability %d as reassigned: %w", unavailability.ID, err))
+		}
+	}
+
+	return reassignedCount, errors.Join(errs...)
+}
+
+// reassignAwayReviewer передает открытые ревью отсутствующего пользователя другим участникам его команды
+func (s *UserService) reassignAwayReviewer(ctx context.Context, userID int) (int, error) {
+	prReviewerMap, err := s.prRepo.GetOpenPRsWithReviewers(ctx, []int{userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get open PRs: %w", err)
+	}
+	if len(prReviewerMap) == 0 {
+		return 0, nil
+	}
+
+	// Замена ищется в команде каждого PR; основная команда ревьювера нужна только для PR без команды
+	teamName, err := s.teamRepo.GetUserTeam(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reviewer team: %w", err)
+	}
+
+	replacements, err := s.planReplacements(ctx, teamName, prReviewerMap, []int{userID}, models.ReassignReasonOOO)
+	if err != nil {
+		return 0, err
+	}
+	if len(replacements) == 0 {
+		return 0, nil
+	}
+
+	applied, err := s.prRepo.BulkReassignReviewers(ctx, replacements)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign reviewers: %w", err)
+	}
+	s.options.observeReplacements(applied)
+	s.options.publishReplacements(ctx, applied)
+	return len(applied), nil
+}
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
 )
 
@@ -17,7 +21,7 @@ func TestCreateUser_Success(t *testing.T) {
 	mockTeam := &mockTeamRepository{}
 
 	service := NewUserService(mockUser, mockPR, mockTeam)
-	user, err := service.CreateUser("Test User", true)
+	user, err := service.CreateUser(context.Background(), "Test User", true, nil)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -42,7 +46,7 @@ func TestGetUser_Success(t *testing.T) {
 	}
 
 	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
-	user, err := service.GetUser(1)
+	user, err := service.GetUser(context.Background(), 1)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -60,7 +64,7 @@ func TestGetUser_NotFound(t *testing.T) {
 	}
 
 	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
-	_, err := service.GetUser(1)
+	_, err := service.GetUser(context.Background(), 1)
 
 	if !errors.Is(err, ErrUserNotFound) {
 		t.Errorf("expected ErrUserNotFound, got %v", err)
@@ -77,7 +81,7 @@ func TestUpdateUser_Success(t *testing.T) {
 	}
 
 	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
-	user, err := service.UpdateUser(1, &newName, &isActive)
+	user, err := service.UpdateUser(context.Background(), 1, &newName, &isActive, nil)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -99,7 +103,7 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	}
 
 	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
-	_, err := service.UpdateUser(1, &newName, nil)
+	_, err := service.UpdateUser(context.Background(), 1, &newName, nil, nil)
 
 	if !errors.Is(err, ErrUserNotFound) {
 		t.Errorf("expected ErrUserNotFound, got %v", err)
@@ -129,13 +133,13 @@ func TestBulkDeactivateTeam_Success(t *testing.T) {
 				1: {1, 2},
 			}, nil
 		},
-		bulkReassignReviewersFunc: func(prMap map[int][]int, team string, excludeIDs []int) (int, error) {
-			return 1, nil
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			return replacements[:1], nil
 		},
 	}
 
 	service := NewUserService(mockUser, mockPR, mockTeam)
-	response, err := service.BulkDeactivateTeam("team1")
+	response, err := service.BulkDeactivateTeam(context.Background(), "team1")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -156,7 +160,7 @@ func TestBulkDeactivateTeam_TeamNotFound(t *testing.T) {
 	}
 
 	service := NewUserService(&mockUserRepository{}, &mockPRRepository{}, mockTeam)
-	_, err := service.BulkDeactivateTeam("nonexistent")
+	_, err := service.BulkDeactivateTeam(context.Background(), "nonexistent")
 
 	if !errors.Is(err, ErrTeamNotFound) {
 		t.Errorf("expected ErrTeamNotFound, got %v", err)
@@ -174,7 +178,7 @@ func TestBulkDeactivateTeam_EmptyTeam(t *testing.T) {
 	}
 
 	service := NewUserService(&mockUserRepository{}, &mockPRRepository{}, mockTeam)
-	response, err := service.BulkDeactivateTeam("team1")
+	response, err := service.BulkDeactivateTeam(context.Background(), "team1")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -186,3 +190,532 @@ func TestBulkDeactivateTeam_EmptyTeam(t *testing.T) {
 		t.Errorf("expected 0 reassigned PRs, got %d", response.ReassignedPRs)
 	}
 }
+
+func TestUpdateUser_MaxOpenReviews(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "User", IsActive: true, MaxOpenReviews: intPtr(2)}, nil
+		},
+	}
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+
+	user, err := service.UpdateUser(context.Background(), 1, nil, nil, intPtr(5))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.MaxOpenReviews == nil || *user.MaxOpenReviews != 5 {
+		t.Errorf("expected limit 5, got %v", user.MaxOpenReviews)
+	}
+
+	user, err = service.UpdateUser(context.Background(), 1, nil, nil, intPtr(-1))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.MaxOpenReviews != nil {
+		t.Errorf("expected limit to be removed, got %d", *user.MaxOpenReviews)
+	}
+}
+
+func TestBulkDeactivateTeam_SkipsReviewersAtCapacity(t *testing.T) {
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 1}}}, nil
+		},
+	}
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 5, MaxOpenReviews: intPtr(1)}, {ID: 6, MaxOpenReviews: intPtr(1)}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {1}, 11: {1}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 7, Reviewers: []int{1}}, {ID: 11, AuthorID: 7, Reviewers: []int{1}}}, nil
+		},
+		getOpenReviewCountsFunc: func(userIDs []int) (map[int]int, error) {
+			return map[int]int{5: 1}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = replacements
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, mockTeam)
+	if _, err := service.BulkDeactivateTeam(context.Background(), "team1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 replacements, got %d", len(applied))
+	}
+	// 5 уже на пределе, 6 может взять только одно ревью
+	if applied[0].NewReviewerID != 6 {
+		t.Errorf("expected PR 10 to get reviewer 6, got %d", applied[0].NewReviewerID)
+	}
+	if applied[1].NewReviewerID != 0 {
+		t.Errorf("expected PR 11 to be left without replacement, got %d", applied[1].NewReviewerID)
+	}
+}
+
+func TestAddUnavailability_Success(t *testing.T) {
+	var created *models.Unavailability
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Name: "Alice", IsActive: true}, nil
+		},
+		createUnavailabilityFunc: func(u *models.Unavailability) error {
+			u.ID = 7
+			created = u
+			return nil
+		},
+	}
+	startsAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	unavailability, err := service.AddUnavailability(context.Background(), 1, startsAt, startsAt.Add(14*24*time.Hour), "vacation")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created == nil || unavailability.ID != 7 || unavailability.UserID != 1 || unavailability.Reason != "vacation" {
+		t.Errorf("unexpected unavailability: %+v", unavailability)
+	}
+}
+
+func TestAddUnavailability_InvalidPeriod(t *testing.T) {
+	startsAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	service := NewUserService(&mockUserRepository{}, &mockPRRepository{}, &mockTeamRepository{})
+	_, err := service.AddUnavailability(context.Background(), 1, startsAt, startsAt, "")
+
+	if !errors.Is(err, ErrInvalidUnavailability) {
+		t.Errorf("expected ErrInvalidUnavailability, got %v", err)
+	}
+}
+
+func TestAddUnavailability_UserNotFound(t *testing.T) {
+	startsAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	service := NewUserService(&mockUserRepository{}, &mockPRRepository{}, &mockTeamRepository{})
+	_, err := service.AddUnavailability(context.Background(), 1, startsAt, startsAt.Add(time.Hour), "")
+
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestDeleteUnavailability_NotFound(t *testing.T) {
+	mockUser := &mockUserRepository{
+		deleteUnavailabilityFunc: func(userID int, id int) (bool, error) {
+			return false, nil
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	err := service.DeleteUnavailability(context.Background(), 1, 7)
+
+	if !errors.Is(err, ErrUnavailabilityNotFound) {
+		t.Errorf("expected ErrUnavailabilityNotFound, got %v", err)
+	}
+}
+
+func TestReassignAwayReviewers_ReassignsAndMarksProcessed(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	var marked []int
+	mockUser := &mockUserRepository{
+		getStartedUnavailabilitiesFunc: func(at time.Time) ([]models.Unavailability, error) {
+			return []models.Unavailability{{ID: 7, UserID: 2}, {ID: 8, UserID: 4}}, nil
+		},
+		markUnavailabilityReassignedFunc: func(id int, at time.Time) error {
+			if !at.Equal(now) {
+				t.Errorf("expected unavailability to be marked at %v, got %v", now, at)
+			}
+			marked = append(marked, id)
+			return nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			// Отсутствующие пользователи уже отфильтрованы репозиторием
+			return []models.User{{ID: 1}, {ID: 3}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			if userIDs[0] == 2 {
+				return map[int][]int{10: {2}}, nil
+			}
+			return map[int][]int{}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 1, Reviewers: []int{2}}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = append(applied, replacements...)
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, &mockTeamRepository{})
+	reassigned, err := service.ReassignAwayReviewers(context.Background(), now)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reassigned != 1 {
+		t.Errorf("expected 1 reassigned review, got %d", reassigned)
+	}
+	if len(applied) != 1 || applied[0].OldReviewerID != 2 || applied[0].NewReviewerID != 3 {
+		t.Errorf("expected reviewer 2 to be replaced by 3, got %+v", applied)
+	}
+	if len(applied) == 1 && applied[0].Reason != models.ReassignReasonOOO {
+		t.Errorf("expected ooo reason, got %q", applied[0].Reason)
+	}
+	if len(marked) != 2 {
+		t.Errorf("expected both unavailabilities to be marked, got %v", marked)
+	}
+}
+
+func TestReassignAwayReviewers_UsesPRTeam(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	mockUser := &mockUserRepository{
+		getStartedUnavailabilitiesFunc: func(at time.Time) ([]models.Unavailability, error) {
+			return []models.Unavailability{{ID: 7, UserID: 2}}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			if teamName == "platform" {
+				return []models.User{{ID: 5}}, nil
+			}
+			return []models.User{{ID: 3}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {2}, 11: {2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{
+				{ID: 10, AuthorID: 1, Reviewers: []int{2}, TeamName: "platform"},
+				{ID: 11, AuthorID: 1, Reviewers: []int{2}},
+			}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = append(applied, replacements...)
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, &mockTeamRepository{})
+	if _, err := service.ReassignAwayReviewers(context.Background(), now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 replacements, got %+v", applied)
+	}
+	// PR 10 принадлежит команде platform, PR 11 без команды - основной команде ревьювера
+	if applied[0].PRID != 10 || applied[0].NewReviewerID != 5 {
+		t.Errorf("expected reviewer from platform on PR 10, got %+v", applied[0])
+	}
+	if applied[1].PRID != 11 || applied[1].NewReviewerID != 3 {
+		t.Errorf("expected reviewer from primary team on PR 11, got %+v", applied[1])
+	}
+}
+
+func TestAvailabilityWorker_UsesServiceClock(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	var checkedAt time.Time
+	mockUser := &mockUserRepository{
+		getStartedUnavailabilitiesFunc: func(at time.Time) ([]models.Unavailability, error) {
+			checkedAt = at
+			return nil, nil
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{}, WithClock(func() time.Time { return now }))
+	NewAvailabilityWorker(service, time.Minute).runOnce(context.Background())
+
+	if !checkedAt.Equal(now) {
+		t.Errorf("expected unavailabilities to be checked at %v, got %v", now, checkedAt)
+	}
+}
+
+func TestReassignAwayReviewers_KeepsApprovedReviewer(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	mockUser := &mockUserRepository{
+		getStartedUnavailabilitiesFunc: func(at time.Time) ([]models.Unavailability, error) {
+			return []models.Unavailability{{ID: 7, UserID: 2}}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 1}, {ID: 3}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {2}, 11: {2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{
+				{ID: 10, AuthorID: 1, Reviewers: []int{2}, Reviews: []models.Review{{ReviewerID: 2, State: models.ReviewStateApproved}}},
+				{ID: 11, AuthorID: 1, Reviewers: []int{2}, Reviews: []models.Review{{ReviewerID: 2, State: models.ReviewStateChangesRequested}}},
+			}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = append(applied, replacements...)
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, &mockTeamRepository{})
+	if _, err := service.ReassignAwayReviewers(context.Background(), now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Одобрение на PR 10 сохраняется, запрошенные изменения на PR 11 передаются другому ревьюверу
+	if len(applied) != 1 || applied[0].PRID != 11 || applied[0].NewReviewerID != 3 {
+		t.Errorf("expected only PR 11 to be reassigned, got %+v", applied)
+	}
+}
+
+func TestBulkDeactivateTeam_KeepsApprovedReviewer(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 5}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 2, IsActive: true}}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 1, Reviewers: []int{2}, TeamName: "platform", Reviews: []models.Review{{ReviewerID: 2, State: models.ReviewStateApproved}}}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = append(applied, replacements...)
+			return replacements, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, mockTeam)
+	result, err := service.BulkDeactivateTeam(context.Background(), "backend")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(applied) != 0 || result.ReassignedPRs != 0 {
+		t.Errorf("expected approved reviewer to stay on the PR, got %+v", applied)
+	}
+}
+
+func TestBulkDeactivateTeam_ReplacesPoolReviewerFromPool(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			if teamName == "security" {
+				return []models.User{{ID: 8}}, nil
+			}
+			return []models.User{{ID: 1}, {ID: 4}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {5}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{
+				ID:        10,
+				AuthorID:  1,
+				TeamName:  "backend",
+				Reviewers: []int{4, 5},
+				Reviews:   []models.Review{{ReviewerID: 4}, {ReviewerID: 5, PoolTeam: "security"}},
+			}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = replacements
+			return replacements, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 5}}}, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, mockTeam)
+	if _, err := service.BulkDeactivateTeam(context.Background(), "security-oncall"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(applied) != 1 || applied[0].NewReviewerID != 8 {
+		t.Errorf("expected pool reviewer 5 to be replaced by 8 from security, got %+v", applied)
+	}
+}
+
+func TestBulkDeactivateTeam_RecordsActor(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 3}}, nil
+		},
+	}
+	var applied []repository.ReviewerReplacement
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 1, Reviewers: []int{2}, TeamName: "platform"}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			applied = replacements
+			return replacements, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 2}}}, nil
+		},
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Role: auth.RoleMember, UserID: 7})
+	service := NewUserService(mockUser, mockPR, mockTeam)
+	if _, err := service.BulkDeactivateTeam(ctx, "backend"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(applied) != 1 || applied[0].ActorID == nil || *applied[0].ActorID != 7 {
+		t.Errorf("expected replacement to be recorded with actor 7, got %+v", applied)
+	}
+}
+
+func TestReassignAwayReviewers_ContinuesAfterError(t *testing.T) {
+	var marked []int
+	mockUser := &mockUserRepository{
+		getStartedUnavailabilitiesFunc: func(at time.Time) ([]models.Unavailability, error) {
+			return []models.Unavailability{{ID: 7, UserID: 2}, {ID: 8, UserID: 4}}, nil
+		},
+		markUnavailabilityReassignedFunc: func(id int, at time.Time) error {
+			marked = append(marked, id)
+			return nil
+		},
+	}
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			if userIDs[0] == 2 {
+				return nil, errors.New("database error")
+			}
+			return map[int][]int{}, nil
+		},
+	}
+
+	service := NewUserService(mockUser, mockPR, &mockTeamRepository{})
+	_, err := service.ReassignAwayReviewers(context.Background(), time.Now())
+
+	if err == nil {
+		t.Error("expected error to be reported")
+	}
+	if len(marked) != 1 || marked[0] != 8 {
+		t.Errorf("expected only unavailability 8 to be marked, got %v", marked)
+	}
+}
+
+func TestAddIdentity_NormalizesAndCreates(t *testing.T) {
+	var created *models.UserIdentity
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		createIdentityFunc: func(identity *models.UserIdentity) error {
+			identity.ID = 1
+			created = identity
+			return nil
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	identity, err := service.AddIdentity(context.Background(), 1, " GitHub ", " alice ")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if identity != created {
+		t.Fatal("expected created identity to be returned")
+	}
+	if created.UserID != 1 || created.Provider != models.ProviderGitHub || created.ExternalID != "alice" {
+		t.Errorf("unexpected identity: %+v", created)
+	}
+}
+
+func TestAddIdentity_AlreadyLinked(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getByIdentityFunc: func(provider models.IdentityProvider, externalID string) (*models.User, error) {
+			return &models.User{ID: 2}, nil
+		},
+		createIdentityFunc: func(identity *models.UserIdentity) error {
+			t.Error("expected identity not to be created")
+			return nil
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	_, err := service.AddIdentity(context.Background(), 1, models.ProviderGitHub, "alice")
+
+	if !errors.Is(err, ErrIdentityAlreadyExists) {
+		t.Errorf("expected ErrIdentityAlreadyExists, got %v", err)
+	}
+}
+
+func TestAddIdentity_LinkedConcurrently(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		createIdentityFunc: func(identity *models.UserIdentity) error {
+			return repository.ErrIdentityAlreadyExists
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	_, err := service.AddIdentity(context.Background(), 1, models.ProviderGitHub, "alice")
+
+	if !errors.Is(err, ErrIdentityAlreadyExists) {
+		t.Errorf("expected ErrIdentityAlreadyExists, got %v", err)
+	}
+}
+
+func TestGetUserByIdentity_NotFound(t *testing.T) {
+	service := NewUserService(&mockUserRepository{}, &mockPRRepository{}, &mockTeamRepository{})
+	_, err := service.GetUserByIdentity(context.Background(), models.ProviderSlack, "U123")
+
+	if !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("expected ErrIdentityNotFound, got %v", err)
+	}
+}
+
+func TestDeleteIdentity_NotFound(t *testing.T) {
+	mockUser := &mockUserRepository{
+		deleteIdentityFunc: func(userID int, id int) (bool, error) {
+			return false, nil
+		},
+	}
+
+	service := NewUserService(mockUser, &mockPRRepository{}, &mockTeamRepository{})
+	err := service.DeleteIdentity(context.Background(), 1, 5)
+
+	if !errors.Is(err, ErrUserIdentityNotFound) {
+		t.Errorf("expected ErrUserIdentityNotFound, got %v", err)
+	}
+}
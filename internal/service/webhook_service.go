@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+const (
+	// WebhookSignatureHeader содержит подпись тела запроса: "sha256=" + hex(HMAC-SHA256(secret, body))
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookEventHeader содержит тип события
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookDeliveryHeader содержит ID доставки; при повторных попытках он не меняется
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+
+	// DefaultWebhookTimeout - таймаут одного запроса к подписчику
+	DefaultWebhookTimeout = 10 * time.Second
+	// WebhookMaxAttempts - сколько раз доставка пытается выполниться, прежде чем будет помечена как failed
+	WebhookMaxAttempts = 5
+
+	webhookRetryBaseDelay    = 30 * time.Second
+	webhookRetryMaxDelay     = time.Hour
+	webhookDeliveryBatchSize = 50
+	// webhookDeliveryLease - на сколько откладывается следующая попытка доставки, взятой воркером
+	webhookDeliveryLease = 5 * time.Minute
+)
+
+// WebhookService управляет подписками на вебхуки, ставит события в очередь доставки
+// и доставляет их подписчикам. Реализует EventPublisher
+type WebhookService struct {
+	repo   repository.WebhookRepositoryInterface
+	client *http.Client
+}
+
+// NewWebhookService создает сервис вебхуков. Если client == nil, используется клиент с DefaultWebhookTimeout
+func NewWebhookService(repo repository.WebhookRepositoryInterface, client *http.Client) *WebhookService {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return &WebhookService{
+		repo:   repo,
+		client: client,
+	}
+}
+
+// CreateSubscription создает подписку. Пустой список events означает подписку на все события
+func (s *WebhookService) CreateSubscription(ctx context.Context, url, secret string, events []models.WebhookEventType) (*models.WebhookSubscription, error) {
+	seen := make(map[models.WebhookEventType]struct{}, len(events))
+	filter := make([]models.WebhookEventType, 0, len(events))
+	for _, event := range events {
+		if !event.IsValid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidWebhookEvent, event)
+		}
+		if _, duplicate := seen[event]; duplicate {
+			continue
+		}
+		seen[event] = struct{}{}
+		filter = append(filter, event)
+	}
+
+	sub := &models.WebhookSubscription{
+		URL:    url,
+		Secret: secret,
+		Events: filter,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	sub, err := s.repo.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if sub == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.repo.GetSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int) error {
+	deleted, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки
+func (s *WebhookService) GetDeliveries(ctx context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.GetDeliveries(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Publish ставит событие в очередь доставки для каждой подписки, которая его принимает.
+// Сама доставка выполняется позже через DeliverPending
+func (s *WebhookService) Publish(ctx context.Context, eventType models.WebhookEventType, data interface{}) error {
+	subscriptions, err := s.repo.GetSubscriptionsForEvent(ctx, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{
+		Event:      eventType,
+		OccurredAt: now,
+		Data:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var errs []error
+	for _, sub := range subscriptions {
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  &now,
+		}
+		if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %d: failed to queue delivery: %w", sub.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeliverPending отправляет доставки, время попытки которых наступило к моменту now.
+// Неудачные попытки повторяются с экспоненциальной задержкой, после WebhookMaxAttempts
+// доставка помечается как failed. Возвращает количество успешных доставок
+func (s *WebhookService) DeliverPending(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, now, now.Add(webhookDeliveryLease), webhookDeliveryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	subscriptions := make(map[int]*models.WebhookSubscription)
+	delivered := 0
+	var errs []error
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		sub, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.GetSubscriptionByID(ctx, delivery.SubscriptionID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("delivery %d: failed to get subscription: %w", delivery.ID, err))
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = sub
+		}
+		if sub == nil {
+			// Подписку удалили после того, как доставка была выбрана
+			continue
+		}
+
+		s.attempt(ctx, sub, delivery, now)
+		if delivery.Status == models.WebhookDeliveryDelivered {
+			delivered++
+		}
+
+		if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %d: failed to save result: %w", delivery.ID, err))
+		}
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// attempt выполняет одну попытку доставки и записывает ее результат в delivery
+func (s *WebhookService) attempt(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+
+	status, err := s.send(ctx, sub, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= WebhookMaxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	next := now.Add(webhookRetryDelay(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+// send отправляет подписанный payload и возвращает HTTP-статус ответа.
+// Ответ с кодом вне диапазона 2xx считается ошибкой
+func (s *WebhookService) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(sub.Secret, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload возвращает значение заголовка WebhookSignatureHeader для тела запроса
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay возвращает задержку перед следующей попыткой после attempts неудачных:
+// 30s, 1m, 2m, 4m... но не больше часа
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempts && delay < webhookRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMaxDelay {
+		delay = webhookRetryMaxDelay
+	}
+	return delay
+}
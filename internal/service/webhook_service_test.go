@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
+	"github.com/Rodjolo/pr-reviewer-service/pkg/models"
+)
+
+type mockWebhookRepository struct {
+	createSubscriptionFunc       func(sub *models.WebhookSubscription) error
+	getSubscriptionByIDFunc      func(id int) (*models.WebhookSubscription, error)
+	getSubscriptionsFunc         func() ([]models.WebhookSubscription, error)
+	getSubscriptionsForEventFunc func(eventType models.WebhookEventType) ([]models.WebhookSubscription, error)
+	deleteSubscriptionFunc       func(id int) (bool, error)
+	createDeliveryFunc           func(d *models.WebhookDelivery) error
+	claimDueDeliveriesFunc       func(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	updateDeliveryFunc           func(d *models.WebhookDelivery) error
+	getDeliveriesFunc            func(subscriptionID int) ([]models.WebhookDelivery, error)
+}
+
+func (m *mockWebhookRepository) CreateSubscription(_ context.Context, sub *models.WebhookSubscription) error {
+	if m.createSubscriptionFunc != nil {
+		return m.createSubscriptionFunc(sub)
+	}
+	sub.ID = 1
+	return nil
+}
+
+func (m *mockWebhookRepository) GetSubscriptionByID(_ context.Context, id int) (*models.WebhookSubscription, error) {
+	if m.getSubscriptionByIDFunc != nil {
+		return m.getSubscriptionByIDFunc(id)
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) GetSubscriptions(_ context.Context) ([]models.WebhookSubscription, error) {
+	if m.getSubscriptionsFunc != nil {
+		return m.getSubscriptionsFunc()
+	}
+	return []models.WebhookSubscription{}, nil
+}
+
+func (m *mockWebhookRepository) GetSubscriptionsForEvent(_ context.Context, eventType models.WebhookEventType) ([]models.WebhookSubscription, error) {
+	if m.getSubscriptionsForEventFunc != nil {
+		return m.getSubscriptionsForEventFunc(eventType)
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) DeleteSubscription(_ context.Context, id int) (bool, error) {
+	if m.deleteSubscriptionFunc != nil {
+		return m.deleteSubscriptionFunc(id)
+	}
+	return true, nil
+}
+
+func (m *mockWebhookRepository) CreateDelivery(_ context.Context, d *models.WebhookDelivery) error {
+	if m.createDeliveryFunc != nil {
+		return m.createDeliveryFunc(d)
+	}
+	return nil
+}
+
+func (m *mockWebhookRepository) ClaimDueDeliveries(_ context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	if m.claimDueDeliveriesFunc != nil {
+		return m.claimDueDeliveriesFunc(now, leaseUntil, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) UpdateDelivery(_ context.Context, d *models.WebhookDelivery) error {
+	if m.updateDeliveryFunc != nil {
+		return m.updateDeliveryFunc(d)
+	}
+	return nil
+}
+
+func (m *mockWebhookRepository) GetDeliveries(_ context.Context, subscriptionID int) ([]models.WebhookDelivery, error) {
+	if m.getDeliveriesFunc != nil {
+		return m.getDeliveriesFunc(subscriptionID)
+	}
+	return []models.WebhookDelivery{}, nil
+}
+
+type recordingPublisher struct {
+	events []models.WebhookEventType
+	data   []interface{}
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, eventType models.WebhookEventType, data interface{}) error {
+	p.events = append(p.events, eventType)
+	p.data = append(p.data, data)
+	return nil
+}
+
+func TestCreateSubscription_InvalidEvent(t *testing.T) {
+	service := NewWebhookService(&mockWebhookRepository{}, nil)
+	_, err := service.CreateSubscription(context.Background(), "http://example.com", "secret", []models.WebhookEventType{"pr.deleted"})
+
+	if !errors.Is(err, ErrInvalidWebhookEvent) {
+		t.Errorf("expected ErrInvalidWebhookEvent, got %v", err)
+	}
+}
+
+func TestCreateSubscription_DeduplicatesEvents(t *testing.T) {
+	var saved *models.WebhookSubscription
+	mockRepo := &mockWebhookRepository{
+		createSubscriptionFunc: func(sub *models.WebhookSubscription) error {
+			saved = sub
+			return nil
+		},
+	}
+
+	service := NewWebhookService(mockRepo, nil)
+	_, err := service.CreateSubscription(context.Background(), "http://example.com", "secret", []models.WebhookEventType{
+		models.WebhookEventPRMerged, models.WebhookEventPRCreated, models.WebhookEventPRMerged,
+	})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(saved.Events) != 2 || saved.Events[0] != models.WebhookEventPRMerged || saved.Events[1] != models.WebhookEventPRCreated {
+		t.Errorf("expected deduplicated events, got %v", saved.Events)
+	}
+}
+
+func TestGetDeliveries_SubscriptionNotFound(t *testing.T) {
+	service := NewWebhookService(&mockWebhookRepository{}, nil)
+	_, err := service.GetDeliveries(context.Background(), 1)
+
+	if !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+func TestPublish_QueuesDeliveryPerSubscription(t *testing.T) {
+	var queued []*models.WebhookDelivery
+	mockRepo := &mockWebhookRepository{
+		getSubscriptionsForEventFunc: func(eventType models.WebhookEventType) ([]models.WebhookSubscription, error) {
+			return []models.WebhookSubscription{{ID: 1}, {ID: 2}}, nil
+		},
+		createDeliveryFunc: func(d *models.WebhookDelivery) error {
+			queued = append(queued, d)
+			return nil
+		},
+	}
+
+	service := NewWebhookService(mockRepo, nil)
+	err := service.Publish(context.Background(), models.WebhookEventPRMerged, &models.PR{ID: 7, Status: models.PRStatusMerged})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(queued))
+	}
+	for i, delivery := range queued {
+		if delivery.SubscriptionID != i+1 || delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt == nil {
+			t.Errorf("unexpected delivery: %+v", delivery)
+		}
+	}
+
+	var payload struct {
+		Event models.WebhookEventType `json:"event"`
+		Data  models.PR               `json:"data"`
+	}
+	if err := json.Unmarshal(queued[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload.Event != models.WebhookEventPRMerged || payload.Data.ID != 7 {
+		t.Errorf("unexpected payload: %s", queued[0].Payload)
+	}
+}
+
+func TestDeliverPending_SignsAndDelivers(t *testing.T) {
+	const secret = "0123456789abcdef"
+	payload := []byte(`{"event":"pr.created","data":{}}`)
+
+	var received *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	var updated *models.WebhookDelivery
+	mockRepo := &mockWebhookRepository{
+		claimDueDeliveriesFunc: func(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+			return []models.WebhookDelivery{{ID: 42, SubscriptionID: 1, EventType: models.WebhookEventPRCreated, Payload: payload}}, nil
+		},
+		getSubscriptionByIDFunc: func(id int) (*models.WebhookSubscription, error) {
+			return &models.WebhookSubscription{ID: id, URL: receiver.URL, Secret: secret}, nil
+		},
+		updateDeliveryFunc: func(d *models.WebhookDelivery) error {
+			updated = d
+			return nil
+		},
+	}
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	service := NewWebhookService(mockRepo, nil)
+	delivered, err := service.DeliverPending(context.Background(), now)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if delivered != 1 {
+		t.Errorf("expected 1 delivery, got %d", delivered)
+	}
+	if received == nil {
+		t.Fatal("expected receiver to be called")
+	}
+	if string(body) != string(payload) {
+		t.Errorf("expected body %s, got %s", payload, body)
+	}
+	if got := received.Header.Get(WebhookSignatureHeader); got != SignWebhookPayload(secret, payload) {
+		t.Errorf("unexpected signature %q", got)
+	}
+	if received.Header.Get(WebhookEventHeader) != string(models.WebhookEventPRCreated) || received.Header.Get(WebhookDeliveryHeader) != "42" {
+		t.Errorf("unexpected headers: %v", received.Header)
+	}
+	if updated.Status != models.WebhookDeliveryDelivered || updated.Attempts != 1 || updated.ResponseStatus != http.StatusNoContent {
+		t.Errorf("unexpected delivery state: %+v", updated)
+	}
+	if updated.DeliveredAt == nil || !updated.DeliveredAt.Equal(now) {
+		t.Errorf("expected delivered_at %v, got %v", now, updated.DeliveredAt)
+	}
+}
+
+func TestDeliverPending_RetriesWithBackoffAndGivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	attempts := 0
+	var updated *models.WebhookDelivery
+	mockRepo := &mockWebhookRepository{
+		claimDueDeliveriesFunc: func(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+			return []models.WebhookDelivery{{ID: 1, SubscriptionID: 1, Attempts: attempts, Status: models.WebhookDeliveryPending}}, nil
+		},
+		getSubscriptionByIDFunc: func(id int) (*models.WebhookSubscription, error) {
+			return &models.WebhookSubscription{ID: id, URL: receiver.URL, Secret: "secret"}, nil
+		},
+		updateDeliveryFunc: func(d *models.WebhookDelivery) error {
+			updated = d
+			return nil
+		},
+	}
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	service := NewWebhookService(mockRepo, nil)
+
+	if _, err := service.DeliverPending(context.Background(), now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Status != models.WebhookDeliveryPending || updated.Attempts != 1 || updated.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("unexpected delivery state after first failure: %+v", updated)
+	}
+	if updated.NextAttemptAt == nil || !updated.NextAttemptAt.Equal(now.Add(30*time.Second)) {
+		t.Errorf("expected retry in 30s, got %v", updated.NextAttemptAt)
+	}
+	if updated.LastError == "" {
+		t.Error("expected last error to be recorded")
+	}
+
+	attempts = WebhookMaxAttempts - 1
+	if _, err := service.DeliverPending(context.Background(), now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Status != models.WebhookDeliveryFailed || updated.NextAttemptAt != nil {
+		t.Errorf("expected delivery to fail after %d attempts, got %+v", WebhookMaxAttempts, updated)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestCreatePR_PublishesEvent(t *testing.T) {
+	mockUser := &mockUserRepository{
+		getByIDFunc: func(id int) (*models.User, error) {
+			return &models.User{ID: id, IsActive: true}, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 2, IsActive: true}, {ID: 3, IsActive: true}}, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getUserTeamFunc: func(userID int) (string, error) {
+			return "backend", nil
+		},
+	}
+
+	publisher := &recordingPublisher{}
+	service := NewPRService(&mockPRRepository{}, mockUser, mockTeam, WithEventPublisher(publisher))
+	pr, err := service.CreatePR(context.Background(), "Test PR", 1, "", nil)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0] != models.WebhookEventPRCreated {
+		t.Fatalf("expected pr.created event, got %v", publisher.events)
+	}
+	if publisher.data[0] != pr {
+		t.Errorf("expected created PR as event data, got %v", publisher.data[0])
+	}
+}
+
+func TestBulkDeactivateTeam_PublishesReassignments(t *testing.T) {
+	mockUser := &mockUserRepository{
+		bulkDeactivateByTeamFunc: func(teamName string) (int, error) {
+			return 1, nil
+		},
+		getActiveUsersByTeamFunc: func(teamName string, excludeUserID int) ([]models.User, error) {
+			return []models.User{{ID: 3}}, nil
+		},
+	}
+	mockPR := &mockPRRepository{
+		getOpenPRsWithReviewersFunc: func(userIDs []int) (map[int][]int, error) {
+			return map[int][]int{10: {2}}, nil
+		},
+		getByIDsFunc: func(ids []int) ([]models.PR, error) {
+			return []models.PR{{ID: 10, AuthorID: 1, Reviewers: []int{2}, TeamName: "platform"}}, nil
+		},
+		bulkReassignReviewersFunc: func(replacements []repository.ReviewerReplacement) ([]repository.ReviewerReplacement, error) {
+			return replacements, nil
+		},
+	}
+	mockTeam := &mockTeamRepository{
+		getByNameFunc: func(name string) (*models.Team, error) {
+			return &models.Team{Name: name, Members: []models.User{{ID: 2}}}, nil
+		},
+	}
+
+	publisher := &recordingPublisher{}
+	service := NewUserService(mockUser, mockPR, mockTeam, WithEventPublisher(publisher))
+	if _, err := service.BulkDeactivateTeam(context.Background(), "backend"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []models.WebhookEventType{models.WebhookEventReviewerReassigned, models.WebhookEventTeamDeactivated}
+	if len(publisher.events) != len(want) || publisher.events[0] != want[0] || publisher.events[1] != want[1] {
+		t.Fatalf("expected events %v, got %v", want, publisher.events)
+	}
+	event, ok := publisher.data[0].(models.ReviewerReassignedEvent)
+	if !ok || event.PRID != 10 || event.OldReviewerID != 2 || event.NewReviewerID != 3 || event.Reason != models.ReassignReasonDeactivation {
+		t.Errorf("unexpected reassignment event %+v", publisher.data[0])
+	}
+}
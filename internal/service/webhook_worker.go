@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWebhookDeliveryInterval - как часто WebhookWorker проверяет очередь доставок
+const DefaultWebhookDeliveryInterval = 5 * time.Second
+
+// WebhookWorker в фоне доставляет события подписчикам вебхуков
+type WebhookWorker struct {
+	webhookService *WebhookService
+	interval       time.Duration
+	running        atomic.Bool
+}
+
+func NewWebhookWorker(webhookService *WebhookService, interval time.Duration) *WebhookWorker {
+	if interval <= 0 {
+		interval = DefaultWebhookDeliveryInterval
+	}
+	return &WebhookWorker{
+		webhookService: webhookService,
+		interval:       interval,
+	}
+}
+
+// Run доставляет события сразу и затем с заданным интервалом, пока не будет отменен ctx.
+// Начатая итерация не прерывается отменой ctx, чтобы при остановке сервера работа не обрывалась на середине
+func (w *WebhookWorker) Run(ctx context.Context) {
+	w.running.Store(true)
+	defer w.running.Store(false)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(context.WithoutCancel(ctx))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running сообщает, выполняется ли Run
+func (w *WebhookWorker) Running() bool {
+	return w.running.Load()
+}
+
+func (w *WebhookWorker) runOnce(ctx context.Context) {
+	if _, err := w.webhookService.DeliverPending(ctx, time.Now()); err != nil {
+		slog.ErrorContext(ctx, "failed to deliver webhooks", "error", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package tracing configures OpenTelemetry tracing for the PR reviewer service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName - имя сервиса в трейсах, если не задана переменная OTEL_SERVICE_NAME
+const ServiceName = "pr-reviewer-service"
+
+// Enabled сообщает, задан ли адрес OTLP-коллектора для трейсов
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup включает экспорт трейсов по OTLP/HTTP, если задан адрес коллектора (см. Enabled). Адрес, заголовки
+// и остальные параметры экспортера читаются из стандартных переменных OTEL_*. Без адреса глобальный провайдер
+// остается no-op и спаны не записываются. Контекст трассировки из входящих заголовков traceparent
+// принимается в обоих случаях. Возвращаемая функция отправляет накопленные спаны и вызывается при остановке
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	// Переменные OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES имеют приоритет над именем по умолчанию
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,8 @@
+-- Настройки назначения ревьюверов для команды
+CREATE TABLE IF NOT EXISTS team_settings (
+    team_name VARCHAR(255) PRIMARY KEY REFERENCES teams(name) ON DELETE CASCADE,
+    required_reviewers INTEGER NOT NULL DEFAULT 2 CHECK (required_reviewers >= 1),
+    min_reviewers INTEGER NOT NULL DEFAULT 2 CHECK (min_reviewers >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (min_reviewers <= required_reviewers)
+);
@@ -0,0 +1,5 @@
+-- Вердикты ревьюверов по PR
+ALTER TABLE pr_reviewers
+    ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (state IN ('PENDING', 'APPROVED', 'CHANGES_REQUESTED')),
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Количество одобрений, необходимое для мержа PR команды
+ALTER TABLE team_settings
+    ADD COLUMN IF NOT EXISTS required_approvals INTEGER NOT NULL DEFAULT 0 CHECK (required_approvals >= 0);
+
+-- Отметка о мерже в обход проверки одобрений
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS force_merged BOOLEAN NOT NULL DEFAULT false;
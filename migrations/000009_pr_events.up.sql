@@ -0,0 +1,16 @@
+-- История изменений PR. Ссылки на пользователей не ограничены внешними ключами,
+-- чтобы история не зависела от дальнейших изменений пользователей
+CREATE TABLE IF NOT EXISTS pr_events (
+    id SERIAL PRIMARY KEY,
+    pr_id INTEGER NOT NULL REFERENCES pull_requests(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,
+    actor_id INTEGER,
+    reviewer_id INTEGER,
+    reason VARCHAR(30) NOT NULL DEFAULT '',
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status VARCHAR(20) NOT NULL DEFAULT '',
+    forced BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_events_pr_id ON pr_events(pr_id, id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_identities;
+DROP INDEX IF EXISTS idx_pull_requests_external;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS external_number;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS external_repo;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS external_provider;
@@ -0,0 +1,21 @@
+-- Ссылка на pull request во внешней системе (GitHub, GitLab). По ней повторные доставки
+-- вебхуков провайдера находят уже созданный PR
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS external_provider VARCHAR(30);
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS external_repo VARCHAR(255);
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS external_number INTEGER;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pull_requests_external
+    ON pull_requests(external_provider, external_repo, external_number)
+    WHERE external_provider IS NOT NULL;
+
+-- Учетные записи пользователей во внешних системах (логин GitHub, GitLab и т.п.)
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(30) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_identities_lookup;
+
+ALTER TABLE user_identities ADD CONSTRAINT user_identities_provider_external_id_key UNIQUE (provider, external_id);
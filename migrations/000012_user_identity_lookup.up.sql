@@ -0,0 +1,4 @@
+-- Логины и email во внешних системах сравниваются без учета регистра
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS user_identities_provider_external_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_lookup ON user_identities(provider, LOWER(external_id));
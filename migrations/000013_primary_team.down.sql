@@ -0,0 +1,3 @@
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS team_name;
+DROP INDEX IF EXISTS idx_team_members_primary;
+ALTER TABLE team_members DROP COLUMN IF EXISTS is_primary;
@@ -0,0 +1,17 @@
+-- Основная команда пользователя: из нее назначаются ревьюверы PR, созданных без явного указания команды
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS is_primary BOOLEAN NOT NULL DEFAULT false;
+
+-- Для существующих пользователей основной становится первая по имени команда
+UPDATE team_members tm
+SET is_primary = true
+WHERE tm.team_name = (SELECT MIN(team_name) FROM team_members WHERE user_id = tm.user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_members_primary ON team_members(user_id) WHERE is_primary;
+
+-- Команда, из которой назначаются ревьюверы PR (в том числе при переназначении)
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS team_name VARCHAR(255) REFERENCES teams(name) ON DELETE SET NULL;
+
+UPDATE pull_requests pr
+SET team_name = tm.team_name
+FROM team_members tm
+WHERE tm.user_id = pr.author_id AND tm.is_primary;
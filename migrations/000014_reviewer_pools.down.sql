@@ -0,0 +1,3 @@
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS pool_team;
+
+DROP TABLE IF EXISTS team_reviewer_pools;
@@ -0,0 +1,11 @@
+-- Пулы ревьюверов: сколько ревьюверов из другой команды назначать на каждый PR команды
+CREATE TABLE IF NOT EXISTS team_reviewer_pools (
+    team_name VARCHAR(255) NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
+    pool_team VARCHAR(255) NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
+    reviewers INTEGER NOT NULL CHECK (reviewers >= 1),
+    PRIMARY KEY (team_name, pool_team),
+    CHECK (team_name <> pool_team)
+);
+
+-- Пул, из которого назначен ревьювер; NULL - команда PR
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS pool_team VARCHAR(255) REFERENCES teams(name) ON DELETE SET NULL;
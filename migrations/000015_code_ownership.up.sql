@@ -0,0 +1,12 @@
+-- Правила владения кодом в стиле CODEOWNERS: файлы по шаблону принадлежат команде или пользователю
+CREATE TABLE IF NOT EXISTS ownership_rules (
+    id SERIAL PRIMARY KEY,
+    pattern VARCHAR(500) NOT NULL,
+    team_name VARCHAR(255) REFERENCES teams(name) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((team_name IS NULL) <> (user_id IS NULL))
+);
+
+-- Измененные файлы PR: по ним подбираются владельцы кода
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS changed_files TEXT[] NOT NULL DEFAULT '{}';
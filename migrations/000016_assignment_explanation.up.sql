@@ -0,0 +1,2 @@
+-- Объяснение последнего автоматического назначения ревьюверов: кандидаты, исключенные участники и правила назначения
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS assignment_explanation JSONB;
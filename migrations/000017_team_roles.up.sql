@@ -0,0 +1,3 @@
+-- Роль участника в команде: лид (lead) управляет составом и настройками команды
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('lead', 'member'));
@@ -1,12 +1,30 @@
 // Package dto provides data transfer objects for API requests and responses.
 package dto
 
+import "time"
+
 // PR Requests
 
 // CreatePRRequest represents the request body for creating a new Pull Request.
+// Draft PRs are created without reviewers; they are assigned once the PR is marked ready.
+// TeamName picks which of the author's teams reviewers come from; omit it to use the author's primary team.
+// ChangedFiles are matched against the ownership rules; owners of the touched paths are assigned first.
 type CreatePRRequest struct {
-	Title    string `json:"title" validate:"required,min=1,max=500" example:"Add new feature"`
-	AuthorID int    `json:"author_id" validate:"required,gt=0" example:"1"`
+	Title        string   `json:"title" validate:"required,min=1,max=500" example:"Add new feature"`
+	TeamName     string   `json:"team_name,omitempty" validate:"omitempty,max=255" example:"backend"`
+	ChangedFiles []string `json:"changed_files,omitempty" validate:"omitempty,max=1000,dive,required,max=1024" example:"internal/service/pr_service.go"`
+	AuthorID     int      `json:"author_id" validate:"required,gt=0" example:"1"`
+	Draft        bool     `json:"draft,omitempty" example:"false"`
+}
+
+// PreviewPRRequest represents the request body for a dry run of reviewer assignment.
+// The fields mean the same as in CreatePRRequest. Seed replays the choice made with that seed;
+// omit it to use a fresh one.
+type PreviewPRRequest struct {
+	Seed         *int64   `json:"seed,omitempty" example:"42"`
+	TeamName     string   `json:"team_name,omitempty" validate:"omitempty,max=255" example:"backend"`
+	ChangedFiles []string `json:"changed_files,omitempty" validate:"omitempty,max=1000,dive,required,max=1024" example:"internal/service/pr_service.go"`
+	AuthorID     int      `json:"author_id" validate:"required,gt=0" example:"1"`
 }
 
 // ReassignRequest represents the request body for reassigning a PR reviewer.
@@ -14,18 +32,40 @@ type ReassignRequest struct {
 	OldReviewerID int `json:"old_reviewer_id" validate:"required,gt=0" example:"2"`
 }
 
+// SubmitReviewRequest represents the request body for submitting a reviewer's verdict on a PR.
+type SubmitReviewRequest struct {
+	State      string `json:"state" validate:"required,oneof=APPROVED CHANGES_REQUESTED" example:"APPROVED"`
+	ReviewerID int    `json:"reviewer_id" validate:"required,gt=0" example:"2"`
+}
+
 // User Requests
 
+// SetPrimaryTeamRequest represents the request body for changing a user's primary team.
+type SetPrimaryTeamRequest struct {
+	TeamName string `json:"team_name" validate:"required,min=1,max=255" example:"backend"`
+}
+
 // CreateUserRequest represents the request body for creating a new user.
+// MaxOpenReviews limits how many open PRs the user can review at once; omit it for no limit.
 type CreateUserRequest struct {
-	IsActive *bool  `json:"is_active,omitempty" example:"true"`
-	Name     string `json:"name" validate:"required,min=1,max=100" example:"Alice"`
+	IsActive       *bool  `json:"is_active,omitempty" example:"true"`
+	MaxOpenReviews *int   `json:"max_open_reviews,omitempty" validate:"omitempty,gte=0" example:"5"`
+	Name           string `json:"name" validate:"required,min=1,max=100" example:"Alice"`
 }
 
 // UpdateUserRequest represents the request body for updating a user.
+// Setting MaxOpenReviews to -1 removes the user's open review limit.
 type UpdateUserRequest struct {
-	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"Alice Updated"`
-	IsActive *bool   `json:"is_active,omitempty" example:"false"`
+	Name           *string `json:"name,omitempty" validate:"omitempty,min=1,max=100" example:"Alice Updated"`
+	IsActive       *bool   `json:"is_active,omitempty" example:"false"`
+	MaxOpenReviews *int    `json:"max_open_reviews,omitempty" validate:"omitempty,gte=-1" example:"3"`
+}
+
+// CreateUnavailabilityRequest represents the request body for adding a user's out-of-office period.
+type CreateUnavailabilityRequest struct {
+	StartsAt time.Time `json:"starts_at" validate:"required" example:"2025-07-01T00:00:00Z"`
+	EndsAt   time.Time `json:"ends_at" validate:"required,gtfield=StartsAt" example:"2025-07-15T00:00:00Z"`
+	Reason   string    `json:"reason,omitempty" validate:"max=255" example:"vacation"`
 }
 
 // Team Requests
@@ -39,3 +79,46 @@ type CreateTeamRequest struct {
 type AddMemberRequest struct {
 	UserID int `json:"user_id" validate:"required,gt=0" example:"1"`
 }
+
+// UpdateTeamSettingsRequest represents the request body for updating team reviewer settings.
+// When MinReviewers is omitted it defaults to RequiredReviewers, i.e. partial assignment is not allowed.
+// When RequiredApprovals is omitted PRs can be merged without approvals.
+// ReviewerPools replaces the team's reviewer pools; omitting it removes them.
+type UpdateTeamSettingsRequest struct {
+	MinReviewers      *int                  `json:"min_reviewers,omitempty" validate:"omitempty,gte=0,ltefield=RequiredReviewers" example:"1"`
+	RequiredApprovals *int                  `json:"required_approvals,omitempty" validate:"omitempty,gte=0,ltefield=RequiredReviewers" example:"1"`
+	ReviewerPools     []ReviewerPoolRequest `json:"reviewer_pools,omitempty" validate:"omitempty,max=5,dive"`
+	RequiredReviewers int                   `json:"required_reviewers" validate:"required,gte=1,lte=10" example:"2"`
+}
+
+// ReviewerPoolRequest requires Reviewers reviewers from team TeamName on every PR of the team.
+type ReviewerPoolRequest struct {
+	TeamName  string `json:"team_name" validate:"required,min=1,max=255" example:"security"`
+	Reviewers int    `json:"reviewers" validate:"required,gte=1,lte=10" example:"1"`
+}
+
+// CreateIdentityRequest represents the request body for linking a user to an external account.
+// Provider is e.g. "github", "gitlab", "email" or "slack"; ExternalID is the login, address or member ID in that system.
+type CreateIdentityRequest struct {
+	Provider   string `json:"provider" validate:"required,min=1,max=30" example:"github"`
+	ExternalID string `json:"external_id" validate:"required,min=1,max=255" example:"alice"`
+}
+
+// Webhook Requests
+
+// CreateWebhookRequest represents the request body for subscribing to webhook events.
+// Secret signs every delivery (HMAC-SHA256 in the X-Webhook-Signature header).
+// Omit Events to receive all event types.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,http_url,max=2048" example:"https://chat.example.com/hooks/reviews"`
+	Secret string   `json:"secret" validate:"required,min=16,max=255" example:"0123456789abcdef"`
+	Events []string `json:"events,omitempty" validate:"omitempty,dive,oneof=pr.created pr.reviewer_reassigned team.deactivated pr.merged" example:"pr.created,pr.merged"`
+}
+
+// CreateOwnershipRuleRequest represents the request body for adding a code ownership rule.
+// Exactly one of TeamName and UserID must be set.
+type CreateOwnershipRuleRequest struct {
+	UserID   *int   `json:"user_id,omitempty" validate:"omitempty,gt=0" example:"1"`
+	Pattern  string `json:"pattern" validate:"required,min=1,max=500" example:"internal/service/**"`
+	TeamName string `json:"team_name,omitempty" validate:"omitempty,max=255" example:"backend"`
+}
@@ -1,8 +1,31 @@
 package dto
 
+import "github.com/Rodjolo/pr-reviewer-service/pkg/models"
+
 // ErrorResponse represents an error response from the API.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code is a stable machine-readable error code, set for errors clients need to tell apart.
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error codes returned in ErrorResponse.Code.
+const (
+	// ErrorCodeInsufficientReviewers means the team has fewer active members than reviewers required.
+	ErrorCodeInsufficientReviewers = "insufficient_reviewers"
+	// ErrorCodeReviewersAtCapacity means there are enough candidates, but all of them reached their open review limit.
+	ErrorCodeReviewersAtCapacity = "reviewers_at_capacity"
+)
+
+// MergeBlockedResponse is returned with 409 Conflict when a PR does not meet its team's merge requirements.
+type MergeBlockedResponse struct {
+	Error              string `json:"error"`
+	PendingReviewers   []int  `json:"pending_reviewers"`
+	ChangesRequestedBy []int  `json:"changes_requested_by"`
+	RequiredApprovals  int    `json:"required_approvals"`
+	Approvals          int    `json:"approvals"`
+	MissingApprovals   int    `json:"missing_approvals"`
 }
 
 // MessageResponse represents a success message response from the API.
@@ -25,3 +48,10 @@ type StatsResponse struct {
 	OpenPRs     int `json:"open_prs"`
 	MergedPRs   int `json:"merged_prs"`
 }
+
+// IntegrationResponse describes how an incoming GitHub/GitLab webhook was applied.
+// Result is one of: created, merged, closed, reopened, ready, unchanged, ignored.
+type IntegrationResponse struct {
+	PR     *models.PR `json:"pr,omitempty"`
+	Result string     `json:"result"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// ExclusionReason tells why a team member was not a reviewer candidate.
+type ExclusionReason string
+
+const (
+	// ExclusionReasonAuthor means the member is the author of the PR.
+	ExclusionReasonAuthor ExclusionReason = "author"
+	// ExclusionReasonInactive means the member is deactivated.
+	ExclusionReasonInactive ExclusionReason = "inactive"
+	// ExclusionReasonOOO means the member is in an unavailability period (vacation, sick leave).
+	ExclusionReasonOOO ExclusionReason = "ooo"
+	// ExclusionReasonAtCapacity means the member already reviews as many open PRs as their limit allows.
+	ExclusionReasonAtCapacity ExclusionReason = "at_capacity"
+)
+
+// ReviewerExclusion records a member of TeamName who was left out of reviewer selection.
+// TeamName is empty for code owners that are single users.
+type ReviewerExclusion struct {
+	TeamName string          `json:"team_name,omitempty"`
+	Reason   ExclusionReason `json:"reason"`
+	UserID   int             `json:"user_id"`
+}
+
+// AssignmentExplanation explains an automatic reviewer assignment: the candidates that were considered
+// with their load, the members that were left out and why, and the rule each reviewer was assigned by.
+// Seed is the random seed the selection strategies used; with the same seed and candidates the choice repeats.
+type AssignmentExplanation struct {
+	Seed           int64                `json:"seed"`
+	CandidateLoads []CandidateLoad      `json:"candidate_loads"`
+	Exclusions     []ReviewerExclusion  `json:"exclusions"`
+	Assignments    []ReviewerAssignment `json:"assignments"`
+}
+
+// ReviewerPreview is the result of a dry run of reviewer assignment: the reviewers CreatePR would assign
+// right now and why. Nothing is stored; with random strategies the actual choice may differ.
+type ReviewerPreview struct {
+	TeamName  string   `json:"team_name"`
+	Reviewers []int    `json:"reviewers"`
+	Reviews   []Review `json:"reviews"`
+	AssignmentExplanation
+}
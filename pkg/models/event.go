@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// PREventType identifies what happened to a pull request.
+type PREventType string
+
+const (
+	// PREventCreated is recorded when a PR is created.
+	PREventCreated PREventType = "created"
+	// PREventReviewerAssigned is recorded when a reviewer is added to a PR.
+	PREventReviewerAssigned PREventType = "reviewer_assigned"
+	// PREventReviewerRemoved is recorded when a reviewer is taken off a PR.
+	PREventReviewerRemoved PREventType = "reviewer_removed"
+	// PREventStatusChanged is recorded when a PR moves between statuses other than MERGED.
+	PREventStatusChanged PREventType = "status_changed"
+	// PREventMerged is recorded when a PR is merged.
+	PREventMerged PREventType = "merged"
+)
+
+// ReassignReason explains why a reviewer was assigned or removed.
+type ReassignReason string
+
+const (
+	// ReassignReasonManual means the reviewer was replaced via the reassign endpoint.
+	ReassignReasonManual ReassignReason = "manual"
+	// ReassignReasonDeactivation means the reviewer was replaced because their team was deactivated.
+	ReassignReasonDeactivation ReassignReason = "deactivation"
+	// ReassignReasonOOO means the reviewer was replaced because their out-of-office period started.
+	ReassignReasonOOO ReassignReason = "ooo"
+	// ReassignReasonUnavailable means the reviewer was replaced when the PR was reopened
+	// because they were no longer available.
+	ReassignReasonUnavailable ReassignReason = "unavailable"
+)
+
+// PREvent is a single entry of a pull request's history.
+// ActorID is the user who caused the event; it is empty for system actions.
+// Forced is set on merged events when the merge bypassed the approvals check.
+type PREvent struct {
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	ActorID    *int           `json:"actor_id,omitempty" db:"actor_id"`
+	ReviewerID *int           `json:"reviewer_id,omitempty" db:"reviewer_id"`
+	Type       PREventType    `json:"type" db:"type"`
+	Reason     ReassignReason `json:"reason,omitempty" db:"reason"`
+	FromStatus PRStatus       `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   PRStatus       `json:"to_status,omitempty" db:"to_status"`
+	ID         int            `json:"id" db:"id"`
+	PRID       int            `json:"pr_id" db:"pr_id"`
+	Forced     bool           `json:"forced,omitempty" db:"forced"`
+}
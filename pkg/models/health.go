@@ -0,0 +1,24 @@
+package models
+
+// HealthStatus is the state of the service or one of its components.
+type HealthStatus string
+
+const (
+	// HealthStatusUp means the component works and the service can serve requests.
+	HealthStatusUp HealthStatus = "up"
+	// HealthStatusDown means the component does not work; the service should not receive traffic.
+	HealthStatusDown HealthStatus = "down"
+)
+
+// ComponentHealth is the result of checking one dependency of the service.
+type ComponentHealth struct {
+	Status HealthStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// HealthReport is returned by the liveness and readiness endpoints.
+// Status is up only when every component is up.
+type HealthReport struct {
+	Status     HealthStatus               `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// IdentityProvider names an external system that users and pull requests can be linked to.
+// Any lowercase name can be used for identities; the constants below are the ones the service itself understands.
+type IdentityProvider string
+
+const (
+	// ProviderGitHub identifies GitHub logins and repositories.
+	ProviderGitHub IdentityProvider = "github"
+	// ProviderGitLab identifies GitLab usernames and projects.
+	ProviderGitLab IdentityProvider = "gitlab"
+	// ProviderEmail identifies email addresses.
+	ProviderEmail IdentityProvider = "email"
+	// ProviderSlack identifies Slack member IDs.
+	ProviderSlack IdentityProvider = "slack"
+)
+
+// UserIdentity links a user to their account in an external system (VCS login, email, chat handle).
+// ExternalID is unique per provider, compared case-insensitively.
+type UserIdentity struct {
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+	Provider   IdentityProvider `json:"provider" db:"provider"`
+	ExternalID string           `json:"external_id" db:"external_id"`
+	ID         int              `json:"id" db:"id"`
+	UserID     int              `json:"user_id" db:"user_id"`
+}
+
+// ExternalRef links a PR to the pull request it mirrors in a VCS provider.
+// Repo is the full repository path (e.g. "org/service").
+type ExternalRef struct {
+	Provider IdentityProvider `json:"provider" db:"external_provider"`
+	Repo     string           `json:"repo" db:"external_repo"`
+	Number   int              `json:"number" db:"external_number"`
+}
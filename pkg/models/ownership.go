@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// OwnershipRule assigns the files matching Pattern to a team or a single user, like a CODEOWNERS entry.
+// Exactly one of TeamName and UserID is set. Patterns use CODEOWNERS syntax: "*" matches within a path
+// segment, "**" across segments, a pattern without "/" matches a file or directory name at any depth,
+// and a trailing "/" matches everything under a directory. When several rules match a file, the most
+// recently created one wins.
+type OwnershipRule struct {
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	Pattern   string    `json:"pattern" db:"pattern"`
+	TeamName  string    `json:"team_name,omitempty" db:"team_name"`
+	ID        int       `json:"id" db:"id"`
+}
+
+// AssignmentSource tells which rule produced a reviewer.
+type AssignmentSource string
+
+const (
+	// AssignmentSourceOwnership marks a reviewer picked as the owner of changed files.
+	AssignmentSourceOwnership AssignmentSource = "ownership"
+	// AssignmentSourceTeam marks a reviewer picked from the PR's team.
+	AssignmentSourceTeam AssignmentSource = "team"
+	// AssignmentSourcePool marks a reviewer picked from one of the team's reviewer pools.
+	AssignmentSourcePool AssignmentSource = "pool"
+)
+
+// ReviewerAssignment explains why a reviewer was assigned to a PR.
+// For ownership assignments RuleID, Pattern and Paths describe the rule and the changed files it matched;
+// TeamName is the team the reviewer was picked from, empty for rules owned by a single user.
+type ReviewerAssignment struct {
+	RuleID     *int             `json:"rule_id,omitempty"`
+	Source     AssignmentSource `json:"source"`
+	TeamName   string           `json:"team_name,omitempty"`
+	Pattern    string           `json:"pattern,omitempty"`
+	Paths      []string         `json:"paths,omitempty"`
+	ReviewerID int              `json:"reviewer_id"`
+}
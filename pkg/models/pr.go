@@ -1,21 +1,135 @@
 // Package models defines data models for the application.
 package models
 
+import "time"
+
 // PRStatus represents the status of a pull request.
 type PRStatus string
 
 const (
+	// PRStatusDraft indicates that the PR is a work in progress and has no reviewers yet.
+	PRStatusDraft PRStatus = "DRAFT"
 	// PRStatusOpen indicates that the PR is open and awaiting review.
 	PRStatusOpen PRStatus = "OPEN"
+	// PRStatusClosed indicates that the PR was abandoned without merging; it can be reopened.
+	PRStatusClosed PRStatus = "CLOSED"
 	// PRStatusMerged indicates that the PR has been merged.
 	PRStatusMerged PRStatus = "MERGED"
 )
 
+// prStatusTransitions lists the statuses each status can move to. MERGED is final.
+var prStatusTransitions = map[PRStatus][]PRStatus{
+	PRStatusDraft:  {PRStatusOpen, PRStatusClosed},
+	PRStatusOpen:   {PRStatusClosed, PRStatusMerged},
+	PRStatusClosed: {PRStatusOpen},
+}
+
+// CanTransitionTo reports whether a PR in status s can move to status next.
+func (s PRStatus) CanTransitionTo(next PRStatus) bool {
+	for _, allowed := range prStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ReviewState represents a reviewer's verdict on a pull request.
+type ReviewState string
+
+const (
+	// ReviewStatePending indicates that the reviewer has not submitted a verdict yet.
+	ReviewStatePending ReviewState = "PENDING"
+	// ReviewStateApproved indicates that the reviewer approved the PR.
+	ReviewStateApproved ReviewState = "APPROVED"
+	// ReviewStateChangesRequested indicates that the reviewer requested changes.
+	ReviewStateChangesRequested ReviewState = "CHANGES_REQUESTED"
+)
+
+// Review holds the verdict of a single assigned reviewer.
+// PoolTeam is set when the reviewer was picked from a team other than the PR's team (a reviewer pool or
+// the owners of changed files); a replacement for this reviewer is picked from the same team.
+type Review struct {
+	ReviewedAt *time.Time  `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	State      ReviewState `json:"state" db:"state"`
+	PoolTeam   string      `json:"pool_team,omitempty" db:"pool_team"`
+	ReviewerID int         `json:"reviewer_id" db:"reviewer_id"`
+}
+
+// CandidateLoad describes how many open PRs a reviewer candidate was already reviewing
+// at the moment reviewers were assigned.
+type CandidateLoad struct {
+	UserID      int `json:"user_id"`
+	OpenReviews int `json:"open_reviews"`
+}
+
 // PR represents a pull request in the system.
+// ForceMerged is set when the PR was merged bypassing the required approvals check.
+// External is set for PRs created from GitHub/GitLab webhooks.
+// TeamName is the team reviewers are picked from; it is empty only for PRs created before teams were stored on PRs.
+// ChangedFiles are used to pick the owners of the touched paths as reviewers.
+// AssignmentSeed, CandidateLoads, Exclusions and Assignments explain the latest automatic reviewer assignment,
+// made when the PR was created or opened; later replacements are recorded in the PR history instead.
 type PR struct {
-	Title     string   `json:"title" db:"title"`
-	Status    PRStatus `json:"status" db:"status"`
-	Reviewers []int    `json:"reviewers" db:"reviewers"`
-	ID        int      `json:"id" db:"id"`
-	AuthorID  int      `json:"author_id" db:"author_id"`
+	External       *ExternalRef         `json:"external,omitempty"`
+	Title          string               `json:"title" db:"title"`
+	TeamName       string               `json:"team_name,omitempty" db:"team_name"`
+	Status         PRStatus             `json:"status" db:"status"`
+	ChangedFiles   []string             `json:"changed_files,omitempty" db:"changed_files"`
+	Reviewers      []int                `json:"reviewers" db:"reviewers"`
+	Reviews        []Review             `json:"reviews"`
+	AssignmentSeed int64                `json:"assignment_seed,omitempty"`
+	CandidateLoads []CandidateLoad      `json:"candidate_loads,omitempty"`
+	Exclusions     []ReviewerExclusion  `json:"exclusions,omitempty"`
+	Assignments    []ReviewerAssignment `json:"assignments,omitempty"`
+	ID             int                  `json:"id" db:"id"`
+	AuthorID       int                  `json:"author_id" db:"author_id"`
+	ForceMerged    bool                 `json:"force_merged" db:"force_merged"`
+}
+
+// ReviewState returns the verdict of the given reviewer, or PENDING if none was recorded.
+func (pr *PR) ReviewState(reviewerID int) ReviewState {
+	for _, review := range pr.Reviews {
+		if review.ReviewerID == reviewerID {
+			return review.State
+		}
+	}
+	return ReviewStatePending
+}
+
+// PoolTeam returns the reviewer pool team the given reviewer was picked from, or "" for the PR's own team.
+func (pr *PR) PoolTeam(reviewerID int) string {
+	for _, review := range pr.Reviews {
+		if review.ReviewerID == reviewerID {
+			return review.PoolTeam
+		}
+	}
+	return ""
+}
+
+// Explanation returns the stored explanation of the latest automatic reviewer assignment.
+func (pr *PR) Explanation() AssignmentExplanation {
+	return AssignmentExplanation{
+		Seed:           pr.AssignmentSeed,
+		CandidateLoads: pr.CandidateLoads,
+		Exclusions:     pr.Exclusions,
+		Assignments:    pr.Assignments,
+	}
+}
+
+// SetExplanation replaces the explanation of the latest automatic reviewer assignment.
+func (pr *PR) SetExplanation(explanation AssignmentExplanation) {
+	pr.AssignmentSeed = explanation.Seed
+	pr.CandidateLoads = explanation.CandidateLoads
+	pr.Exclusions = explanation.Exclusions
+	pr.Assignments = explanation.Assignments
+}
+
+// ReviewerIDs returns the IDs of the given reviews in order.
+func ReviewerIDs(reviews []Review) []int {
+	ids := make([]int, len(reviews))
+	for i, review := range reviews {
+		ids[i] = review.ReviewerID
+	}
+	return ids
 }
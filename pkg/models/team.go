@@ -1,7 +1,67 @@
 package models
 
 // Team represents a team in the system.
+// Leads lists the IDs of members whose role in the team is TeamRoleLead.
 type Team struct {
 	Name    string `json:"name" db:"name"`
 	Members []User `json:"members"`
+	Leads   []int  `json:"leads"`
+}
+
+// TeamRole is the role of a member within a team.
+type TeamRole string
+
+const (
+	// TeamRoleLead may change the team's members and settings.
+	TeamRoleLead TeamRole = "lead"
+	// TeamRoleMember is the default role of a team member.
+	TeamRoleMember TeamRole = "member"
+)
+
+// TeamMembership describes one of the teams a user belongs to and the user's role in it.
+// Each user who belongs to any team has exactly one primary team; it is used for PRs created without an explicit team.
+type TeamMembership struct {
+	TeamName  string   `json:"team_name" db:"team_name"`
+	Role      TeamRole `json:"role" db:"role"`
+	IsPrimary bool     `json:"is_primary" db:"is_primary"`
+}
+
+const (
+	// DefaultRequiredReviewers is the number of reviewers assigned when a team has no settings.
+	DefaultRequiredReviewers = 2
+	// DefaultMinReviewers is the minimum number of reviewers a PR can be created with when a team has no settings.
+	DefaultMinReviewers = 2
+	// DefaultRequiredApprovals is the number of approvals required to merge a PR when a team has no settings.
+	DefaultRequiredApprovals = 0
+)
+
+// ReviewerPool requires a number of reviewers from another team on every PR of the team that declares it,
+// e.g. one reviewer from the security team.
+type ReviewerPool struct {
+	TeamName  string `json:"team_name" db:"pool_team"`
+	Reviewers int    `json:"reviewers" db:"reviewers"`
+}
+
+// TeamSettings holds per-team reviewer assignment settings.
+// RequiredReviewers is how many reviewers CreatePR tries to assign from the team itself; when fewer candidates
+// are available the PR is still created as long as at least MinReviewers can be assigned.
+// ReviewerPools add reviewers from other teams on top of that; each pool must be filled completely.
+// RequiredApprovals is how many reviewers must approve a PR before it can be merged.
+type TeamSettings struct {
+	TeamName          string         `json:"team_name" db:"team_name"`
+	ReviewerPools     []ReviewerPool `json:"reviewer_pools"`
+	RequiredReviewers int            `json:"required_reviewers" db:"required_reviewers"`
+	MinReviewers      int            `json:"min_reviewers" db:"min_reviewers"`
+	RequiredApprovals int            `json:"required_approvals" db:"required_approvals"`
+}
+
+// DefaultTeamSettings returns the settings used for teams that have not configured their own.
+func DefaultTeamSettings(teamName string) *TeamSettings {
+	return &TeamSettings{
+		TeamName:          teamName,
+		RequiredReviewers: DefaultRequiredReviewers,
+		MinReviewers:      DefaultMinReviewers,
+		RequiredApprovals: DefaultRequiredApprovals,
+		ReviewerPools:     []ReviewerPool{},
+	}
 }
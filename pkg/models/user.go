@@ -1,8 +1,24 @@
 package models
 
+import "time"
+
 // User represents a user in the system.
+// MaxOpenReviews limits how many open PRs the user can review at once; nil means no limit.
 type User struct {
-	Name     string `json:"name" db:"name"`
-	ID       int    `json:"id" db:"id"`
-	IsActive bool   `json:"is_active" db:"is_active"`
+	Name           string `json:"name" db:"name"`
+	MaxOpenReviews *int   `json:"max_open_reviews" db:"max_open_reviews"`
+	ID             int    `json:"id" db:"id"`
+	IsActive       bool   `json:"is_active" db:"is_active"`
+}
+
+// Unavailability is a period when the user is away (vacation, sick leave, etc.)
+// and must not be assigned as a reviewer. ReassignedAt is set once the user's
+// open reviews have been handed over to other team members.
+type Unavailability struct {
+	StartsAt     time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at" db:"ends_at"`
+	ReassignedAt *time.Time `json:"reassigned_at,omitempty" db:"reassigned_at"`
+	Reason       string     `json:"reason" db:"reason"`
+	ID           int        `json:"id" db:"id"`
+	UserID       int        `json:"user_id" db:"user_id"`
 }
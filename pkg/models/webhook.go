@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEventType identifies an event that webhook subscribers can receive.
+type WebhookEventType string
+
+const (
+	// WebhookEventPRCreated is sent when a PR is created. Data is the created PR.
+	WebhookEventPRCreated WebhookEventType = "pr.created"
+	// WebhookEventReviewerReassigned is sent when a reviewer of an open PR is replaced. Data is a ReviewerReassignedEvent.
+	WebhookEventReviewerReassigned WebhookEventType = "pr.reviewer_reassigned"
+	// WebhookEventTeamDeactivated is sent when all members of a team are deactivated. Data is a TeamDeactivatedEvent.
+	WebhookEventTeamDeactivated WebhookEventType = "team.deactivated"
+	// WebhookEventPRMerged is sent when a PR is merged. Data is the merged PR.
+	WebhookEventPRMerged WebhookEventType = "pr.merged"
+)
+
+// WebhookEventTypes lists all event types subscribers can filter on.
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventPRCreated,
+	WebhookEventReviewerReassigned,
+	WebhookEventTeamDeactivated,
+	WebhookEventPRMerged,
+}
+
+// IsValid reports whether t is a known webhook event type.
+func (t WebhookEventType) IsValid() bool {
+	for _, known := range WebhookEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription is an external endpoint that receives signed event notifications.
+// An empty Events list subscribes to all event types. Secret is used to sign payloads and is never returned by the API.
+type WebhookSubscription struct {
+	CreatedAt time.Time          `json:"created_at" db:"created_at"`
+	URL       string             `json:"url" db:"url"`
+	Secret    string             `json:"-" db:"secret"`
+	Events    []WebhookEventType `json:"events" db:"events"`
+	ID        int                `json:"id" db:"id"`
+}
+
+// Accepts reports whether the subscription wants events of the given type.
+func (s *WebhookSubscription) Accepts(eventType WebhookEventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of a single webhook delivery.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending means the delivery has not succeeded yet and will be retried.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered means the receiver answered with a 2xx status.
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed means all delivery attempts have been used up.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event queued for one subscription together with the outcome of its delivery attempts.
+// ResponseStatus is the HTTP status of the last attempt (0 if the receiver could not be reached).
+type WebhookDelivery struct {
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+	EventType      WebhookEventType      `json:"event_type" db:"event_type"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	LastError      string                `json:"last_error,omitempty" db:"last_error"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	ID             int                   `json:"id" db:"id"`
+	SubscriptionID int                   `json:"subscription_id" db:"subscription_id"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty" db:"response_status"`
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers.
+type WebhookPayload struct {
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       interface{}      `json:"data"`
+	Event      WebhookEventType `json:"event"`
+}
+
+// ReviewerReassignedEvent describes a reviewer replacement on an open PR.
+// NewReviewerID is 0 when the old reviewer was removed without a replacement.
+type ReviewerReassignedEvent struct {
+	Reason        ReassignReason `json:"reason"`
+	PRID          int            `json:"pr_id"`
+	OldReviewerID int            `json:"old_reviewer_id"`
+	NewReviewerID int            `json:"new_reviewer_id"`
+}
+
+// TeamDeactivatedEvent describes a bulk team deactivation.
+type TeamDeactivatedEvent struct {
+	TeamName         string `json:"team_name"`
+	DeactivatedUsers int    `json:"deactivated_users"`
+	ReassignedPRs    int    `json:"reassigned_prs"`
+}
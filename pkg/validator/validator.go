@@ -50,6 +50,14 @@ func formatFieldError(fieldError validator.FieldError) string {
 		return fmt.Sprintf("%s must be less than %s", field, fieldError.Param())
 	case "lte":
 		return fmt.Sprintf("%s must be less than or equal to %s", field, fieldError.Param())
+	case "gtfield":
+		return fmt.Sprintf("%s must be after %s", field, strings.ToLower(fieldError.Param()))
+	case "ltefield":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, strings.ToLower(fieldError.Param()))
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	case "http_url":
+		return fmt.Sprintf("%s must be a valid http(s) URL", field)
 	case "email":
 		return fmt.Sprintf("%s must be a valid email address", field)
 	default:
@@ -2,8 +2,10 @@ package e2e
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,8 +16,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Rodjolo/pr-reviewer-service/internal/auth"
 	"github.com/Rodjolo/pr-reviewer-service/internal/database"
 	"github.com/Rodjolo/pr-reviewer-service/internal/handlers"
+	"github.com/Rodjolo/pr-reviewer-service/internal/metrics"
 	"github.com/Rodjolo/pr-reviewer-service/internal/repository"
 	"github.com/Rodjolo/pr-reviewer-service/internal/router"
 	"github.com/Rodjolo/pr-reviewer-service/internal/service"
@@ -137,18 +141,29 @@ func TestMain(m *testing.M) {
 	userRepo := repository.NewUserRepository(testDB.DB)
 	teamRepo := repository.NewTeamRepository(testDB.DB)
 	prRepo := repository.NewPRRepository(testDB.DB)
+	webhookRepo := repository.NewWebhookRepository(testDB.DB)
+	ownershipRepo := repository.NewOwnershipRepository(testDB.DB)
 
 	// Инициализируем сервисы
 	userService := service.NewUserService(userRepo, prRepo, teamRepo)
 	teamService := service.NewTeamService(teamRepo, userRepo)
-	prService := service.NewPRService(prRepo, userRepo, teamRepo)
+	prService := service.NewPRService(prRepo, userRepo, teamRepo, service.WithOwnershipRules(ownershipRepo))
 	statsService := service.NewStatsService(prRepo)
+	webhookService := service.NewWebhookService(webhookRepo, nil)
+	integrationService := service.NewIntegrationService(prService, prRepo, userRepo, "", "")
+	ownershipService := service.NewOwnershipService(ownershipRepo, teamRepo, userRepo)
+	healthService := service.NewHealthService(repository.NewHealthRepository(testDB.DB), database.SchemaVersion, nil)
 
 	// Инициализируем handlers
-	h := handlers.NewHandlers(prService, userService, teamService, statsService)
+	h := handlers.NewHandlers(prService, userService, teamService, statsService, webhookService, integrationService, ownershipService, healthService)
 
 	// Настраиваем роутер
-	r := router.NewRouter(h)
+	// Аутентификация выключена: политики доступа проверяются unit-тестами роутера
+	authenticator, err := auth.NewAuthenticator(auth.Config{})
+	if err != nil {
+		log.Fatalf("Could not create authenticator: %s", err)
+	}
+	r := router.NewRouter(h, router.DefaultRequestTimeout, metrics.New(testDB.DB), authenticator)
 
 	// Создаем тестовый сервер
 	testServer = httptest.NewServer(r)
@@ -381,6 +396,66 @@ func TestAddTeamMember(t *testing.T) {
 	}
 }
 
+// TestPromoteTeamMember назначает участника лидом команды и снимает роль
+func TestPromoteTeamMember(t *testing.T) {
+	cleanupTestData(t)
+
+	userReq := dto.CreateUserRequest{Name: "Alice", IsActive: boolPtr(true)}
+	resp, _ := makeRequest("POST", "/users", userReq)
+	var user models.User
+	json.NewDecoder(resp.Body).Decode(&user)
+	resp.Body.Close()
+
+	resp, _ = makeRequest("POST", "/teams", dto.CreateTeamRequest{Name: "backend"})
+	if resp != nil {
+		resp.Body.Close()
+	}
+	resp, _ = makeRequest("POST", "/teams/backend/members", dto.AddMemberRequest{UserID: user.ID})
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	resp, err := makeRequest("POST", fmt.Sprintf("/teams/backend/members/%d/promote", user.ID), nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, string(body))
+	}
+	var team models.Team
+	if err := json.NewDecoder(resp.Body).Decode(&team); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+	if len(team.Leads) != 1 || team.Leads[0] != user.ID {
+		t.Errorf("Expected user %d to be the only lead, got %v", user.ID, team.Leads)
+	}
+
+	resp, err = makeRequest("POST", fmt.Sprintf("/teams/backend/members/%d/demote", user.ID), nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&team); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(team.Leads) != 0 {
+		t.Errorf("Expected no leads after demotion, got %v", team.Leads)
+	}
+
+	// Роль можно выдать только участнику команды
+	resp, err = makeRequest("POST", "/teams/backend/members/999999/promote", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a non-member, got %d", resp.StatusCode)
+	}
+}
+
 // TestCreatePR создает PR с автоматическим назначением ревьюверов
 func TestCreatePR(t *testing.T) {
 	cleanupTestData(t)
@@ -591,6 +666,25 @@ func TestMergePR(t *testing.T) {
 			t.Errorf("Expected status 409 after merge, got %d", resp.StatusCode)
 		}
 	}
+
+	// Запрос, проверивший статус до мержа, не должен закрыть PR: переход перепроверяется под блокировкой
+	err = repository.NewPRRepository(testDB.DB).UpdateStatus(context.Background(), pr.ID, models.PRStatusClosed, time.Now(), nil)
+	if !errors.Is(err, repository.ErrInvalidStatusTransition) {
+		t.Errorf("Expected ErrInvalidStatusTransition for a merged PR, got %v", err)
+	}
+
+	// Запросы, проверившие PR до мержа, не должны менять ревьюверов и вердикты: PR перепроверяется под блокировкой
+	prRepo := repository.NewPRRepository(testDB.DB)
+	if len(mergedPR.Reviewers) > 0 {
+		err = prRepo.SetReviewState(context.Background(), pr.ID, mergedPR.Reviewers[0], models.ReviewStateApproved, time.Now())
+		if !errors.Is(err, repository.ErrPRNotOpen) {
+			t.Errorf("Expected ErrPRNotOpen for a review on a merged PR, got %v", err)
+		}
+		err = prRepo.ReassignReviewer(context.Background(), pr.ID, mergedPR.Reviewers[0], userIDs[0], nil)
+		if !errors.Is(err, repository.ErrPRNotOpen) {
+			t.Errorf("Expected ErrPRNotOpen for a reassignment on a merged PR, got %v", err)
+		}
+	}
 }
 
 // TestBulkDeactivateTeam тестирует массовую деактивацию команды
@@ -752,3 +846,24 @@ func TestGetStats(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// TestReadiness проверяет, что после применения миграций сервис готов принимать запросы
+func TestReadiness(t *testing.T) {
+	resp, err := makeRequest("GET", "/readyz", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var report models.HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if report.Components["migrations"].Status != models.HealthStatusUp {
+		t.Errorf("Expected migrations to be up, got %+v", report.Components["migrations"])
+	}
+}